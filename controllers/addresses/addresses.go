@@ -3,10 +3,17 @@
 package addresses
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/request"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
 )
 
@@ -64,12 +71,43 @@ type Address struct {
 	// The date of the last edit to this resource.
 	EditDate string `json:"editDate,omitempty"`
 
-	// A map[string]interface{} of custom fields to set on the resource. Note
+	// A map of custom fields to set on the resource. Note
 	// that this functionality requires PHPIPAM 1.3 or higher with the "Nest
 	// custom fields" flag set on the specific API integration. If this is not
 	// enabled, this map will be nil on GETs and POSTs and PATCHes with this
 	// field set will fail. Use the explicit custom field functions instead.
-	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+	CustomFields phpipam.CustomFields `json:"custom_fields,omitempty"`
+}
+
+// batchWorkers is the maximum number of concurrent requests made by batch
+// methods such as GetAddressesByIDs.
+const batchWorkers = 8
+
+// The IDs of the address tags in a default PHPIPAM installation. Tags record
+// the administrative state of an address, and are set by users or by the
+// DHCP and scan integrations - they are separate from the ping status that
+// PHPIPAM derives from an address's LastSeen time.
+const (
+	// TagOffline marks an address as allocated but not in use.
+	TagOffline = 1
+
+	// TagUsed marks an address as in use. This is the default for new
+	// addresses.
+	TagUsed = 2
+
+	// TagReserved marks an address as reserved for future use.
+	TagReserved = 3
+
+	// TagDHCP marks an address as part of a DHCP pool.
+	TagDHCP = 4
+)
+
+// LastSeenTime parses the address's LastSeen time via
+// phpipam.ParseDateTime, in the server's time zone loc. If loc is nil, the
+// server is assumed to be in the local time zone. ok is false, with a zero
+// time, if the address has never been seen.
+func (a Address) LastSeenTime(loc *time.Location) (t time.Time, ok bool, err error) {
+	return phpipam.ParseDateTime(a.LastSeen, loc)
 }
 
 // Controller is the base client for the Addresses controller.
@@ -91,24 +129,251 @@ func (c *Controller) CreateAddress(in Address) (message string, err error) {
 	return
 }
 
+// CreateAddressWithID creates an address by sending a POST request, returning the ID of
+// the new address. An error is returned if the API does not supply the ID.
+func (c *Controller) CreateAddressWithID(in Address) (id int, err error) {
+	if id, _, err = c.SendCreateRequest("/addresses/", &in); err == nil && id == 0 {
+		err = errors.New("No ID returned for created address")
+	}
+	return
+}
+
+// CreateAddressIfFree creates an address like CreateAddress, but checks that
+// its IP is free in its subnet via IsAddressFree first. If the address
+// already exists, a conflict *request.APIError is returned without sending
+// the POST, rather than the error PHPIPAM returns for duplicate addresses.
+//
+// Note that another client may still create the address between the check
+// and the POST.
+func (c *Controller) CreateAddressIfFree(in Address) (message string, err error) {
+	var free bool
+	if free, err = c.IsAddressFree(in.SubnetID, in.IPAddress); err != nil {
+		return
+	}
+	if !free {
+		err = &request.APIError{
+			Code:    http.StatusConflict,
+			Message: fmt.Sprintf("Address %s already exists in subnet %d", in.IPAddress, in.SubnetID),
+		}
+		return
+	}
+	message, err = c.CreateAddress(in)
+	return
+}
+
 // CreateAddress creates a first free in subnet address by sending a POST request.
 func (c *Controller) CreateFirstFreeAddress(id int, in Address) (out string, err error) {
         err = c.SendRequest("POST", fmt.Sprintf("/addresses/first_free/%d/", id), &in, &out)
         return
 }
 
+// ReserveAddress reserves the first free address in a subnet, tagging it with
+// the "Reserved" tag (TagReserved) and the supplied description. The created
+// address is returned, including its assigned IP and ID.
+func (c *Controller) ReserveAddress(subnetID int, description string) (out Address, err error) {
+	in := Address{
+		Description: description,
+		Tag:         TagReserved,
+	}
+	out, err = c.CreateFirstFreeAddressWithResult(subnetID, in)
+	return
+}
+
+// CreateFirstFreeAddressWithResult creates the first free address in a subnet
+// by sending a POST request, and returns the full created address. The address
+// is looked up by the ID supplied in the create response, or if there is none,
+// by the assigned IP via GetAddressByIPInSubnet.
+func (c *Controller) CreateFirstFreeAddressWithResult(id int, in Address) (out Address, err error) {
+	var addrID int
+	var ip string
+	if addrID, ip, err = c.SendCreateRequest(fmt.Sprintf("/addresses/first_free/%d/", id), &in); err != nil {
+		return
+	}
+	if addrID != 0 {
+		out, err = c.GetAddressByID(addrID)
+		return
+	}
+	out, err = c.GetAddressByIPInSubnet(ip, id)
+	return
+}
+
+// ListAddresses lists all addresses.
+func (c *Controller) ListAddresses() (out []Address, err error) {
+	err = c.SendRequest("GET", "/addresses/all/", &struct{}{}, &out)
+	return
+}
+
 // GetAddressByID GETs an address via its ID.
 func (c *Controller) GetAddressByID(id int) (out Address, err error) {
 	err = c.SendRequest("GET", fmt.Sprintf("/addresses/%d/", id), &struct{}{}, &out)
 	return
 }
 
+// GetAddressesByIDs GETs the addresses with the supplied IDs, via concurrent
+// calls to GetAddressByID. At most 8 requests are made at once.
+//
+// The addresses that were fetched are returned in the order of the supplied
+// IDs. If any lookups fail, the returned error is a phpipam.MultiError
+// containing the error for each failed ID, and the failed addresses are
+// omitted from the output.
+func (c *Controller) GetAddressesByIDs(ids []int) (out []Address, err error) {
+	results := make([]Address, len(ids))
+	errs := make([]error, len(ids))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < batchWorkers && w < len(ids); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i], errs[i] = c.GetAddressByID(ids[i])
+			}
+		}()
+	}
+	for i := range ids {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	merr := phpipam.MultiError{}
+	for i, e := range errs {
+		if e != nil {
+			merr[ids[i]] = e
+			continue
+		}
+		out = append(out, results[i])
+	}
+	if len(merr) > 0 {
+		err = merr
+	}
+	return
+}
+
 // GetAddressesByIP searches for an address by its IP.
 //
 // According to the spec, this can return multiple addresses, however it's not
 // entirely clear how to perform a search that would yield multiple results.
+//
+// The IP, which may also be supplied in CIDR notation, is validated and
+// normalized via phpipam.PathIP or phpipam.PathCIDR before the request is sent.
 func (c *Controller) GetAddressesByIP(ipaddr string) (out []Address, err error) {
-	err = c.SendRequest("GET", fmt.Sprintf("/addresses/search/%s/", ipaddr), &struct{}{}, &out)
+	var path string
+	if strings.Contains(ipaddr, "/") {
+		path, err = phpipam.PathCIDR(ipaddr)
+	} else {
+		path, err = phpipam.PathIP(ipaddr)
+	}
+	if err != nil {
+		return
+	}
+	err = c.SendRequest("GET", fmt.Sprintf("/addresses/search/%s/", path), &struct{}{}, &out)
+	return
+}
+
+// GetAddressByIPInSubnet GETs the address with the supplied IP in the subnet
+// with the supplied ID. The IP is validated and normalized via phpipam.PathIP
+// before the request is sent.
+func (c *Controller) GetAddressByIPInSubnet(ipaddr string, subnetID int) (out Address, err error) {
+	var path string
+	if path, err = phpipam.PathIP(ipaddr); err != nil {
+		return
+	}
+	err = c.SendRequest("GET", fmt.Sprintf("/addresses/%s/%d/", path, subnetID), &struct{}{}, &out)
+	return
+}
+
+// IsAddressFree returns true if there is no address with the supplied IP in
+// the subnet with the supplied ID, via GetAddressByIPInSubnet. A not found
+// error from the lookup means the address is free, and is not returned.
+func (c *Controller) IsAddressFree(subnetID int, ipaddr string) (free bool, err error) {
+	var out Address
+	if out, err = c.GetAddressByIPInSubnet(ipaddr, subnetID); err != nil {
+		if request.IsNotFound(err) {
+			return true, nil
+		}
+		return
+	}
+	free = out.ID == 0
+	return
+}
+
+// GetAddressesByTag GETs all addresses that have been assigned the tag with
+// the supplied ID.
+func (c *Controller) GetAddressesByTag(tagID int) (out []Address, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/addresses/tags/%d/addresses/", tagID), &struct{}{}, &out)
+	return
+}
+
+// NormalizeMAC returns a MAC address in the lowercase, colon-separated form
+// PHPIPAM stores (ie: 00:1a:2b:3c:4d:5e). Colon, dash, and dotted (ie:
+// 001a.2b3c.4d5e) forms are accepted, as are 12 hex digits with no
+// separators. An error is returned if mac is not a 48-bit MAC address.
+func NormalizeMAC(mac string) (string, error) {
+	s := strings.TrimSpace(mac)
+	if len(s) == 12 && !strings.ContainsAny(s, ":-.") {
+		s = s[0:4] + "." + s[4:8] + "." + s[8:12]
+	}
+	hw, err := net.ParseMAC(s)
+	if err != nil || len(hw) != 6 {
+		return "", fmt.Errorf("Invalid MAC address %q", mac)
+	}
+	return hw.String(), nil
+}
+
+// GetAddressesByMAC GETs all addresses with the supplied MAC address, using
+// the filter_by and filter_value query parameters. The MAC address is
+// normalized via NormalizeMAC first. An empty list is returned if no addresses
+// match.
+//
+// The result is filtered again client-side, comparing the normalized forms of
+// the MAC addresses, as older PHPIPAM versions ignore the filter parameters.
+func (c *Controller) GetAddressesByMAC(mac string) (out []Address, err error) {
+	if mac, err = NormalizeMAC(mac); err != nil {
+		return
+	}
+
+	var all []Address
+	opts := phpipam.ListOptions{FilterBy: "mac", FilterValue: mac}
+	if err = c.SendRequestWithParams("GET", "/addresses/all/", opts.Values(), &struct{}{}, &all); err != nil {
+		if !request.IsNotFound(err) {
+			return
+		}
+		err = nil
+	}
+	out = []Address{}
+	for _, v := range all {
+		if m, merr := NormalizeMAC(v.MACAddress); merr == nil && m == mac {
+			out = append(out, v)
+		}
+	}
+	return
+}
+
+// SearchAddressesByCustomField searches for addresses that have the supplied
+// value set in a custom field, using the filter_by and filter_value query
+// parameters.
+//
+// The field is validated against the schema returned by
+// GetAddressCustomFieldsSchema first, and an error is returned if it does not
+// exist.
+func (c *Controller) SearchAddressesByCustomField(field, value string) (out []Address, err error) {
+	var schema map[string]phpipam.CustomField
+	if schema, err = c.GetAddressCustomFieldsSchema(); err != nil {
+		return
+	}
+	if _, ok := schema[field]; !ok {
+		return nil, fmt.Errorf("Custom field %s not found in schema for controller addresses", field)
+	}
+
+	opts := phpipam.ListOptions{FilterBy: field, FilterValue: value}
+	err = c.SendRequestWithParams("GET", "/addresses/all/", opts.Values(), &struct{}{}, &out)
+	return
+}
+
+// GetAddressChangelog GETs the changelog of an address via its ID.
+func (c *Controller) GetAddressChangelog(id int) (out []phpipam.ChangelogEntry, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/addresses/%d/changelog/", id), &struct{}{}, &out)
 	return
 }
 
@@ -126,14 +391,92 @@ func (c *Controller) GetAddressCustomFields(id int) (out map[string]interface{},
 	return
 }
 
-// UpdateAddress updates an address by sending a PATCH request.
+// UpdateAddress updates an address by sending a PATCH request. Fields with
+// zero values are not sent - use PatchAddress to set a field to zero, or to
+// update only specific fields.
 func (c *Controller) UpdateAddress(in Address) (message string, err error) {
 	err = c.SendRequest("PATCH", "/addresses/", &in, &message)
 	return
 }
 
-// UpdateAddressCustomFields PATCHes the subnet's custom fields via
-// client.UpdateCustomFields.
+// PatchAddress PATCHes only the supplied fields of an address, via
+// client.PatchFields, leaving all other fields unchanged.
+//
+// The keys of fields are the address's JSON field names, as used in the tags
+// of Address (ie: is_gateway or description). A nil value is sent as null,
+// which clears the field. For example, this marks an address as a gateway
+// without touching anything else:
+//
+//   c.PatchAddress(id, map[string]interface{}{"is_gateway": phpipam.BoolIntString(true)})
+//
+// An error is returned if any of the keys are not fields of Address.
+func (c *Controller) PatchAddress(id int, fields map[string]interface{}) (message string, err error) {
+	message, err = c.Client.PatchFields("addresses", id, Address{}, fields)
+	return
+}
+
+// MoveAddress moves the address with the supplied ID into the subnet with
+// newSubnetID by PATCHing its subnetId, keeping the address's ID, custom
+// fields, and changelog intact.
+//
+// phpIPAM checks that the IP address fits in the new subnet. If it does not,
+// or the move is otherwise rejected, the error is a *request.APIError holding
+// the code and message supplied by the API.
+func (c *Controller) MoveAddress(id int, newSubnetID int) (message string, err error) {
+	message, err = c.PatchAddress(id, map[string]interface{}{"subnetId": newSubnetID})
+	return
+}
+
+// EnsureAddress makes sure that an address matching in exists, and returns it
+// along with whether or not it was created.
+//
+// The address is looked up by its IPAddress and SubnetID via
+// GetAddressByIPInSubnet. If it does not exist, it is created. Otherwise, any
+// fields set in in that differ from the existing address are updated via
+// PatchAddress - see phpipam.ChangedFields for how differences are
+// determined. Fields with zero values are left as they are. If the API
+// rejects the create because another client created the address in the
+// meantime, the existing address is used instead. If the API does not supply
+// the ID of a created address, it is looked up again.
+func (c *Controller) EnsureAddress(in Address) (out Address, created bool, err error) {
+	if out, err = c.GetAddressByIPInSubnet(in.IPAddress, in.SubnetID); request.IsNotFound(err) {
+		var id int
+		if id, _, err = c.SendCreateRequest("/addresses/", &in); err == nil {
+			if id != 0 {
+				out, err = c.GetAddressByID(id)
+			} else {
+				out, err = c.GetAddressByIPInSubnet(in.IPAddress, in.SubnetID)
+			}
+			return out, true, err
+		}
+		var apiErr *request.APIError
+		if !errors.As(err, &apiErr) {
+			return
+		}
+		var lerr error
+		if out, lerr = c.GetAddressByIPInSubnet(in.IPAddress, in.SubnetID); lerr != nil {
+			return
+		}
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+
+	var fields map[string]interface{}
+	if fields, err = phpipam.ChangedFields(in, out, "ip", "subnetId"); err != nil || len(fields) == 0 {
+		return
+	}
+	if _, err = c.PatchAddress(out.ID, fields); err != nil {
+		return
+	}
+	out, err = c.GetAddressByID(out.ID)
+	return
+}
+
+// UpdateAddressCustomFields PATCHes the address's custom fields via
+// client.UpdateCustomFields. The fields are validated against the schema first,
+// and an error is returned if any of them are not defined.
 func (c *Controller) UpdateAddressCustomFields(id int, in map[string]interface{}) (message string, err error) {
 	message, err = c.Client.UpdateCustomFields(id, in, "addresses")
 	return
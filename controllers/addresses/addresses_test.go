@@ -1,14 +1,19 @@
 package addresses
 
 import (
+	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/request"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
 	"github.com/pavel-z1/phpipam-sdk-go/testacc"
 )
@@ -190,6 +195,16 @@ const testDeleteAddressOutputJSON = `
 }
 `
 
+const testCreateAddressWithIDOutputJSON = `
+{
+  "code": 201,
+  "success": true,
+  "message": "Address created",
+  "id": "42",
+  "data": "Address created"
+}
+`
+
 func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
 	ts := httptest.NewServer(http.HandlerFunc(f))
 	return ts
@@ -209,6 +224,80 @@ func httpCreatedTestServer(output string) *httptest.Server {
 	})
 }
 
+// httpSearchByCustomFieldTestServer returns a server that responds with the
+// address custom field schema, or the addresses in output, recording the query
+// string of the last search in query.
+func httpSearchByCustomFieldTestServer(output string, query *string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/custom_fields/") {
+			http.Error(w, testGetAddressCustomFieldsSchemaJSON, http.StatusOK)
+			return
+		}
+		*query = r.URL.RawQuery
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
+var testReserveAddressOutputExpected = Address{
+	ID:          11,
+	SubnetID:    3,
+	IPAddress:   "10.10.1.10",
+	Description: "reserved for foo",
+	Tag:         TagReserved,
+}
+
+const testReserveAddressCreateOutputJSON = `
+{
+  "code": 201,
+  "success": true,
+  "message": "Address created",
+  "id": "11",
+  "data": "10.10.1.10"
+}
+`
+
+const testReserveAddressGetOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "11",
+    "subnetId": "3",
+    "ip": "10.10.1.10",
+    "description": "reserved for foo",
+    "tag": "3"
+  }
+}
+`
+
+const testCreateFirstFreeAddressNoIDOutputJSON = `
+{
+  "code": 201,
+  "success": true,
+  "message": "Address created",
+  "data": "10.10.1.10"
+}
+`
+
+// httpReserveAddressTestServer returns a server that responds to the first
+// free address POST with create, and any GET with get. The body of the POST is
+// recorded in body, and the path of the last GET in path.
+func httpReserveAddressTestServer(create, get string, body, path *string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		switch r.Method {
+		case "POST":
+			b, _ := ioutil.ReadAll(r.Body)
+			*body = string(b)
+			http.Error(w, create, http.StatusCreated)
+		default:
+			*path = r.URL.Path
+			http.Error(w, get, http.StatusOK)
+		}
+	})
+}
+
 func fullSessionConfig() *session.Session {
 	return &session.Session{
 		Config: phpipam.Config{
@@ -241,6 +330,111 @@ func TestCreateAddress(t *testing.T) {
 	}
 }
 
+func TestCreateAddressWithID(t *testing.T) {
+	ts := httpCreatedTestServer(testCreateAddressWithIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := 42
+	actual, err := client.CreateAddressWithID(testCreateAddressInput)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if expected != actual {
+		t.Fatalf("Expected %d, got %d", expected, actual)
+	}
+}
+
+func TestListAddresses(t *testing.T) {
+	ts := httpOKTestServer(testGetAddressesByIPOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetAddressesByIPOutputExpected
+	actual, err := client.ListAddresses()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestReserveAddress(t *testing.T) {
+	var body, path string
+	ts := httpReserveAddressTestServer(testReserveAddressCreateOutputJSON, testReserveAddressGetOutputJSON, &body, &path)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testReserveAddressOutputExpected
+	actual, err := client.ReserveAddress(3, "reserved for foo")
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	expectedBody := `{"description":"reserved for foo","tag":"3"}`
+	if body != expectedBody {
+		t.Fatalf("Expected body %s, got %s", expectedBody, body)
+	}
+}
+
+func TestCreateFirstFreeAddressWithResult(t *testing.T) {
+	cases := []struct {
+		name         string
+		create       string
+		expectedPath string
+	}{
+		{
+			name:         "ID in response",
+			create:       testReserveAddressCreateOutputJSON,
+			expectedPath: "/0123456789abcdefgh/addresses/11/",
+		},
+		{
+			name:         "lookup by IP",
+			create:       testCreateFirstFreeAddressNoIDOutputJSON,
+			expectedPath: "/0123456789abcdefgh/addresses/10.10.1.10/3/",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var body, path string
+			ts := httpReserveAddressTestServer(tc.create, testReserveAddressGetOutputJSON, &body, &path)
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewController(sess)
+
+			in := Address{Description: "reserved for foo", Tag: TagReserved}
+			expected := testReserveAddressOutputExpected
+			actual, err := client.CreateFirstFreeAddressWithResult(3, in)
+			if err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+
+			if !reflect.DeepEqual(expected, actual) {
+				t.Fatalf("Expected %#v, got %#v", expected, actual)
+			}
+
+			if path != tc.expectedPath {
+				t.Fatalf("Expected path %s, got %s", tc.expectedPath, path)
+			}
+		})
+	}
+}
+
 func TestGetAddressByID(t *testing.T) {
 	ts := httpOKTestServer(testGetAddressByIDOutputJSON)
 	defer ts.Close()
@@ -277,6 +471,538 @@ func TestGetAddressesByIP(t *testing.T) {
 	}
 }
 
+const testGetAddressByIDTemplateJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "%d",
+    "subnetId": "3",
+    "ip": "10.10.1.%d"
+  }
+}
+`
+
+const testAddressNotFoundOutputJSON = `
+{
+  "code": 404,
+  "success": false,
+  "message": "Address not found"
+}
+`
+
+// httpGetAddressesByIDsTestServer returns a server that responds to address
+// lookups by ID with an address built from the ID, or a 404 for IDs in missing.
+func httpGetAddressesByIDsTestServer(missing map[int]bool) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		var id int
+		fmt.Sscanf(r.URL.Path, "/0123456789abcdefgh/addresses/%d/", &id)
+		if missing[id] {
+			http.Error(w, testAddressNotFoundOutputJSON, http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf(testGetAddressByIDTemplateJSON, id, id), http.StatusOK)
+	})
+}
+
+func TestGetAddressesByIDs(t *testing.T) {
+	ts := httpGetAddressesByIDsTestServer(map[int]bool{})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	ids := []int{12, 3, 7, 20, 1, 9, 15, 4, 11, 2}
+	actual, err := client.GetAddressesByIDs(ids)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if len(actual) != len(ids) {
+		t.Fatalf("Expected %d addresses, got %d", len(ids), len(actual))
+	}
+	for i, id := range ids {
+		expected := Address{ID: id, SubnetID: 3, IPAddress: fmt.Sprintf("10.10.1.%d", id)}
+		if !reflect.DeepEqual(expected, actual[i]) {
+			t.Fatalf("Expected %#v at index %d, got %#v", expected, i, actual[i])
+		}
+	}
+}
+
+func TestGetAddressesByIDsError(t *testing.T) {
+	ts := httpGetAddressesByIDsTestServer(map[int]bool{7: true, 1: true})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	actual, err := client.GetAddressesByIDs([]int{12, 7, 3, 1})
+	merr, ok := err.(phpipam.MultiError)
+	if !ok {
+		t.Fatalf("Expected phpipam.MultiError, got %#v", err)
+	}
+	if len(merr) != 2 || !request.IsNotFound(merr[7]) || !request.IsNotFound(merr[1]) {
+		t.Fatalf("Expected not found errors for IDs 7 and 1, got %s", merr)
+	}
+
+	if len(actual) != 2 || actual[0].ID != 12 || actual[1].ID != 3 {
+		t.Fatalf("Expected addresses 12 and 3, got %#v", actual)
+	}
+}
+
+func TestGetAddressesByIPPath(t *testing.T) {
+	cases := []struct {
+		in           string
+		expectedPath string
+		err          bool
+	}{
+		{in: "10.10.1.10", expectedPath: "/0123456789abcdefgh/addresses/search/10.10.1.10/"},
+		{in: "10.10.1.10/24", expectedPath: "/0123456789abcdefgh/addresses/search/10.10.1.10/24/"},
+		{in: "2001:db8::10", expectedPath: "/0123456789abcdefgh/addresses/search/2001:db8::10/"},
+		{in: "2001:0db8:0000:0000:0000:0000:0000:0010", expectedPath: "/0123456789abcdefgh/addresses/search/2001:db8::10/"},
+		{in: "2001:0db8::0010/64", expectedPath: "/0123456789abcdefgh/addresses/search/2001:db8::10/64/"},
+		{in: "10.10.1", err: true},
+		{in: "2001:db8::10/200", err: true},
+	}
+
+	for _, tc := range cases {
+		var path string
+		ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			w.Header().Add("Content-Type", "application/json")
+			http.Error(w, testGetAddressesByIPOutputJSON, http.StatusOK)
+		})
+		sess := fullSessionConfig()
+		sess.Config.Endpoint = ts.URL
+		client := NewController(sess)
+
+		_, err := client.GetAddressesByIP(tc.in)
+		ts.Close()
+		if tc.err {
+			if err == nil {
+				t.Fatalf("%s: expected error", tc.in)
+			}
+			if path != "" {
+				t.Fatalf("%s: expected no request, got %s", tc.in, path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Bad: %s", tc.in, err)
+		}
+		if path != tc.expectedPath {
+			t.Fatalf("%s: expected path %s, got %s", tc.in, tc.expectedPath, path)
+		}
+	}
+}
+
+func TestGetAddressByIPInSubnetPath(t *testing.T) {
+	cases := []struct {
+		in           string
+		expectedPath string
+		err          bool
+	}{
+		{in: "2001:db8::10", expectedPath: "/0123456789abcdefgh/addresses/2001:db8::10/3/"},
+		{in: "2001:0db8:0000:0000:0000:0000:0000:0010", expectedPath: "/0123456789abcdefgh/addresses/2001:db8::10/3/"},
+		{in: "2001:db8::10/64", err: true},
+		{in: "foo", err: true},
+	}
+
+	for _, tc := range cases {
+		var path string
+		ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			w.Header().Add("Content-Type", "application/json")
+			http.Error(w, testReserveAddressGetOutputJSON, http.StatusOK)
+		})
+		sess := fullSessionConfig()
+		sess.Config.Endpoint = ts.URL
+		client := NewController(sess)
+
+		_, err := client.GetAddressByIPInSubnet(tc.in, 3)
+		ts.Close()
+		if tc.err {
+			if err == nil {
+				t.Fatalf("%s: expected error", tc.in)
+			}
+			if path != "" {
+				t.Fatalf("%s: expected no request, got %s", tc.in, path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Bad: %s", tc.in, err)
+		}
+		if path != tc.expectedPath {
+			t.Fatalf("%s: expected path %s, got %s", tc.in, tc.expectedPath, path)
+		}
+	}
+}
+
+// httpIsAddressFreeTestServer returns a server that responds to address
+// lookups with existing, or a 404 if existing is empty. The method and path
+// of each request are recorded in calls.
+func httpIsAddressFreeTestServer(existing string, calls *[]string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		*calls = append(*calls, r.Method+" "+strings.TrimPrefix(r.URL.Path, "/0123456789abcdefgh"))
+		switch {
+		case r.Method == "POST":
+			http.Error(w, `{"code":201,"success":true,"id":"11","data":"Address created"}`, http.StatusCreated)
+		case existing == "":
+			http.Error(w, `{"code":404,"success":false,"message":"Address not found"}`, http.StatusNotFound)
+		default:
+			http.Error(w, `{"code":200,"success":true,"data":`+existing+`}`, http.StatusOK)
+		}
+	})
+}
+
+func TestIsAddressFree(t *testing.T) {
+	cases := []struct {
+		name     string
+		existing string
+		expected bool
+	}{
+		{name: "free", expected: true},
+		{name: "taken", existing: `{"id":"11","subnetId":"3","ip":"10.10.1.10"}`, expected: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			ts := httpIsAddressFreeTestServer(tc.existing, &calls)
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewController(sess)
+
+			actual, err := client.IsAddressFree(3, "10.10.1.10")
+			if err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+			if actual != tc.expected {
+				t.Fatalf("Expected %t, got %t", tc.expected, actual)
+			}
+			if expected := []string{"GET /addresses/10.10.1.10/3/"}; !reflect.DeepEqual(expected, calls) {
+				t.Fatalf("Expected calls %v, got %v", expected, calls)
+			}
+		})
+	}
+}
+
+func TestIsAddressFreeError(t *testing.T) {
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, `{"code":500,"success":false,"message":"Database error"}`, http.StatusInternalServerError)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	if free, err := client.IsAddressFree(3, "10.10.1.10"); err == nil || free {
+		t.Fatalf("Expected error and not free, got %t, %v", free, err)
+	}
+}
+
+func TestCreateAddressIfFree(t *testing.T) {
+	in := Address{SubnetID: 3, IPAddress: "10.10.1.10"}
+	cases := []struct {
+		name          string
+		existing      string
+		expectedCalls []string
+		conflict      bool
+	}{
+		{
+			name:          "free",
+			expectedCalls: []string{"GET /addresses/10.10.1.10/3/", "POST /addresses/"},
+		},
+		{
+			name:          "taken",
+			existing:      `{"id":"11","subnetId":"3","ip":"10.10.1.10"}`,
+			expectedCalls: []string{"GET /addresses/10.10.1.10/3/"},
+			conflict:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			ts := httpIsAddressFreeTestServer(tc.existing, &calls)
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewController(sess)
+
+			_, err := client.CreateAddressIfFree(in)
+			if tc.conflict != request.IsConflict(err) {
+				t.Fatalf("Expected conflict %t, got %v", tc.conflict, err)
+			}
+			if !tc.conflict && err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+			if !reflect.DeepEqual(tc.expectedCalls, calls) {
+				t.Fatalf("Expected calls %v, got %v", tc.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestGetAddressesByTag(t *testing.T) {
+	var path string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testGetAddressesByIPOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetAddressesByIPOutputExpected
+	actual, err := client.GetAddressesByTag(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	expectedPath := "/0123456789abcdefgh/addresses/tags/2/addresses/"
+	if path != expectedPath {
+		t.Fatalf("Expected path %s, got %s", expectedPath, path)
+	}
+}
+
+func TestAddressLastSeenTime(t *testing.T) {
+	loc := time.FixedZone("UTC+1", 60*60)
+	cases := []struct {
+		lastSeen  string
+		expected  time.Time
+		ok        bool
+		expectErr bool
+	}{
+		{lastSeen: "2023-01-02 15:04:05", expected: time.Date(2023, 1, 2, 14, 4, 5, 0, time.UTC), ok: true},
+		{lastSeen: "0000-00-00 00:00:00"},
+		{lastSeen: ""},
+		{lastSeen: "yesterday", expectErr: true},
+	}
+
+	for _, tc := range cases {
+		actual, ok, err := Address{LastSeen: tc.lastSeen}.LastSeenTime(loc)
+		if tc.expectErr != (err != nil) {
+			t.Fatalf("%q: expected error %t, got %v", tc.lastSeen, tc.expectErr, err)
+		}
+		if ok != tc.ok || !actual.Equal(tc.expected) {
+			t.Fatalf("%q: expected %s (%t), got %s (%t)", tc.lastSeen, tc.expected, tc.ok, actual, ok)
+		}
+	}
+}
+
+func TestNormalizeMAC(t *testing.T) {
+	cases := []struct {
+		in        string
+		expected  string
+		expectErr bool
+	}{
+		{in: "00:1A:2B:3C:4D:5E", expected: "00:1a:2b:3c:4d:5e"},
+		{in: "00-1a-2b-3c-4d-5e", expected: "00:1a:2b:3c:4d:5e"},
+		{in: "001a.2b3c.4d5e", expected: "00:1a:2b:3c:4d:5e"},
+		{in: "001A2B3C4D5E", expected: "00:1a:2b:3c:4d:5e"},
+		{in: " 00:1a:2b:3c:4d:5e ", expected: "00:1a:2b:3c:4d:5e"},
+		{in: "", expectErr: true},
+		{in: "00:1a:2b:3c:4d", expectErr: true},
+		{in: "00:00:5e:00:53:01:ff:fe", expectErr: true},
+		{in: "foo", expectErr: true},
+	}
+
+	for _, tc := range cases {
+		actual, err := NormalizeMAC(tc.in)
+		if tc.expectErr != (err != nil) {
+			t.Fatalf("%q: expected error %t, got %v", tc.in, tc.expectErr, err)
+		}
+		if actual != tc.expected {
+			t.Fatalf("%q: expected %q, got %q", tc.in, tc.expected, actual)
+		}
+	}
+}
+
+func TestGetAddressesByMAC(t *testing.T) {
+	var path, query string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.Path, r.URL.RawQuery
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, `{"code":200,"success":true,"data":[`+
+			`{"id":"11","subnetId":"3","ip":"10.10.1.10","mac":"00:1a:2b:3c:4d:5e"},`+
+			`{"id":"12","subnetId":"4","ip":"10.10.2.10","mac":"00-1A-2B-3C-4D-5E"},`+
+			`{"id":"13","subnetId":"4","ip":"10.10.2.11","mac":"00:1a:2b:3c:4d:5f"}]}`, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	actual, err := client.GetAddressesByMAC("001a.2b3c.4d5e")
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	var ids []int
+	for _, v := range actual {
+		ids = append(ids, v.ID)
+	}
+	if expected := []int{11, 12}; !reflect.DeepEqual(expected, ids) {
+		t.Fatalf("Expected addresses %v, got %v", expected, ids)
+	}
+	if expected := "/0123456789abcdefgh/addresses/all/"; path != expected {
+		t.Fatalf("Expected path %s, got %s", expected, path)
+	}
+	if expected := "filter_by=mac&filter_value=00%3A1a%3A2b%3A3c%3A4d%3A5e"; query != expected {
+		t.Fatalf("Expected query %s, got %s", expected, query)
+	}
+}
+
+func TestGetAddressesByMACNotFound(t *testing.T) {
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, `{"code":404,"success":false,"message":"No addresses found"}`, http.StatusNotFound)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	actual, err := client.GetAddressesByMAC("00:1a:2b:3c:4d:5e")
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if actual == nil || len(actual) != 0 {
+		t.Fatalf("Expected empty list, got %#v", actual)
+	}
+}
+
+func TestSearchAddressesByCustomField(t *testing.T) {
+	var query string
+	ts := httpSearchByCustomFieldTestServer(testGetAddressesByIPOutputJSON, &query)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetAddressesByIPOutputExpected
+	actual, err := client.SearchAddressesByCustomField("CustomTestAddresses", "asset 1")
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	expectedQuery := "filter_by=CustomTestAddresses&filter_value=asset+1"
+	if query != expectedQuery {
+		t.Fatalf("Expected query %s, got %s", expectedQuery, query)
+	}
+}
+
+func TestSearchAddressesByCustomFieldInvalidField(t *testing.T) {
+	var query string
+	ts := httpSearchByCustomFieldTestServer(testGetAddressesByIPOutputJSON, &query)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	_, err := client.SearchAddressesByCustomField("AssetID", "1")
+	if err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+
+	expected := "Custom field AssetID not found in schema for controller addresses"
+	if err.Error() != expected {
+		t.Fatalf("Expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestUpdateAddressCustomFieldsInvalidField(t *testing.T) {
+	var query string
+	ts := httpSearchByCustomFieldTestServer(testGetAddressesByIPOutputJSON, &query)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	_, err := client.UpdateAddressCustomFields(11, map[string]interface{}{"CustomTestAdresses": "foo"})
+	if err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+
+	expected := "Custom field CustomTestAdresses not found in schema for controller addresses"
+	if err.Error() != expected {
+		t.Fatalf("Expected %q, got %q", expected, err.Error())
+	}
+}
+
+const testGetAddressChangelogOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "user": "Admin",
+      "action": "edit",
+      "result": "success",
+      "date": "2026-01-15 10:42:07",
+      "diff": {
+        "description": "[old] foo\r\n[new] bar"
+      }
+    }
+  ]
+}
+`
+
+var testGetAddressChangelogOutputExpected = []phpipam.ChangelogEntry{
+	phpipam.ChangelogEntry{
+		User:   "Admin",
+		Action: "edit",
+		Result: "success",
+		Date:   "2026-01-15 10:42:07",
+		Diff: map[string]interface{}{
+			"description": "[old] foo\r\n[new] bar",
+		},
+	},
+}
+
+func TestGetAddressChangelog(t *testing.T) {
+	var path string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testGetAddressChangelogOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetAddressChangelogOutputExpected
+	actual, err := client.GetAddressChangelog(3)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	expectedPath := "/0123456789abcdefgh/addresses/3/changelog/"
+	if path != expectedPath {
+		t.Fatalf("Expected path %s, got %s", expectedPath, path)
+	}
+}
+
 func TestGetAddressCustomFieldsSchema(t *testing.T) {
 	ts := httpOKTestServer(testGetAddressCustomFieldsSchemaJSON)
 	defer ts.Close()
@@ -314,6 +1040,224 @@ func TestUpdateAddress(t *testing.T) {
 	}
 }
 
+func TestPatchAddress(t *testing.T) {
+	var body string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testUpdateAddressOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	fields := map[string]interface{}{
+		"is_gateway":  phpipam.BoolIntString(true),
+		"excludePing": phpipam.BoolIntString(false),
+	}
+	if _, err := client.PatchAddress(11, fields); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	expected := `{"excludePing":"0","id":11,"is_gateway":"1"}`
+	if body != expected {
+		t.Fatalf("Expected body %s, got %s", expected, body)
+	}
+}
+
+func TestPatchAddressUnknownField(t *testing.T) {
+	sess := fullSessionConfig()
+	client := NewController(sess)
+
+	_, err := client.PatchAddress(11, map[string]interface{}{"isGateway": true})
+	expected := "Field isGateway is not a known field for controller addresses"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Expected error %q, got %v", expected, err)
+	}
+}
+
+func TestMoveAddress(t *testing.T) {
+	var method, path, body string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(b)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testUpdateAddressOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	if _, err := client.MoveAddress(11, 4); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if method != "PATCH" || path != "/0123456789abcdefgh/addresses/" {
+		t.Fatalf("Expected PATCH /0123456789abcdefgh/addresses/, got %s %s", method, path)
+	}
+	expected := `{"id":11,"subnetId":4}`
+	if body != expected {
+		t.Fatalf("Expected body %s, got %s", expected, body)
+	}
+}
+
+func TestMoveAddressRejected(t *testing.T) {
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, `{"code":400,"success":false,"message":"IP address not in selected subnet! (10.10.1.10)"}`, http.StatusBadRequest)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	_, err := client.MoveAddress(11, 4)
+	apiErr, ok := err.(*request.APIError)
+	if !ok {
+		t.Fatalf("Expected *request.APIError, got %T: %v", err, err)
+	}
+	if apiErr.Code != http.StatusBadRequest || apiErr.Message != "IP address not in selected subnet! (10.10.1.10)" {
+		t.Fatalf("Unexpected error: %+v", apiErr)
+	}
+}
+
+// httpEnsureAddressTestServer returns a server that responds to IP lookups in
+// a subnet with existing, or a 404 if existing is empty, and to lookups by ID
+// with existing. How creates are handled depends on create: "conflict" fails
+// them with a 409 as if another client had created the address with ID 8,
+// "noid" creates the address with ID 9 without returning the ID, and
+// anything else creates it with ID 9. The method and path of each request are
+// recorded in calls, and the body of any PATCH in body.
+func httpEnsureAddressTestServer(existing, create string, calls *[]string, body *string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/0123456789abcdefgh")
+		*calls = append(*calls, r.Method+" "+path)
+		switch {
+		case r.Method == "POST" && create == "conflict":
+			existing = `{"id":"8","subnetId":"3","ip":"10.10.1.10","hostname":"other"}`
+			http.Error(w, `{"code":409,"success":false,"message":"IP address already exists"}`, http.StatusConflict)
+		case r.Method == "POST" && create == "noid":
+			existing = `{"id":"9","subnetId":"3","ip":"10.10.1.10","hostname":"web1"}`
+			http.Error(w, `{"code":201,"success":true,"data":"Address created"}`, http.StatusCreated)
+		case r.Method == "POST":
+			existing = `{"id":"9","subnetId":"3","ip":"10.10.1.10","hostname":"web1"}`
+			http.Error(w, `{"code":201,"success":true,"id":"9","data":"Address created"}`, http.StatusCreated)
+		case r.Method == "PATCH":
+			b, _ := ioutil.ReadAll(r.Body)
+			*body = string(b)
+			http.Error(w, testUpdateAddressOutputJSON, http.StatusOK)
+		case existing == "":
+			http.Error(w, `{"code":404,"success":false,"message":"Address not found"}`, http.StatusNotFound)
+		default:
+			http.Error(w, `{"code":200,"success":true,"data":`+existing+`}`, http.StatusOK)
+		}
+	})
+}
+
+func TestEnsureAddress(t *testing.T) {
+	in := Address{
+		SubnetID:  3,
+		IPAddress: "10.10.1.10",
+		Hostname:  "web1",
+	}
+
+	cases := []struct {
+		name            string
+		existing        string
+		create          string
+		expectedCreated bool
+		expectedID      int
+		expectedCalls   []string
+		expectedBody    string
+	}{
+		{
+			name:            "create",
+			expectedCreated: true,
+			expectedID:      9,
+			expectedCalls:   []string{"GET /addresses/10.10.1.10/3/", "POST /addresses/", "GET /addresses/9/"},
+		},
+		{
+			name:            "create without ID",
+			create:          "noid",
+			expectedCreated: true,
+			expectedID:      9,
+			expectedCalls:   []string{"GET /addresses/10.10.1.10/3/", "POST /addresses/", "GET /addresses/10.10.1.10/3/"},
+		},
+		{
+			name:          "created concurrently",
+			create:        "conflict",
+			expectedID:    8,
+			expectedCalls: []string{"GET /addresses/10.10.1.10/3/", "POST /addresses/", "GET /addresses/10.10.1.10/3/", "PATCH /addresses/", "GET /addresses/8/"},
+			expectedBody:  `{"hostname":"web1","id":8}`,
+		},
+		{
+			name:          "unchanged",
+			existing:      `{"id":"7","subnetId":"3","ip":"10.10.1.10","hostname":"web1","description":"Web server"}`,
+			expectedID:    7,
+			expectedCalls: []string{"GET /addresses/10.10.1.10/3/"},
+		},
+		{
+			name:          "drifted",
+			existing:      `{"id":"7","subnetId":"3","ip":"10.10.1.10","hostname":"old"}`,
+			expectedID:    7,
+			expectedCalls: []string{"GET /addresses/10.10.1.10/3/", "PATCH /addresses/", "GET /addresses/7/"},
+			expectedBody:  `{"hostname":"web1","id":7}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			var body string
+			ts := httpEnsureAddressTestServer(tc.existing, tc.create, &calls, &body)
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewController(sess)
+
+			out, created, err := client.EnsureAddress(in)
+			if err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+			if created != tc.expectedCreated {
+				t.Fatalf("Expected created to be %t, got %t", tc.expectedCreated, created)
+			}
+			if out.ID != tc.expectedID {
+				t.Fatalf("Expected ID %d, got %d", tc.expectedID, out.ID)
+			}
+			if !reflect.DeepEqual(tc.expectedCalls, calls) {
+				t.Fatalf("Expected calls %v, got %v", tc.expectedCalls, calls)
+			}
+			if body != tc.expectedBody {
+				t.Fatalf("Expected body %s, got %s", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestEnsureAddressCreateError(t *testing.T) {
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		if r.Method == "POST" {
+			http.Error(w, `{"code":400,"success":false,"message":"IP address not in selected subnet"}`, http.StatusBadRequest)
+			return
+		}
+		http.Error(w, `{"code":404,"success":false,"message":"Address not found"}`, http.StatusNotFound)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	_, _, err := client.EnsureAddress(Address{SubnetID: 3, IPAddress: "10.10.9.10"})
+	if err == nil || !strings.Contains(err.Error(), "IP address not in selected subnet") {
+		t.Fatalf("Expected create error, got %v", err)
+	}
+}
+
 func TestDeleteAddress(t *testing.T) {
 	ts := httpOKTestServer(testDeleteAddressOutputJSON)
 	defer ts.Close()
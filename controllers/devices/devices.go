@@ -0,0 +1,82 @@
+// Package devices provides types and methods for working with the devices
+// controller.
+package devices
+
+import (
+	"fmt"
+
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+// Device represents a PHPIPAM device.
+type Device struct {
+	// The device ID.
+	ID int `json:"id,string,omitempty"`
+
+	// The device's hostname.
+	Hostname string `json:"hostname,omitempty"`
+
+	// The device's management IP address.
+	IPAddress string `json:"ip,omitempty"`
+
+	// The ID of the device type. See the tools/devicetypes controller to
+	// resolve this to a name.
+	Type int `json:"type,string,omitempty"`
+
+	// A detailed description of the device.
+	Description string `json:"description,omitempty"`
+
+	// The IDs of the sections this device is available in, separated by
+	// semicolons.
+	Sections string `json:"sections,omitempty"`
+
+	// The ID of the rack the device is mounted in.
+	Rack int `json:"rack,string,omitempty"`
+
+	// The rack unit the device starts at.
+	RackStart int `json:"rack_start,string,omitempty"`
+
+	// The size of the device in rack units.
+	RackSize int `json:"rack_size,string,omitempty"`
+
+	// The location index of the device.
+	Location int `json:"location,string,omitempty"`
+
+	// The date of the last edit to this resource.
+	EditDate string `json:"editDate,omitempty"`
+
+	// A map of custom fields to set on the resource. Note
+	// that this functionality requires PHPIPAM 1.3 or higher with the "Nest
+	// custom fields" flag set on the specific API integration. If this is not
+	// enabled, this map will be nil on GETs and POSTs and PATCHes with this
+	// field set will fail.
+	CustomFields phpipam.CustomFields `json:"custom_fields,omitempty"`
+}
+
+// Controller is the base client for the Devices controller.
+type Controller struct {
+	client.Client
+}
+
+// NewController returns a new instance of the client for the Devices
+// controller.
+func NewController(sess *session.Session) *Controller {
+	c := &Controller{
+		Client: *client.NewClient(sess),
+	}
+	return c
+}
+
+// ListDevices lists all devices.
+func (c *Controller) ListDevices() (out []Device, err error) {
+	err = c.SendRequest("GET", "/devices/", &struct{}{}, &out)
+	return
+}
+
+// GetDeviceByID GETs a device via its ID.
+func (c *Controller) GetDeviceByID(id int) (out Device, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/devices/%d/", id), &struct{}{}, &out)
+	return
+}
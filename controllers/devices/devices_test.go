@@ -0,0 +1,137 @@
+package devices
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+var testListDevicesOutputExpected = []Device{
+	Device{
+		ID:          1,
+		Hostname:    "sw1.cust1.local",
+		IPAddress:   "10.10.1.2",
+		Type:        1,
+		Description: "Access switch",
+		Sections:    "1;2",
+		Rack:        1,
+		RackStart:   10,
+		RackSize:    1,
+		Location:    1,
+	},
+}
+
+const testListDevicesOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "1",
+      "hostname": "sw1.cust1.local",
+      "ip": "10.10.1.2",
+      "type": "1",
+      "description": "Access switch",
+      "sections": "1;2",
+      "snmp_community": null,
+      "rack": "1",
+      "rack_start": "10",
+      "rack_size": "1",
+      "location": "1",
+      "editDate": null
+    }
+  ]
+}
+`
+
+var testGetDeviceByIDOutputExpected = Device{
+	ID:        2,
+	Hostname:  "rtr1.cust1.local",
+	IPAddress: "10.10.1.1",
+	Type:      2,
+}
+
+const testGetDeviceByIDOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "2",
+    "hostname": "rtr1.cust1.local",
+    "ip": "10.10.1.1",
+    "type": "2",
+    "description": null,
+    "sections": null,
+    "rack": null,
+    "rack_start": null,
+    "rack_size": null,
+    "location": null,
+    "editDate": null
+  }
+}
+`
+
+func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(f))
+	return ts
+}
+
+func httpOKTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
+func fullSessionConfig() *session.Session {
+	return &session.Session{
+		Config: phpipam.Config{
+			AppID:    "0123456789abcdefgh",
+			Password: "changeit",
+			Username: "nobody",
+		},
+		Token: session.Token{
+			String: "foobarbazboop",
+		},
+	}
+}
+
+func TestListDevices(t *testing.T) {
+	ts := httpOKTestServer(testListDevicesOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testListDevicesOutputExpected
+	actual, err := client.ListDevices()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetDeviceByID(t *testing.T) {
+	ts := httpOKTestServer(testGetDeviceByIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetDeviceByIDOutputExpected
+	actual, err := client.GetDeviceByID(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
@@ -0,0 +1,108 @@
+// Package l2domains provides types and methods for working with the L2 domain
+// controller.
+package l2domains
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/vlans"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/request"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+// L2Domain represents a PHPIPAM layer 2 domain. VLANs belong to an L2 domain
+// via their DomainID field.
+type L2Domain struct {
+	// The L2 domain ID.
+	ID int `json:"id,string,omitempty"`
+
+	// The name of the L2 domain.
+	Name string `json:"name,omitempty"`
+
+	// A detailed description of the L2 domain.
+	Description string `json:"description,omitempty"`
+
+	// A semicolon-separated list of the IDs of the sections the L2 domain is
+	// available in.
+	Sections string `json:"sections,omitempty"`
+
+	// The date of the last edit to this resource.
+	EditDate string `json:"editDate,omitempty"`
+}
+
+// Controller is the base client for the L2 domain controller.
+type Controller struct {
+	client.Client
+}
+
+// NewController returns a new instance of the client for the L2 domain
+// controller.
+func NewController(sess *session.Session) *Controller {
+	c := &Controller{
+		Client: *client.NewClient(sess),
+	}
+	return c
+}
+
+// ListL2Domains lists all L2 domains.
+func (c *Controller) ListL2Domains() (out []L2Domain, err error) {
+	err = c.SendRequest("GET", "/l2domains/", &struct{}{}, &out)
+	return
+}
+
+// GetL2DomainByID GETs an L2 domain via its ID.
+func (c *Controller) GetL2DomainByID(id int) (out L2Domain, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/l2domains/%d/", id), &struct{}{}, &out)
+	return
+}
+
+// GetL2DomainByName returns the L2 domain with the supplied name.
+//
+// PHPIPAM does not provide an API method for this, and does not require L2
+// domain names to be unique, so the lookup is performed client-side over the
+// results of ListL2Domains. A not found *request.APIError is returned if there
+// is no such L2 domain, and an error is also returned if the name is
+// ambiguous.
+func (c *Controller) GetL2DomainByName(name string) (out L2Domain, err error) {
+	var list []L2Domain
+	if list, err = c.ListL2Domains(); err != nil {
+		return
+	}
+	var matches []L2Domain
+	for _, v := range list {
+		if v.Name == name {
+			matches = append(matches, v)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		err = &request.APIError{
+			Code:    http.StatusNotFound,
+			Message: fmt.Sprintf("No L2 domain named %s found", name),
+		}
+	case 1:
+		out = matches[0]
+	default:
+		err = fmt.Errorf("Found %d L2 domains named %s, expected 1", len(matches), name)
+	}
+	return
+}
+
+// GetVLANsInL2Domain GETs the VLANs in an L2 domain via the domain's ID.
+func (c *Controller) GetVLANsInL2Domain(id int) (out []vlans.VLAN, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/l2domains/%d/vlans/", id), &struct{}{}, &out)
+	return
+}
+
+// GetVLANsInL2DomainByName GETs the VLANs in an L2 domain, resolving the
+// domain by name via GetL2DomainByName first. Its errors are returned as-is if
+// the name does not match exactly one L2 domain.
+func (c *Controller) GetVLANsInL2DomainByName(name string) (out []vlans.VLAN, err error) {
+	var domain L2Domain
+	if domain, err = c.GetL2DomainByName(name); err != nil {
+		return
+	}
+	return c.GetVLANsInL2Domain(domain.ID)
+}
@@ -0,0 +1,294 @@
+package l2domains
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/vlans"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/request"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+var testListL2DomainsOutputExpected = []L2Domain{
+	L2Domain{
+		ID:          1,
+		Name:        "default",
+		Description: "Default L2 domain",
+	},
+	L2Domain{
+		ID:          2,
+		Name:        "dc1",
+		Description: "Datacenter 1",
+		Sections:    "1;2",
+	},
+	L2Domain{
+		ID:          3,
+		Name:        "lab",
+		Description: "Lab A",
+	},
+	L2Domain{
+		ID:          4,
+		Name:        "lab",
+		Description: "Lab B",
+	},
+}
+
+const testListL2DomainsOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "1",
+      "name": "default",
+      "description": "Default L2 domain",
+      "sections": null,
+      "editDate": null
+    },
+    {
+      "id": "2",
+      "name": "dc1",
+      "description": "Datacenter 1",
+      "sections": "1;2",
+      "editDate": null
+    },
+    {
+      "id": "3",
+      "name": "lab",
+      "description": "Lab A",
+      "sections": null,
+      "editDate": null
+    },
+    {
+      "id": "4",
+      "name": "lab",
+      "description": "Lab B",
+      "sections": null,
+      "editDate": null
+    }
+  ]
+}
+`
+
+var testGetL2DomainByIDOutputExpected = L2Domain{
+	ID:          2,
+	Name:        "dc1",
+	Description: "Datacenter 1",
+	Sections:    "1;2",
+}
+
+const testGetL2DomainByIDOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "2",
+    "name": "dc1",
+    "description": "Datacenter 1",
+    "sections": "1;2",
+    "editDate": null
+  }
+}
+`
+
+var testGetVLANsInL2DomainOutputExpected = []vlans.VLAN{
+	vlans.VLAN{
+		ID:       3,
+		DomainID: 2,
+		Name:     "web",
+		Number:   100,
+	},
+	vlans.VLAN{
+		ID:       4,
+		DomainID: 2,
+		Name:     "db",
+		Number:   200,
+	},
+}
+
+const testGetVLANsInL2DomainOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "3",
+      "domainId": "2",
+      "name": "web",
+      "number": "100",
+      "description": null,
+      "editDate": null
+    },
+    {
+      "id": "4",
+      "domainId": "2",
+      "name": "db",
+      "number": "200",
+      "description": null,
+      "editDate": null
+    }
+  ]
+}
+`
+
+func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(f))
+	return ts
+}
+
+func httpOKTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
+// httpVLANsByNameTestServer returns a server that responds to L2 domain
+// listings with all L2 domains, and to VLAN listings with the VLANs in
+// testGetVLANsInL2DomainOutputJSON, recording the path of the VLAN listing in
+// path.
+func httpVLANsByNameTestServer(path *string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/vlans/") {
+			*path = r.URL.Path
+			http.Error(w, testGetVLANsInL2DomainOutputJSON, http.StatusOK)
+			return
+		}
+		http.Error(w, testListL2DomainsOutputJSON, http.StatusOK)
+	})
+}
+
+func fullSessionConfig() *session.Session {
+	return &session.Session{
+		Config: phpipam.Config{
+			AppID:    "0123456789abcdefgh",
+			Password: "changeit",
+			Username: "nobody",
+		},
+		Token: session.Token{
+			String: "foobarbazboop",
+		},
+	}
+}
+
+func TestListL2Domains(t *testing.T) {
+	ts := httpOKTestServer(testListL2DomainsOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testListL2DomainsOutputExpected
+	actual, err := client.ListL2Domains()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetL2DomainByID(t *testing.T) {
+	ts := httpOKTestServer(testGetL2DomainByIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetL2DomainByIDOutputExpected
+	actual, err := client.GetL2DomainByID(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetL2DomainByName(t *testing.T) {
+	ts := httpOKTestServer(testListL2DomainsOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetL2DomainByIDOutputExpected
+	actual, err := client.GetL2DomainByName("dc1")
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	if _, err := client.GetL2DomainByName("dc2"); !request.IsNotFound(err) {
+		t.Fatalf("Expected not found error, got %v", err)
+	}
+	if _, err := client.GetL2DomainByName("lab"); err == nil || !strings.Contains(err.Error(), "Found 2 L2 domains named lab") {
+		t.Fatalf("Expected ambiguous name error, got %v", err)
+	}
+}
+
+func TestGetVLANsInL2Domain(t *testing.T) {
+	ts := httpOKTestServer(testGetVLANsInL2DomainOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetVLANsInL2DomainOutputExpected
+	actual, err := client.GetVLANsInL2Domain(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetVLANsInL2DomainByName(t *testing.T) {
+	var path string
+	ts := httpVLANsByNameTestServer(&path)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetVLANsInL2DomainOutputExpected
+	actual, err := client.GetVLANsInL2DomainByName("dc1")
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+	if expectedPath := "/0123456789abcdefgh/l2domains/2/vlans/"; path != expectedPath {
+		t.Fatalf("Expected path %s, got %s", expectedPath, path)
+	}
+}
+
+func TestGetVLANsInL2DomainByNameAmbiguous(t *testing.T) {
+	var path string
+	ts := httpVLANsByNameTestServer(&path)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	if _, err := client.GetVLANsInL2DomainByName("lab"); err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+	if path != "" {
+		t.Fatalf("Expected no VLAN listing, got request to %s", path)
+	}
+}
@@ -0,0 +1,144 @@
+// Package requests provides types and methods for working with the IP
+// address requests controller.
+package requests
+
+import (
+	"fmt"
+
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+// The states of an IP address request, as returned by Request.Status.
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+// Request represents a PHPIPAM IP address request.
+type Request struct {
+	// The request ID.
+	ID int `json:"id,string,omitempty"`
+
+	// The ID of the subnet that the address is requested in.
+	SubnetID int `json:"subnetId,string,omitempty"`
+
+	// The requested IP address. If this is blank, the first free address in
+	// the subnet is assigned on approval.
+	IPAddress string `json:"ip_addr,omitempty"`
+
+	// A detailed description of the requested address.
+	Description string `json:"description,omitempty"`
+
+	// A hostname for the requested address.
+	Hostname string `json:"hostname,omitempty"`
+
+	// The MAC address for the requested address.
+	MACAddress string `json:"mac,omitempty"`
+
+	// The tag ID for the requested address.
+	State int `json:"state,string,omitempty"`
+
+	// The address owner (customer, hostname, application, etc).
+	Owner string `json:"owner,omitempty"`
+
+	// The email address of the requester.
+	Requester string `json:"requester,omitempty"`
+
+	// The reason for the request, as supplied by the requester.
+	Comment string `json:"comment,omitempty"`
+
+	// true if the request has been processed by an administrator.
+	Processed phpipam.BoolIntString `json:"processed,omitempty"`
+
+	// true if the request has been accepted. This is only meaningful if
+	// Processed is true.
+	Accepted phpipam.BoolIntString `json:"accepted,omitempty"`
+
+	// A comment from the administrator that processed the request.
+	AdminComment string `json:"adminComment,omitempty"`
+}
+
+// Status returns the status of the request, derived from its Processed and
+// Accepted flags. This is one of StatusPending, StatusApproved, or
+// StatusRejected.
+func (r Request) Status() string {
+	switch {
+	case !bool(r.Processed):
+		return StatusPending
+	case bool(r.Accepted):
+		return StatusApproved
+	}
+	return StatusRejected
+}
+
+// Controller is the base client for the requests controller.
+type Controller struct {
+	client.Client
+}
+
+// NewController returns a new instance of the client for the requests
+// controller.
+func NewController(sess *session.Session) *Controller {
+	c := &Controller{
+		Client: *client.NewClient(sess),
+	}
+	return c
+}
+
+// ListRequests lists all IP address requests.
+func (c *Controller) ListRequests() (out []Request, err error) {
+	err = c.SendRequest("GET", "/requests/", &struct{}{}, &out)
+	return
+}
+
+// CreateRequest creates an IP address request by sending a POST request.
+func (c *Controller) CreateRequest(in Request) (message string, err error) {
+	err = c.SendRequest("POST", "/requests/", &in, &message)
+	return
+}
+
+// GetRequestByID GETs an IP address request via its ID.
+func (c *Controller) GetRequestByID(id int) (out Request, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/requests/%d/", id), &struct{}{}, &out)
+	return
+}
+
+// ApproveRequest marks an IP address request as processed and accepted, with
+// the supplied administrator comment.
+func (c *Controller) ApproveRequest(id int, adminComment string) (message string, err error) {
+	message, err = c.processRequest(id, true, adminComment)
+	return
+}
+
+// RejectRequest marks an IP address request as processed and rejected, with
+// the supplied administrator comment.
+func (c *Controller) RejectRequest(id int, adminComment string) (message string, err error) {
+	message, err = c.processRequest(id, false, adminComment)
+	return
+}
+
+// processRequest PATCHes the processed, accepted, and admin comment fields of
+// an IP address request for ApproveRequest and RejectRequest. The flags are
+// always sent, so that a request can be rejected.
+func (c *Controller) processRequest(id int, accepted bool, adminComment string) (message string, err error) {
+	in := struct {
+		Processed    phpipam.BoolIntString `json:"processed"`
+		Accepted     phpipam.BoolIntString `json:"accepted"`
+		AdminComment string                `json:"adminComment,omitempty"`
+	}{
+		Processed:    true,
+		Accepted:     phpipam.BoolIntString(accepted),
+		AdminComment: adminComment,
+	}
+	err = c.SendRequest("PATCH", fmt.Sprintf("/requests/%d/", id), &in, &message)
+	return
+}
+
+// DeleteRequest deletes an IP address request by its ID.
+func (c *Controller) DeleteRequest(id int) (message string, err error) {
+	err = c.SendRequest("DELETE", fmt.Sprintf("/requests/%d/", id), &struct{}{}, &message)
+	return
+}
@@ -0,0 +1,311 @@
+package requests
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+var testListRequestsOutputExpected = []Request{
+	Request{
+		ID:          1,
+		SubnetID:    3,
+		IPAddress:   "10.10.1.20",
+		Description: "build agent",
+		Hostname:    "build01.example.com",
+		State:       2,
+		Requester:   "jdoe@example.com",
+		Comment:     "New CI capacity",
+	},
+	Request{
+		ID:           2,
+		SubnetID:     3,
+		Requester:    "jdoe@example.com",
+		Processed:    true,
+		Accepted:     true,
+		AdminComment: "ok",
+	},
+}
+
+const testListRequestsOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "1",
+      "subnetId": "3",
+      "ip_addr": "10.10.1.20",
+      "description": "build agent",
+      "hostname": "build01.example.com",
+      "mac": null,
+      "state": "2",
+      "owner": null,
+      "requester": "jdoe@example.com",
+      "comment": "New CI capacity",
+      "processed": "0",
+      "accepted": null,
+      "adminComment": null
+    },
+    {
+      "id": "2",
+      "subnetId": "3",
+      "ip_addr": null,
+      "description": null,
+      "hostname": null,
+      "mac": null,
+      "state": null,
+      "owner": null,
+      "requester": "jdoe@example.com",
+      "comment": null,
+      "processed": "1",
+      "accepted": "1",
+      "adminComment": "ok"
+    }
+  ]
+}
+`
+
+var testCreateRequestInput = Request{
+	SubnetID:  3,
+	Requester: "jdoe@example.com",
+	Comment:   "New CI capacity",
+}
+
+const testCreateRequestOutputExpected = `Request created`
+const testCreateRequestOutputJSON = `
+{
+  "code": 201,
+  "success": true,
+  "data": "Request created"
+}
+`
+
+var testGetRequestByIDOutputExpected = testListRequestsOutputExpected[0]
+
+const testGetRequestByIDOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "1",
+    "subnetId": "3",
+    "ip_addr": "10.10.1.20",
+    "description": "build agent",
+    "hostname": "build01.example.com",
+    "mac": null,
+    "state": "2",
+    "owner": null,
+    "requester": "jdoe@example.com",
+    "comment": "New CI capacity",
+    "processed": "0",
+    "accepted": null,
+    "adminComment": null
+  }
+}
+`
+
+const testProcessRequestOutputExpected = `Request updated`
+const testProcessRequestOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Request updated"
+}
+`
+
+const testDeleteRequestOutputExpected = `Request deleted`
+const testDeleteRequestOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Request deleted"
+}
+`
+
+func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(f))
+	return ts
+}
+
+func httpOKTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
+func httpCreatedTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusCreated)
+	})
+}
+
+// httpProcessRequestTestServer returns a server that responds with output,
+// recording the path and body of the request in path and body.
+func httpProcessRequestTestServer(output string, path, body *string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		*path = r.URL.Path
+		*body = string(b)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
+func fullSessionConfig() *session.Session {
+	return &session.Session{
+		Config: phpipam.Config{
+			AppID:    "0123456789abcdefgh",
+			Password: "changeit",
+			Username: "nobody",
+		},
+		Token: session.Token{
+			String: "foobarbazboop",
+		},
+	}
+}
+
+func TestRequestStatus(t *testing.T) {
+	cases := []struct {
+		in       Request
+		expected string
+	}{
+		{in: Request{}, expected: StatusPending},
+		{in: Request{Accepted: true}, expected: StatusPending},
+		{in: Request{Processed: true, Accepted: true}, expected: StatusApproved},
+		{in: Request{Processed: true}, expected: StatusRejected},
+	}
+
+	for _, tc := range cases {
+		if actual := tc.in.Status(); actual != tc.expected {
+			t.Fatalf("Expected %s for %#v, got %s", tc.expected, tc.in, actual)
+		}
+	}
+}
+
+func TestListRequests(t *testing.T) {
+	ts := httpOKTestServer(testListRequestsOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testListRequestsOutputExpected
+	actual, err := client.ListRequests()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestCreateRequest(t *testing.T) {
+	ts := httpCreatedTestServer(testCreateRequestOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testCreateRequestInput
+	expected := testCreateRequestOutputExpected
+	actual, err := client.CreateRequest(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetRequestByID(t *testing.T) {
+	ts := httpOKTestServer(testGetRequestByIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetRequestByIDOutputExpected
+	actual, err := client.GetRequestByID(1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestApproveRequest(t *testing.T) {
+	var path, body string
+	ts := httpProcessRequestTestServer(testProcessRequestOutputJSON, &path, &body)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testProcessRequestOutputExpected
+	actual, err := client.ApproveRequest(1, "approved via ticket 123")
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	expectedPath := "/0123456789abcdefgh/requests/1/"
+	if path != expectedPath {
+		t.Fatalf("Expected path %s, got %s", expectedPath, path)
+	}
+
+	expectedBody := `{"processed":"1","accepted":"1","adminComment":"approved via ticket 123"}`
+	if body != expectedBody {
+		t.Fatalf("Expected body %s, got %s", expectedBody, body)
+	}
+}
+
+func TestRejectRequest(t *testing.T) {
+	var path, body string
+	ts := httpProcessRequestTestServer(testProcessRequestOutputJSON, &path, &body)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	if _, err := client.RejectRequest(1, ""); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	expectedBody := `{"processed":"1","accepted":"0"}`
+	if body != expectedBody {
+		t.Fatalf("Expected body %s, got %s", expectedBody, body)
+	}
+}
+
+func TestDeleteRequest(t *testing.T) {
+	ts := httpOKTestServer(testDeleteRequestOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testDeleteRequestOutputExpected
+	actual, err := client.DeleteRequest(1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
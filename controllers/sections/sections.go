@@ -3,11 +3,17 @@
 package sections
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"strings"
 
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/addresses"
 	"github.com/pavel-z1/phpipam-sdk-go/controllers/subnets"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/request"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
 )
 
@@ -32,7 +38,9 @@ type Section struct {
 	// Whether or not to check consistency for subnets and IP addresses.
 	StrictMode phpipam.BoolIntString `json:"strictMode,omitempty"`
 
-	// How to order subnets in this section when viewing.
+	// How to order subnets in this section when viewing. This should be one of
+	// the SubnetOrder constants - use SetSubnetOrdering to set it with
+	// validation.
 	SubnetOrdering string `json:"subnetOrdering,omitempty"`
 
 	// The order position of this section when displaying sections.
@@ -54,6 +62,66 @@ type Section struct {
 
 	// The ID of the DNS resolver to be used for this section.
 	DNS int `json:"DNS,string,omitempty"`
+
+	// A map of custom fields to set on the resource. Note
+	// that this functionality requires PHPIPAM 1.3 or higher with the "Nest
+	// custom fields" flag set on the specific API integration. If this is not
+	// enabled, this map will be nil on GETs and POSTs and PATCHes with this
+	// field set will fail. Use the explicit custom field functions instead.
+	CustomFields phpipam.CustomFields `json:"custom_fields,omitempty"`
+}
+
+// The subnet orderings accepted by PHPIPAM for Section.SubnetOrdering.
+const (
+	SubnetOrderDefault         = "default"
+	SubnetOrderSubnetAsc       = "subnet,asc"
+	SubnetOrderSubnetDesc      = "subnet,desc"
+	SubnetOrderDescriptionAsc  = "description,asc"
+	SubnetOrderDescriptionDesc = "description,desc"
+)
+
+// ValidSubnetOrdering returns true if ordering is one of the SubnetOrder
+// constants.
+func ValidSubnetOrdering(ordering string) bool {
+	switch ordering {
+	case SubnetOrderDefault, SubnetOrderSubnetAsc, SubnetOrderSubnetDesc,
+		SubnetOrderDescriptionAsc, SubnetOrderDescriptionDesc:
+		return true
+	}
+	return false
+}
+
+// SetSubnetOrdering sets the section's SubnetOrdering, returning an error and
+// leaving it unchanged if ordering is not one of the SubnetOrder constants.
+func (s *Section) SetSubnetOrdering(ordering string) error {
+	if !ValidSubnetOrdering(ordering) {
+		return fmt.Errorf("Invalid subnet ordering %q", ordering)
+	}
+	s.SubnetOrdering = ordering
+	return nil
+}
+
+// validate checks the section for values that PHPIPAM would silently ignore.
+// An empty SubnetOrdering is accepted, as it is omitted from requests.
+func (s Section) validate() error {
+	if s.SubnetOrdering != "" && !ValidSubnetOrdering(s.SubnetOrdering) {
+		return fmt.Errorf("Invalid subnet ordering %q", s.SubnetOrdering)
+	}
+	return nil
+}
+
+// SectionNode is a section within the tree returned by GetSectionTree.
+type SectionNode struct {
+	Section
+
+	// The sections nested directly under this one.
+	Children []SectionNode
+}
+
+// PermissionsMap parses the section's Permissions via
+// phpipam.ParsePermissions, returning a map of group IDs to access levels.
+func (s Section) PermissionsMap() (map[int]int, error) {
+	return phpipam.ParsePermissions(s.Permissions)
 }
 
 // Controller is the base client for the Sections controller.
@@ -71,43 +139,478 @@ func NewController(sess *session.Session) *Controller {
 
 // ListSections lists all sections.
 func (c *Controller) ListSections() (out []Section, err error) {
-	err = c.SendRequest("GET", "/sections/", &struct{}{}, &out)
+	return c.ListSectionsWithOptions(phpipam.ListOptions{})
+}
+
+// ListSectionsWithOptions lists all sections, filtered and ordered as per the
+// supplied options.
+func (c *Controller) ListSectionsWithOptions(opts phpipam.ListOptions) (out []Section, err error) {
+	err = c.SendRequestWithParams("GET", "/sections/", opts.Values(), &struct{}{}, &out)
 	return
 }
 
-// CreateSection creates a section by sending a POST request.
+// GetSectionTree lists all sections via ListSections, and returns them as a
+// tree built from their MasterSection links. Top-level sections are returned
+// in the order that the API lists them, as are the children of each section.
+//
+// Sections whose parent does not exist are treated as top-level sections. If
+// the parent links form a cycle, the cycle is broken at the first section in
+// it that the API lists, which is returned as a top-level section.
+func (c *Controller) GetSectionTree() (out []SectionNode, err error) {
+	var list []Section
+	if list, err = c.ListSections(); err != nil {
+		return
+	}
+	out = sectionTree(list)
+	return
+}
+
+// sectionTree builds the section tree for GetSectionTree.
+func sectionTree(list []Section) []SectionNode {
+	exists := make(map[int]bool)
+	children := make(map[int][]Section)
+	for _, v := range list {
+		exists[v.ID] = true
+		children[v.MasterSection] = append(children[v.MasterSection], v)
+	}
+
+	visited := make(map[int]bool)
+	var build func(s Section) SectionNode
+	build = func(s Section) SectionNode {
+		visited[s.ID] = true
+		node := SectionNode{Section: s}
+		for _, child := range children[s.ID] {
+			if !visited[child.ID] {
+				node.Children = append(node.Children, build(child))
+			}
+		}
+		return node
+	}
+
+	var out []SectionNode
+	for _, v := range list {
+		if v.MasterSection == 0 || !exists[v.MasterSection] {
+			out = append(out, build(v))
+		}
+	}
+	// Anything not visited yet is part of a cycle.
+	for _, v := range list {
+		if !visited[v.ID] {
+			out = append(out, build(v))
+		}
+	}
+	return out
+}
+
+// CreateSection creates a section by sending a POST request. An error is
+// returned without sending the request if the section's SubnetOrdering is
+// set to an unknown value.
 func (c *Controller) CreateSection(in Section) (message string, err error) {
+	if err = in.validate(); err != nil {
+		return
+	}
 	err = c.SendRequest("POST", "/sections/", &in, &message)
 	return
 }
 
+// CreateSectionWithID creates a section by sending a POST request, returning
+// the ID of the new section. An error is returned if the API does not supply
+// the ID - the section is not looked up by name instead, as section names are
+// not guaranteed to be unique.
+func (c *Controller) CreateSectionWithID(in Section) (id int, err error) {
+	if err = in.validate(); err != nil {
+		return
+	}
+	if id, _, err = c.SendCreateRequest("/sections/", &in); err == nil && id == 0 {
+		err = errors.New("No ID returned for created section")
+	}
+	return
+}
+
 // GetSectionByID GETs a section via its ID.
 func (c *Controller) GetSectionByID(id int) (out Section, err error) {
 	err = c.SendRequest("GET", fmt.Sprintf("/sections/%d/", id), &struct{}{}, &out)
 	return
 }
 
-// GetSectionByName GETs a section via its name.
+// GetSectionByName GETs a section via its name. The name is escaped, so names
+// containing spaces or other special characters can be used.
+//
+// Note that names containing a slash are sent with the slash encoded as %2F,
+// which some web servers reject by default (ie: Apache, unless
+// AllowEncodedSlashes is enabled). If more than one section has the same name,
+// the API returns only one of them - use GetSectionsByName to get all of them.
 func (c *Controller) GetSectionByName(name string) (out Section, err error) {
-	err = c.SendRequest("GET", fmt.Sprintf("/sections/%s/", name), &struct{}{}, &out)
+	err = c.SendRequest("GET", fmt.Sprintf("/sections/%s/", url.PathEscape(name)), &struct{}{}, &out)
+	return
+}
+
+// GetSectionsByName lists all sections via ListSections, and returns the ones
+// with the supplied name. Names are compared case-insensitively, as they are
+// by the API's database.
+func (c *Controller) GetSectionsByName(name string) (out []Section, err error) {
+	var list []Section
+	if list, err = c.ListSections(); err != nil {
+		return
+	}
+	for _, v := range list {
+		if strings.EqualFold(v.Name, name) {
+			out = append(out, v)
+		}
+	}
 	return
 }
 
 // GetSubnetsInSection GETs the subnets in a section by section ID.
 func (c *Controller) GetSubnetsInSection(id int) (out []subnets.Subnet, err error) {
-	err = c.SendRequest("GET", fmt.Sprintf("/sections/%d/subnets/", id), &struct{}{}, &out)
+	return c.GetSubnetsInSectionWithOptions(id, phpipam.ListOptions{})
+}
+
+// GetSubnetsInSectionWithOptions GETs the subnets in a section by section ID,
+// filtered and ordered as per the supplied options.
+func (c *Controller) GetSubnetsInSectionWithOptions(id int, opts phpipam.ListOptions) (out []subnets.Subnet, err error) {
+	err = c.SendRequestWithParams("GET", fmt.Sprintf("/sections/%d/subnets/", id), opts.Values(), &struct{}{}, &out)
+	return
+}
+
+// GetSubnetsInSectionFiltered GETs the subnets in a section by section ID.
+// If includeFolders is false, folders are left out, returning only the
+// subnets that can hold addresses.
+func (c *Controller) GetSubnetsInSectionFiltered(id int, includeFolders bool) (out []subnets.Subnet, err error) {
+	if out, err = c.GetSubnetsInSection(id); err != nil || includeFolders {
+		return
+	}
+	out = subnets.WithoutFolders(out)
+	return
+}
+
+// GetFoldersInSection GETs the folders in a section by section ID, leaving
+// out the subnets that can hold addresses.
+func (c *Controller) GetFoldersInSection(id int) (out []subnets.Subnet, err error) {
+	if out, err = c.GetSubnetsInSection(id); err != nil {
+		return
+	}
+	out = subnets.OnlyFolders(out)
+	return
+}
+
+// GetOverlappingSubnets returns the subnets in a section whose address range
+// intersects with the supplied CIDR (ie: 10.10.1.0/24 or 2001:db8::/64). This
+// includes exact duplicates, subnets that contain the CIDR, and subnets that
+// are contained by it.
+//
+// PHPIPAM does not provide an API method for this, so the check is performed
+// client-side over the results of GetSubnetsInSection. Folders are ignored.
+func (c *Controller) GetOverlappingSubnets(cidr string, sectionID int) (out []subnets.Subnet, err error) {
+	var candidate *net.IPNet
+	if _, candidate, err = net.ParseCIDR(cidr); err != nil {
+		return nil, fmt.Errorf("Invalid CIDR %s: %s", cidr, err)
+	}
+
+	var list []subnets.Subnet
+	if list, err = c.GetSubnetsInSection(sectionID); err != nil {
+		return
+	}
+
+	for _, v := range list {
+		if v.IsFolderSubnet() {
+			continue
+		}
+		_, n, perr := net.ParseCIDR(fmt.Sprintf("%s/%d", v.SubnetAddress, v.Mask))
+		if perr != nil {
+			continue
+		}
+		if n.Contains(candidate.IP) || candidate.Contains(n.IP) {
+			out = append(out, v)
+		}
+	}
+	return
+}
+
+// SetSectionDNSDefaults applies the supplied DNS flags to every subnet in a
+// section via subnets.UpdateSubnetDNSFlags, and returns the outcome for each
+// subnet.
+//
+// Folders cannot have DNS settings, so they are skipped and noted in the
+// results. Errors for individual subnets are recorded in their respective
+// results - err is only set if the subnets in the section could not be
+// listed.
+func (c *Controller) SetSectionDNSDefaults(sectionID int, recursive, records, resolve bool) (out []subnets.BulkResult, err error) {
+	var list []subnets.Subnet
+	list, err = c.GetSubnetsInSection(sectionID)
+	if err != nil {
+		return
+	}
+
+	sc := subnets.NewController(c.Session)
+	for _, v := range list {
+		result := subnets.BulkResult{ID: v.ID}
+		if v.IsFolderSubnet() {
+			result.Skipped = true
+			result.Reason = "subnet is a folder"
+		} else {
+			result.Message, result.Error = sc.UpdateSubnetDNSFlags(v.ID, recursive, records, resolve)
+		}
+		out = append(out, result)
+	}
+	return
+}
+
+// ChangeSubnetSection moves a subnet to another section, keeping its
+// addresses. The target section is looked up first, and its error is
+// returned if it does not exist.
+//
+// This lives on the sections controller, rather than the subnets one, as the
+// subnets package can't depend on this one.
+func (c *Controller) ChangeSubnetSection(id int, sectionID int) (message string, err error) {
+	if _, err = c.GetSectionByID(sectionID); err != nil {
+		return
+	}
+	message, err = subnets.NewController(c.Session).UpdateSubnet(subnets.Subnet{
+		ID:        id,
+		SectionID: sectionID,
+	})
+	return
+}
+
+// GetSectionCustomFieldsSchema GETs the custom fields for the sections
+// controller via client.GetCustomFieldsSchema.
+func (c *Controller) GetSectionCustomFieldsSchema() (out map[string]phpipam.CustomField, err error) {
+	out, err = c.Client.GetCustomFieldsSchema("sections")
 	return
 }
 
-// UpdateSection updates a section by sending a PATCH request.
+// GetSectionCustomFields GETs the custom fields for a section via
+// client.GetCustomFields.
+func (c *Controller) GetSectionCustomFields(id int) (out map[string]interface{}, err error) {
+	out, err = c.Client.GetCustomFields(id, "sections")
+	return
+}
+
+// UpdateSectionCustomFields PATCHes the section's custom fields via
+// client.UpdateCustomFields. The fields are validated against the schema first,
+// and an error is returned if any of them are not defined.
+func (c *Controller) UpdateSectionCustomFields(id int, in map[string]interface{}) (message string, err error) {
+	message, err = c.Client.UpdateCustomFields(id, in, "sections")
+	return
+}
+
+// UpdateSection updates a section by sending a PATCH request. As with
+// CreateSection, the section's SubnetOrdering is validated first.
 func (c *Controller) UpdateSection(in Section) (err error) {
+	if err = in.validate(); err != nil {
+		return
+	}
 	err = c.SendRequest("PATCH", "/sections/", &in, &struct{}{})
 	return
 }
 
+// EnsureSection makes sure that a section matching in exists, and returns it
+// along with whether or not it was created.
+//
+// The section is looked up by its Name via GetSectionsByName, and an error is
+// returned if more than one section matches. If it does not exist, it is
+// created. Otherwise, any fields set in in that differ from the existing
+// section are updated - see phpipam.ChangedFields for how differences are
+// determined. Fields with zero values are left as they are. If the API
+// rejects the create because another client created the section in the
+// meantime, the existing section is used instead. If the API does not supply
+// the ID of a created section, it is looked up again.
+func (c *Controller) EnsureSection(in Section) (out Section, created bool, err error) {
+	if err = in.validate(); err != nil {
+		return
+	}
+	var found bool
+	if out, found, err = c.findSection(in.Name); err != nil {
+		return
+	}
+	if !found {
+		var id int
+		if id, _, err = c.SendCreateRequest("/sections/", &in); err == nil {
+			if id != 0 {
+				out, err = c.GetSectionByID(id)
+			} else if out, found, err = c.findSection(in.Name); err == nil && !found {
+				err = fmt.Errorf("Created section %s not found", in.Name)
+			}
+			return out, true, err
+		}
+		var apiErr *request.APIError
+		if !errors.As(err, &apiErr) {
+			return
+		}
+		var lerr error
+		if out, found, lerr = c.findSection(in.Name); lerr != nil || !found {
+			return
+		}
+		err = nil
+	}
+
+	var fields map[string]interface{}
+	if fields, err = phpipam.ChangedFields(in, out, "name"); err != nil || len(fields) == 0 {
+		return
+	}
+	if _, err = c.PatchFields("sections", out.ID, Section{}, fields); err != nil {
+		return
+	}
+	out, err = c.GetSectionByID(out.ID)
+	return
+}
+
+// findSection looks up the section with the supplied name. found is false if
+// there is no such section, and an error is returned if there is more than
+// one.
+func (c *Controller) findSection(name string) (out Section, found bool, err error) {
+	var list []Section
+	if list, err = c.GetSectionsByName(name); err != nil {
+		return
+	}
+	switch len(list) {
+	case 0:
+	case 1:
+		out, found = list[0], true
+	default:
+		err = fmt.Errorf("Found %d sections named %s, expected 1", len(list), name)
+	}
+	return
+}
+
 // DeleteSection deletes a section by sending a DELETE request. All subnets and
 // addresses in the section will be deleted as well.
 func (c *Controller) DeleteSection(id int) (err error) {
 	err = c.SendRequest("DELETE", fmt.Sprintf("/sections/%d/", id), &struct{}{}, &struct{}{})
 	return
 }
+
+// SectionScope wraps the subnets and addresses controllers, scoping their
+// operations to a single section so that its ID does not need to be passed to
+// every call. Creates have SectionID set automatically, and lookups only
+// return resources in the section. Use WithSection to get one.
+//
+// This is a convenience layer over the existing controller methods, and makes
+// the same requests they do.
+type SectionScope struct {
+	// The ID of the section the scope is limited to.
+	SectionID int
+
+	sections  *Controller
+	subnets   *subnets.Controller
+	addresses *addresses.Controller
+}
+
+// WithSection returns a SectionScope for the section with the supplied ID,
+// sharing this controller's session.
+func (c *Controller) WithSection(id int) *SectionScope {
+	return &SectionScope{
+		SectionID: id,
+		sections:  c,
+		subnets:   subnets.NewController(c.Session),
+		addresses: addresses.NewController(c.Session),
+	}
+}
+
+// scope sets the scope's section on a subnet, returning an error if it is
+// already set to a different section.
+func (s *SectionScope) scope(in *subnets.Subnet) error {
+	if in.SectionID != 0 && in.SectionID != s.SectionID {
+		return fmt.Errorf("Subnet is in section %d, but the scope is section %d", in.SectionID, s.SectionID)
+	}
+	in.SectionID = s.SectionID
+	return nil
+}
+
+// GetSubnets GETs the subnets and folders in the section via
+// GetSubnetsInSection.
+func (s *SectionScope) GetSubnets() ([]subnets.Subnet, error) {
+	return s.sections.GetSubnetsInSection(s.SectionID)
+}
+
+// GetSubnetsByCIDR GETs the subnets in the section matching a CIDR via
+// subnets.GetSubnetsByCIDRAndSection.
+func (s *SectionScope) GetSubnetsByCIDR(cidr string) ([]subnets.Subnet, error) {
+	return s.subnets.GetSubnetsByCIDRAndSection(cidr, s.SectionID)
+}
+
+// GetSubnetByCIDR GETs the single subnet in the section matching a CIDR via
+// subnets.GetSubnetByCIDRInSection.
+func (s *SectionScope) GetSubnetByCIDR(cidr string) (subnets.Subnet, error) {
+	return s.subnets.GetSubnetByCIDRInSection(cidr, s.SectionID)
+}
+
+// SearchSubnetsByDescription returns the subnets in the section whose
+// description contains term, ignoring case. An empty list is returned if no
+// subnets match.
+func (s *SectionScope) SearchSubnetsByDescription(term string) (out []subnets.Subnet, err error) {
+	var list []subnets.Subnet
+	if list, err = s.GetSubnets(); err != nil {
+		return
+	}
+	term = strings.ToLower(term)
+	out = []subnets.Subnet{}
+	for _, v := range list {
+		if strings.Contains(strings.ToLower(v.Description), term) {
+			out = append(out, v)
+		}
+	}
+	return
+}
+
+// CreateSubnet creates a subnet in the section via subnets.CreateSubnet.
+func (s *SectionScope) CreateSubnet(in subnets.Subnet) (message string, err error) {
+	if err = s.scope(&in); err != nil {
+		return
+	}
+	message, err = s.subnets.CreateSubnet(in)
+	return
+}
+
+// CreateSubnetWithID creates a subnet in the section via
+// subnets.CreateSubnetWithID, returning the ID of the new subnet.
+func (s *SectionScope) CreateSubnetWithID(in subnets.Subnet) (id int, err error) {
+	if err = s.scope(&in); err != nil {
+		return
+	}
+	id, err = s.subnets.CreateSubnetWithID(in)
+	return
+}
+
+// CreateChildSubnet creates child as a nested subnet of the master subnet in
+// the section matching masterCIDR via subnets.CreateChildSubnet.
+func (s *SectionScope) CreateChildSubnet(masterCIDR string, child subnets.Subnet) (message string, err error) {
+	if err = s.scope(&child); err != nil {
+		return
+	}
+	message, err = s.subnets.CreateChildSubnet(masterCIDR, s.SectionID, child)
+	return
+}
+
+// CreateFolder creates a folder in the section via subnets.CreateFolder,
+// returning the ID of the new folder.
+func (s *SectionScope) CreateFolder(name string, masterFolderID int) (int, error) {
+	return s.subnets.CreateFolder(s.SectionID, name, masterFolderID)
+}
+
+// GetAddressesByIP searches for the addresses in the section matching an IP
+// address or CIDR via addresses.GetAddressesByIP. Addresses in other
+// sections are left out, which takes an additional request to list the
+// subnets in the section.
+func (s *SectionScope) GetAddressesByIP(ipaddr string) (out []addresses.Address, err error) {
+	var all []addresses.Address
+	if all, err = s.addresses.GetAddressesByIP(ipaddr); err != nil {
+		return
+	}
+
+	var list []subnets.Subnet
+	if list, err = s.GetSubnets(); err != nil {
+		return
+	}
+	inSection := make(map[int]bool)
+	for _, v := range list {
+		inSection[v.ID] = true
+	}
+	for _, v := range all {
+		if inSection[v.SubnetID] {
+			out = append(out, v)
+		}
+	}
+	return
+}
@@ -1,16 +1,19 @@
 package sections
 
 import (
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/pavel-z1/phpipam-sdk-go/controllers/subnets"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/request"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
 	"github.com/pavel-z1/phpipam-sdk-go/testacc"
 )
@@ -411,6 +414,136 @@ const testDeleteSectionOutputJSON = `
 }
 `
 
+const testSetSectionDNSDefaultsUpdateOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Subnet updated"
+}
+`
+
+var testSetSectionDNSDefaultsOutputExpected = []subnets.BulkResult{
+	subnets.BulkResult{ID: 5, Skipped: true, Reason: "subnet is a folder"},
+	subnets.BulkResult{ID: 2, Message: "Subnet updated"},
+	subnets.BulkResult{ID: 3, Message: "Subnet updated"},
+	subnets.BulkResult{ID: 4, Message: "Subnet updated"},
+	subnets.BulkResult{ID: 6, Message: "Subnet updated"},
+}
+
+const testGetOverlappingSubnetsOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "5",
+      "subnet": "0.0.0.0",
+      "mask": "",
+      "sectionId": "1",
+      "masterSubnetId": "0",
+      "isFolder": "1"
+    },
+    {
+      "id": "2",
+      "subnet": "10.10.0.0",
+      "mask": "16",
+      "sectionId": "1",
+      "masterSubnetId": "0",
+      "isFolder": "0"
+    },
+    {
+      "id": "3",
+      "subnet": "10.10.1.0",
+      "mask": "24",
+      "sectionId": "1",
+      "masterSubnetId": "2",
+      "isFolder": "0"
+    },
+    {
+      "id": "4",
+      "subnet": "10.10.2.0",
+      "mask": "24",
+      "sectionId": "1",
+      "masterSubnetId": "2",
+      "isFolder": "0"
+    },
+    {
+      "id": "7",
+      "subnet": "2001:db8::",
+      "mask": "64",
+      "sectionId": "1",
+      "masterSubnetId": "0",
+      "isFolder": "0"
+    }
+  ]
+}
+`
+
+const testCreateSectionWithIDOutputJSON = `
+{
+  "code": 201,
+  "success": true,
+  "message": "Section created",
+  "id": "42",
+  "data": "Section created"
+}
+`
+
+var testGetSectionCustomFieldsSchemaExpected = map[string]phpipam.CustomField{
+	"CustomTestSections": phpipam.CustomField{
+		Name:    "CustomTestSections",
+		Type:    "varchar(255)",
+		Comment: "Test field for sections controller",
+		Null:    "YES",
+		Default: "",
+	},
+}
+
+const testGetSectionCustomFieldsSchemaJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "CustomTestSections": {
+      "name": "CustomTestSections",
+      "type": "varchar(255)",
+      "Comment": "Test field for sections controller",
+      "Null": "YES",
+      "Default": ""
+    }
+  }
+}
+`
+
+var testGetSectionCustomFieldsExpected = map[string]interface{}{
+	"CustomTestSections": "team-a",
+}
+
+const testGetSectionCustomFieldsJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "1",
+    "name": "Customers",
+    "CustomTestSections": "team-a"
+  }
+}
+`
+
+// httpSectionCustomFieldsTestServer returns a server that responds with the
+// section custom field schema, or output for any other request.
+func httpSectionCustomFieldsTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/custom_fields/") {
+			http.Error(w, testGetSectionCustomFieldsSchemaJSON, http.StatusOK)
+			return
+		}
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
 func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
 	ts := httptest.NewServer(http.HandlerFunc(f))
 	return ts
@@ -430,6 +563,54 @@ func httpCreatedTestServer(output string) *httptest.Server {
 	})
 }
 
+func httpSetSectionDNSDefaultsTestServer() *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		switch r.Method {
+		case "GET":
+			http.Error(w, testGetSubnetsInSectionOutputJSON, http.StatusOK)
+		default:
+			http.Error(w, testSetSectionDNSDefaultsUpdateOutputJSON, http.StatusOK)
+		}
+	})
+}
+
+const testChangeSubnetSectionOutputExpected = `Subnet updated`
+const testChangeSubnetSectionOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Subnet updated"
+}
+`
+
+const testSectionNotFoundOutputJSON = `
+{
+  "code": 404,
+  "success": false,
+  "message": "Section does not exist"
+}
+`
+
+// httpChangeSubnetSectionTestServer returns a server that responds to section
+// lookups with section, or a 404 if section is empty, and records the body of
+// any subnet update in body.
+func httpChangeSubnetSectionTestServer(section string, body *string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		switch {
+		case r.Method == "GET" && section == "":
+			http.Error(w, testSectionNotFoundOutputJSON, http.StatusNotFound)
+		case r.Method == "GET":
+			http.Error(w, section, http.StatusOK)
+		default:
+			b, _ := ioutil.ReadAll(r.Body)
+			*body = string(b)
+			http.Error(w, testChangeSubnetSectionOutputJSON, http.StatusOK)
+		}
+	})
+}
+
 func fullSessionConfig() *session.Session {
 	return &session.Session{
 		Config: phpipam.Config{
@@ -480,6 +661,205 @@ func TestCreateSection(t *testing.T) {
 	}
 }
 
+func TestSetSubnetOrdering(t *testing.T) {
+	var s Section
+	if err := s.SetSubnetOrdering(SubnetOrderDescriptionAsc); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if s.SubnetOrdering != "description,asc" {
+		t.Fatalf("Expected subnet ordering description,asc, got %s", s.SubnetOrdering)
+	}
+
+	if err := s.SetSubnetOrdering("descripton,asc"); err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+	if s.SubnetOrdering != "description,asc" {
+		t.Fatalf("Expected subnet ordering to be unchanged, got %s", s.SubnetOrdering)
+	}
+}
+
+func TestCreateSectionInvalidSubnetOrdering(t *testing.T) {
+	var requests int
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testCreateSectionOutputJSON, http.StatusCreated)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testCreateSectionInput
+	in.SubnetOrdering = "vlan"
+	if _, err := client.CreateSection(in); err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+	if err := client.UpdateSection(in); err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+	if requests != 0 {
+		t.Fatalf("Expected no requests, got %d", requests)
+	}
+}
+
+// httpEnsureSectionTestServer returns a server that responds to section
+// listings with the sections in existing, and to lookups by ID with a section
+// with that ID. How creates are handled depends on create: "conflict" fails
+// them with a 409 as if another client had created the section with ID 8,
+// "noid" creates the section with ID 9 without returning the ID, and anything
+// else creates it with ID 9. The method and path of each request are recorded
+// in calls, and the body of any PATCH in body.
+func httpEnsureSectionTestServer(existing []string, create string, calls *[]string, body *string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/0123456789abcdefgh")
+		*calls = append(*calls, r.Method+" "+path)
+		switch {
+		case r.Method == "POST" && create == "conflict":
+			existing = append(existing, `{"id":"8","name":"Customers"}`)
+			http.Error(w, `{"code":409,"success":false,"message":"Section Customers already exists"}`, http.StatusConflict)
+		case r.Method == "POST" && create == "noid":
+			existing = append(existing, `{"id":"9","name":"Customers","description":"Customer networks","subnetOrdering":"subnet,asc"}`)
+			http.Error(w, `{"code":201,"success":true,"data":"Section created"}`, http.StatusCreated)
+		case r.Method == "POST":
+			http.Error(w, `{"code":201,"success":true,"id":"9","data":"Section created"}`, http.StatusCreated)
+		case r.Method == "PATCH":
+			b, _ := ioutil.ReadAll(r.Body)
+			*body = string(b)
+			http.Error(w, testUpdateSectionOutputJSON, http.StatusOK)
+		case path == "/sections/":
+			http.Error(w, `{"code":200,"success":true,"data":[`+strings.Join(existing, ",")+`]}`, http.StatusOK)
+		default:
+			id := strings.Trim(strings.TrimPrefix(path, "/sections/"), "/")
+			http.Error(w, `{"code":200,"success":true,"data":{"id":"`+id+`","name":"Customers"}}`, http.StatusOK)
+		}
+	})
+}
+
+func TestEnsureSection(t *testing.T) {
+	in := Section{
+		Name:           "Customers",
+		Description:    "Customer networks",
+		SubnetOrdering: SubnetOrderSubnetAsc,
+	}
+
+	cases := []struct {
+		name            string
+		existing        []string
+		create          string
+		expectedCreated bool
+		expectedID      int
+		expectedCalls   []string
+		expectedBody    string
+	}{
+		{
+			name:            "create",
+			existing:        []string{`{"id":"1","name":"IPv4"}`},
+			expectedCreated: true,
+			expectedID:      9,
+			expectedCalls:   []string{"GET /sections/", "POST /sections/", "GET /sections/9/"},
+		},
+		{
+			name:            "create without ID",
+			existing:        []string{`{"id":"1","name":"IPv4"}`},
+			create:          "noid",
+			expectedCreated: true,
+			expectedID:      9,
+			expectedCalls:   []string{"GET /sections/", "POST /sections/", "GET /sections/"},
+		},
+		{
+			name:          "created concurrently",
+			existing:      []string{`{"id":"1","name":"IPv4"}`},
+			create:        "conflict",
+			expectedID:    8,
+			expectedCalls: []string{"GET /sections/", "POST /sections/", "GET /sections/", "PATCH /sections/", "GET /sections/8/"},
+			expectedBody:  `{"description":"Customer networks","id":8,"subnetOrdering":"subnet,asc"}`,
+		},
+		{
+			name: "unchanged",
+			existing: []string{
+				`{"id":"1","name":"IPv4"}`,
+				`{"id":"7","name":"customers","description":"Customer networks","subnetOrdering":"subnet,asc"}`,
+			},
+			expectedID:    7,
+			expectedCalls: []string{"GET /sections/"},
+		},
+		{
+			name:          "drifted",
+			existing:      []string{`{"id":"7","name":"Customers","description":"Customer networks","subnetOrdering":"default"}`},
+			expectedID:    7,
+			expectedCalls: []string{"GET /sections/", "PATCH /sections/", "GET /sections/7/"},
+			expectedBody:  `{"id":7,"subnetOrdering":"subnet,asc"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			var body string
+			ts := httpEnsureSectionTestServer(tc.existing, tc.create, &calls, &body)
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewController(sess)
+
+			out, created, err := client.EnsureSection(in)
+			if err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+			if created != tc.expectedCreated {
+				t.Fatalf("Expected created to be %t, got %t", tc.expectedCreated, created)
+			}
+			if out.ID != tc.expectedID {
+				t.Fatalf("Expected ID %d, got %d", tc.expectedID, out.ID)
+			}
+			if !reflect.DeepEqual(tc.expectedCalls, calls) {
+				t.Fatalf("Expected calls %v, got %v", tc.expectedCalls, calls)
+			}
+			if body != tc.expectedBody {
+				t.Fatalf("Expected body %s, got %s", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestEnsureSectionAmbiguous(t *testing.T) {
+	var calls []string
+	var body string
+	ts := httpEnsureSectionTestServer([]string{
+		`{"id":"6","name":"Customers"}`,
+		`{"id":"7","name":"customers"}`,
+	}, "", &calls, &body)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	_, _, err := client.EnsureSection(Section{Name: "Customers"})
+	if err == nil || !strings.Contains(err.Error(), "Found 2 sections named Customers") {
+		t.Fatalf("Expected ambiguous section error, got %v", err)
+	}
+}
+
+func TestCreateSectionWithID(t *testing.T) {
+	ts := httpCreatedTestServer(testCreateSectionWithIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := 42
+	actual, err := client.CreateSectionWithID(testCreateSectionInput)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if expected != actual {
+		t.Fatalf("Expected %d, got %d", expected, actual)
+	}
+}
+
 func TestGetSectionByID(t *testing.T) {
 	ts := httpOKTestServer(testGetSectionOutputJSON)
 	defer ts.Close()
@@ -516,6 +896,181 @@ func TestGetSectionByName(t *testing.T) {
 	}
 }
 
+func TestGetSectionByNameEscaped(t *testing.T) {
+	cases := []struct {
+		name         string
+		expectedPath string
+	}{
+		{name: "Data Center 1", expectedPath: "/0123456789abcdefgh/sections/Data%20Center%201/"},
+		{name: "DC/1", expectedPath: "/0123456789abcdefgh/sections/DC%2F1/"},
+	}
+
+	for _, tc := range cases {
+		var path string
+		ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.EscapedPath()
+			w.Header().Add("Content-Type", "application/json")
+			http.Error(w, testGetSectionOutputJSON, http.StatusOK)
+		})
+		sess := fullSessionConfig()
+		sess.Config.Endpoint = ts.URL
+		client := NewController(sess)
+
+		_, err := client.GetSectionByName(tc.name)
+		ts.Close()
+		if err != nil {
+			t.Fatalf("%s: Bad: %s", tc.name, err)
+		}
+		if path != tc.expectedPath {
+			t.Fatalf("%s: expected path %s, got %s", tc.name, tc.expectedPath, path)
+		}
+	}
+}
+
+const testGetSectionsByNameOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "4",
+      "name": "Data Center 1"
+    },
+    {
+      "id": "5",
+      "name": "Data Center 2"
+    },
+    {
+      "id": "6",
+      "name": "data center 1"
+    }
+  ]
+}
+`
+
+func TestGetSectionsByName(t *testing.T) {
+	ts := httpOKTestServer(testGetSectionsByNameOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := []Section{
+		Section{ID: 4, Name: "Data Center 1"},
+		Section{ID: 6, Name: "data center 1"},
+	}
+	actual, err := client.GetSectionsByName("Data Center 1")
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+const testGetSectionTreeOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "1",
+      "name": "Customers",
+      "masterSection": "0"
+    },
+    {
+      "id": "2",
+      "name": "Customer A",
+      "masterSection": "1"
+    },
+    {
+      "id": "3",
+      "name": "Customer A Lab",
+      "masterSection": "2"
+    },
+    {
+      "id": "4",
+      "name": "Customer B",
+      "masterSection": "1"
+    },
+    {
+      "id": "5",
+      "name": "Orphan",
+      "masterSection": "99"
+    },
+    {
+      "id": "6",
+      "name": "Cycle A",
+      "masterSection": "7"
+    },
+    {
+      "id": "7",
+      "name": "Cycle B",
+      "masterSection": "6"
+    },
+    {
+      "id": "8",
+      "name": "Self",
+      "masterSection": "8"
+    }
+  ]
+}
+`
+
+var testGetSectionTreeOutputExpected = []SectionNode{
+	SectionNode{
+		Section: Section{ID: 1, Name: "Customers"},
+		Children: []SectionNode{
+			SectionNode{
+				Section: Section{ID: 2, Name: "Customer A", MasterSection: 1},
+				Children: []SectionNode{
+					SectionNode{Section: Section{ID: 3, Name: "Customer A Lab", MasterSection: 2}},
+				},
+			},
+			SectionNode{Section: Section{ID: 4, Name: "Customer B", MasterSection: 1}},
+		},
+	},
+	SectionNode{Section: Section{ID: 5, Name: "Orphan", MasterSection: 99}},
+	SectionNode{
+		Section: Section{ID: 6, Name: "Cycle A", MasterSection: 7},
+		Children: []SectionNode{
+			SectionNode{Section: Section{ID: 7, Name: "Cycle B", MasterSection: 6}},
+		},
+	},
+	SectionNode{Section: Section{ID: 8, Name: "Self", MasterSection: 8}},
+}
+
+func TestSectionPermissionsMap(t *testing.T) {
+	expected := map[int]int{3: phpipam.PermissionRead, 2: phpipam.PermissionReadWrite}
+	actual, err := testListSectionsOutputExpected[0].PermissionsMap()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetSectionTree(t *testing.T) {
+	ts := httpOKTestServer(testGetSectionTreeOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSectionTreeOutputExpected
+	actual, err := client.GetSectionTree()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
 func TestGetSubnetsInSection(t *testing.T) {
 	ts := httpOKTestServer(testGetSubnetsInSectionOutputJSON)
 	defer ts.Close()
@@ -534,6 +1089,244 @@ func TestGetSubnetsInSection(t *testing.T) {
 	}
 }
 
+func TestGetSubnetsInSectionWithOptions(t *testing.T) {
+	var uri string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		uri = r.URL.RequestURI()
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testGetSubnetsInSectionOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	opts := phpipam.ListOptions{
+		FilterBy:    "isFolder",
+		FilterValue: "0",
+		OrderBy:     "description",
+		Order:       "asc",
+	}
+	expected := testGetSubnetsInSectionExpected
+	actual, err := client.GetSubnetsInSectionWithOptions(1, opts)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	expectedURI := "/0123456789abcdefgh/sections/1/subnets/?filter_by=isFolder&filter_value=0&order=asc&order_by=description"
+	if uri != expectedURI {
+		t.Fatalf("Expected URI %s, got %s", expectedURI, uri)
+	}
+}
+
+func TestGetSubnetsInSectionFiltered(t *testing.T) {
+	ts := httpOKTestServer(testGetSubnetsInSectionOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSubnetsInSectionExpected
+	actual, err := client.GetSubnetsInSectionFiltered(1, true)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	expected = testGetSubnetsInSectionExpected[1:]
+	actual, err = client.GetSubnetsInSectionFiltered(1, false)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetFoldersInSection(t *testing.T) {
+	ts := httpOKTestServer(testGetSubnetsInSectionOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSubnetsInSectionExpected[:1]
+	actual, err := client.GetFoldersInSection(1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetOverlappingSubnets(t *testing.T) {
+	ts := httpOKTestServer(testGetOverlappingSubnetsOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	cases := []struct {
+		Name     string
+		CIDR     string
+		Expected []int
+	}{
+		{Name: "exact duplicate", CIDR: "10.10.1.0/24", Expected: []int{2, 3}},
+		{Name: "contained", CIDR: "10.10.2.128/25", Expected: []int{2, 4}},
+		{Name: "containing", CIDR: "10.0.0.0/8", Expected: []int{2, 3, 4}},
+		{Name: "no overlap", CIDR: "192.168.0.0/24", Expected: nil},
+		{Name: "IPv6", CIDR: "2001:db8::/56", Expected: []int{7}},
+		{Name: "IPv6 no overlap", CIDR: "2001:db9::/64", Expected: nil},
+	}
+
+	for _, tc := range cases {
+		out, err := client.GetOverlappingSubnets(tc.CIDR, 1)
+		if err != nil {
+			t.Fatalf("%s: Bad: %s", tc.Name, err)
+		}
+		var actual []int
+		for _, v := range out {
+			actual = append(actual, v.ID)
+		}
+		if !reflect.DeepEqual(tc.Expected, actual) {
+			t.Fatalf("%s: Expected %#v, got %#v", tc.Name, tc.Expected, actual)
+		}
+	}
+}
+
+func TestGetOverlappingSubnetsInvalidCIDR(t *testing.T) {
+	ts := httpOKTestServer(testGetOverlappingSubnetsOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	if _, err := client.GetOverlappingSubnets("10.10.1.0", 1); err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+}
+
+func TestSetSectionDNSDefaults(t *testing.T) {
+	ts := httpSetSectionDNSDefaultsTestServer()
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testSetSectionDNSDefaultsOutputExpected
+	actual, err := client.SetSectionDNSDefaults(1, true, true, false)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %s, got %s", spew.Sdump(expected), spew.Sdump(actual))
+	}
+}
+
+func TestChangeSubnetSection(t *testing.T) {
+	var body string
+	ts := httpChangeSubnetSectionTestServer(testGetSectionOutputJSON, &body)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testChangeSubnetSectionOutputExpected
+	actual, err := client.ChangeSubnetSection(8, 1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	expectedBody := `{"id":"8","sectionId":"1"}`
+	if body != expectedBody {
+		t.Fatalf("Expected body %s, got %s", expectedBody, body)
+	}
+}
+
+func TestChangeSubnetSectionNotFound(t *testing.T) {
+	var body string
+	ts := httpChangeSubnetSectionTestServer("", &body)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	_, err := client.ChangeSubnetSection(8, 5)
+	if !request.IsNotFound(err) {
+		t.Fatalf("Expected not found error, got %v", err)
+	}
+
+	if body != "" {
+		t.Fatalf("Expected no subnet update, got %s", body)
+	}
+}
+
+func TestGetSectionCustomFieldsSchema(t *testing.T) {
+	ts := httpOKTestServer(testGetSectionCustomFieldsSchemaJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSectionCustomFieldsSchemaExpected
+	actual, err := client.GetSectionCustomFieldsSchema()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetSectionCustomFields(t *testing.T) {
+	ts := httpSectionCustomFieldsTestServer(testGetSectionCustomFieldsJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSectionCustomFieldsExpected
+	actual, err := client.GetSectionCustomFields(1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestUpdateSectionCustomFieldsIllegalField(t *testing.T) {
+	ts := httpSectionCustomFieldsTestServer(testUpdateSectionOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	_, err := client.UpdateSectionCustomFields(1, map[string]interface{}{"Owner": "team-a"})
+	if err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+
+	expected := "Custom field Owner not found in schema for controller sections"
+	if err.Error() != expected {
+		t.Fatalf("Expected %q, got %q", expected, err.Error())
+	}
+}
+
 func TestUpdateSection(t *testing.T) {
 	ts := httpOKTestServer(testUpdateSectionOutputJSON)
 	defer ts.Close()
@@ -560,6 +1353,134 @@ func TestDeleteSection(t *testing.T) {
 	}
 }
 
+// httpSectionScopeTestServer returns a server with subnets 3 and 4 in
+// section 1, subnet 7 in section 2, and an address matching 10.10.1.10 in
+// each of subnets 3 and 7. The body of any POST is recorded in body.
+func httpSectionScopeTestServer(body *string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/0123456789abcdefgh")
+		switch {
+		case r.Method == "POST":
+			b, _ := ioutil.ReadAll(r.Body)
+			*body = string(b)
+			http.Error(w, `{"code":201,"success":true,"id":"9","data":"Subnet created"}`, http.StatusCreated)
+		case path == "/sections/1/subnets/":
+			http.Error(w, `{"code":200,"success":true,"data":[`+
+				`{"id":"3","subnet":"10.10.1.0","mask":"24","sectionId":"1","description":"Web servers"},`+
+				`{"id":"4","sectionId":"1","description":"Site A","isFolder":"1"}]}`, http.StatusOK)
+		case path == "/subnets/cidr/10.10.1.0/24/":
+			http.Error(w, `{"code":200,"success":true,"data":[`+
+				`{"id":"3","subnet":"10.10.1.0","mask":"24","sectionId":"1"},`+
+				`{"id":"7","subnet":"10.10.1.0","mask":"24","sectionId":"2"}]}`, http.StatusOK)
+		case path == "/addresses/search/10.10.1.10/":
+			http.Error(w, `{"code":200,"success":true,"data":[`+
+				`{"id":"11","subnetId":"3","ip":"10.10.1.10"},`+
+				`{"id":"12","subnetId":"7","ip":"10.10.1.10"}]}`, http.StatusOK)
+		default:
+			http.Error(w, `{"code":404,"success":false,"message":"Not found"}`, http.StatusNotFound)
+		}
+	})
+}
+
+func TestSectionScopeGetSubnetsByCIDR(t *testing.T) {
+	var body string
+	ts := httpSectionScopeTestServer(&body)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	scope := NewController(sess).WithSection(1)
+
+	actual, err := scope.GetSubnetsByCIDR("10.10.1.0/24")
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if len(actual) != 1 || actual[0].ID != 3 {
+		t.Fatalf("Expected subnet 3 only, got %#v", actual)
+	}
+
+	subnet, err := scope.GetSubnetByCIDR("10.10.1.0/24")
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if subnet.ID != 3 {
+		t.Fatalf("Expected subnet 3, got %d", subnet.ID)
+	}
+}
+
+func TestSectionScopeSearchSubnetsByDescription(t *testing.T) {
+	var body string
+	ts := httpSectionScopeTestServer(&body)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	scope := NewController(sess).WithSection(1)
+
+	actual, err := scope.SearchSubnetsByDescription("web")
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if len(actual) != 1 || actual[0].ID != 3 {
+		t.Fatalf("Expected subnet 3 only, got %#v", actual)
+	}
+}
+
+func TestSectionScopeCreateSubnet(t *testing.T) {
+	var body string
+	ts := httpSectionScopeTestServer(&body)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	scope := NewController(sess).WithSection(1)
+
+	id, err := scope.CreateSubnetWithID(subnets.Subnet{SubnetAddress: "10.10.2.0", Mask: 24})
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if id != 9 {
+		t.Fatalf("Expected ID 9, got %d", id)
+	}
+	expected := `{"subnet":"10.10.2.0","mask":"24","sectionId":"1"}`
+	if body != expected {
+		t.Fatalf("Expected body %s, got %s", expected, body)
+	}
+}
+
+func TestSectionScopeCreateSubnetOtherSection(t *testing.T) {
+	var body string
+	ts := httpSectionScopeTestServer(&body)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	scope := NewController(sess).WithSection(1)
+
+	_, err := scope.CreateSubnet(subnets.Subnet{SubnetAddress: "10.10.2.0", Mask: 24, SectionID: 2})
+	expected := "Subnet is in section 2, but the scope is section 1"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Expected error %q, got %v", expected, err)
+	}
+	if body != "" {
+		t.Fatalf("Expected no request, got %s", body)
+	}
+}
+
+func TestSectionScopeGetAddressesByIP(t *testing.T) {
+	var body string
+	ts := httpSectionScopeTestServer(&body)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	scope := NewController(sess).WithSection(1)
+
+	actual, err := scope.GetAddressesByIP("10.10.1.10")
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if len(actual) != 1 || actual[0].ID != 11 {
+		t.Fatalf("Expected address 11 only, got %#v", actual)
+	}
+}
+
 // testAccSectionsCRUDCreate tests the creation part of the sections controller
 // CRUD acceptance test.
 func testAccSectionsCRUDCreate(t *testing.T, sess *session.Session, s Section) {
@@ -3,14 +3,30 @@
 package subnets
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/pavel-z1/phpipam-sdk-go/controllers/addresses"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/request"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
 )
 
+// batchWorkers is the maximum number of concurrent requests made by batch
+// methods such as GetAddressesInSubnetWithCustomFields.
+const batchWorkers = 8
+
 // Subnet represents a PHPIPAM subnet.
 type Subnet struct {
 	// The subnet ID.
@@ -57,6 +73,9 @@ type Subnet struct {
 	// Controls if DNS hostname records are displayed.
 	DNSRecords phpipam.BoolIntString `json:"DNSrecords,omitempty"`
 
+	// Controls if hostnames should be resolved for addresses in the subnet.
+	ResolveDNS phpipam.BoolIntString `json:"resolveDNS,omitempty"`
+
 	// Controls if IP requests are allowed for the subnet.
 	AllowRequests phpipam.BoolIntString `json:"allowRequests,omitempty"`
 
@@ -69,7 +88,9 @@ type Subnet struct {
 	// Controls if new hosts should be discovered for new host scans.
 	DiscoverSubnet phpipam.BoolIntString `json:"discoverSubnet,omitempty"`
 
-	// Controls if we are adding a subnet or folder.
+	// Controls if we are adding a subnet or folder. Note that for folders,
+	// fields that only apply to actual subnets, such as SubnetAddress and
+	// Mask, are empty.
 	IsFolder phpipam.BoolIntString `json:"isFolder,omitempty"`
 
 	// Marks the subnet as used.
@@ -90,12 +111,266 @@ type Subnet struct {
 	// Gateway IP ID
 	GatewayID  string `json:"gatewayId,omitempty"`
 
-	// A map[string]interface{} of custom fields to set on the resource. Note
+	// The nameserver set assigned to the subnet via NameserverID, as returned
+	// by the API on GETs. This is nil if the subnet has no nameserver set. Use
+	// NameserverIPs and NameserverName to read it.
+	Nameservers map[string]interface{} `json:"nameservers,omitempty"`
+
+	// A map of custom fields to set on the resource. Note
 	// that this functionality requires PHPIPAM 1.3 or higher with the "Nest
 	// custom fields" flag set on the specific API integration. If this is not
 	// enabled, this map will be nil on GETs and POSTs and PATCHes with this
 	// field set will fail. Use the explicit custom field functions instead.
-	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+	CustomFields phpipam.CustomFields `json:"custom_fields,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler for the Subnet type. The API
+// returns the LinkedSubnet, VLANID, VRFID and MasterSubnetID fields as null,
+// "" or an unquoted number on some servers, which all decode to their int
+// value, with null and "" decoding to zero.
+func (s *Subnet) UnmarshalJSON(b []byte) error {
+	type subnet Subnet
+	in := struct {
+		*subnet
+		LinkedSubnet   phpipam.JSONIntString `json:"linked_subnet"`
+		VLANID         phpipam.JSONIntString `json:"vlanId"`
+		VRFID          phpipam.JSONIntString `json:"vrfId"`
+		MasterSubnetID phpipam.JSONIntString `json:"masterSubnetId"`
+	}{
+		subnet:         (*subnet)(s),
+		LinkedSubnet:   phpipam.JSONIntString(s.LinkedSubnet),
+		VLANID:         phpipam.JSONIntString(s.VLANID),
+		VRFID:          phpipam.JSONIntString(s.VRFID),
+		MasterSubnetID: phpipam.JSONIntString(s.MasterSubnetID),
+	}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	s.LinkedSubnet = int(in.LinkedSubnet)
+	s.VLANID = int(in.VLANID)
+	s.VRFID = int(in.VRFID)
+	s.MasterSubnetID = int(in.MasterSubnetID)
+	return nil
+}
+
+// SubnetCalculation represents the network calculation PHPIPAM supplies with
+// a subnet, as shown by its IP calculator. The API returns this with
+// different keys for IPv4 and IPv6 subnets, which are normalized here.
+type SubnetCalculation struct {
+	// The address type, either IPv4 or IPv6.
+	Type string
+
+	// The network address of the subnet (i.e. 10.10.1.0 or 2001:db8::).
+	Network string
+
+	// The subnet's mask in number of bits (i.e. 24).
+	Bitmask int
+
+	// The subnet's mask in dotted quad format (i.e. 255.255.255.0). This is
+	// empty for IPv6 subnets.
+	Netmask string
+
+	// The broadcast address of the subnet. This is empty for IPv6 subnets,
+	// which have no broadcast address.
+	Broadcast string
+
+	// The first usable host address in the subnet.
+	MinHostIP string
+
+	// The last usable host address in the subnet.
+	MaxHostIP string
+
+	// The number of usable host addresses in the subnet. This can exceed the
+	// range of an int for IPv6 subnets - use HostCount to read it.
+	NumberOfHosts json.Number
+}
+
+// subnetCalculationJSON is the calculation object as supplied by the API.
+type subnetCalculationJSON struct {
+	Type          string      `json:"Type"`
+	Network       string      `json:"Network"`
+	Prefix        string      `json:"Subnet prefix"`
+	Bitmask       json.Number `json:"Subnet bitmask"`
+	PrefixLength  json.Number `json:"Prefix length"`
+	Netmask       string      `json:"Subnet netmask"`
+	Broadcast     string      `json:"Broadcast"`
+	MinHostIP     string      `json:"Min host IP"`
+	MaxHostIP     string      `json:"Max host IP"`
+	NumberOfHosts json.Number `json:"Number of hosts"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler for the SubnetCalculation type.
+func (sc *SubnetCalculation) UnmarshalJSON(b []byte) error {
+	var in subnetCalculationJSON
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	out := SubnetCalculation{
+		Type:          in.Type,
+		Network:       in.Network,
+		Netmask:       in.Netmask,
+		Broadcast:     in.Broadcast,
+		MinHostIP:     in.MinHostIP,
+		MaxHostIP:     in.MaxHostIP,
+		NumberOfHosts: in.NumberOfHosts,
+	}
+	bits := in.Bitmask
+	if in.Prefix != "" {
+		parts := strings.SplitN(in.Prefix, "/", 2)
+		out.Network = parts[0]
+		if bits == "" && len(parts) == 2 {
+			bits = json.Number(parts[1])
+		}
+	}
+	if bits == "" {
+		bits = in.PrefixLength
+	}
+	if bits != "" {
+		n, err := bits.Int64()
+		if err != nil {
+			return fmt.Errorf("Invalid subnet bitmask %q: %s", bits, err)
+		}
+		out.Bitmask = int(n)
+	}
+	*sc = out
+	return nil
+}
+
+// HostCount returns NumberOfHosts as a big.Int. nil is returned if the API
+// did not supply a valid number of hosts.
+func (sc SubnetCalculation) HostCount() *big.Int {
+	n, ok := new(big.Int).SetString(sc.NumberOfHosts.String(), 10)
+	if !ok {
+		return nil
+	}
+	return n
+}
+
+// SubnetWithCalculation represents a PHPIPAM subnet along with its network
+// calculation.
+type SubnetWithCalculation struct {
+	Subnet
+
+	// The network calculation for the subnet.
+	Calculation SubnetCalculation `json:"calculation"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler for the SubnetWithCalculation
+// type. This is needed as the embedded Subnet's UnmarshalJSON would otherwise
+// skip the calculation.
+func (sc *SubnetWithCalculation) UnmarshalJSON(b []byte) error {
+	if err := sc.Subnet.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	in := struct {
+		Calculation *SubnetCalculation `json:"calculation"`
+	}{
+		Calculation: &sc.Calculation,
+	}
+	return json.Unmarshal(b, &in)
+}
+
+// BulkResult represents the outcome of a single subnet operation performed as
+// part of a bulk request.
+type BulkResult struct {
+	// The ID of the subnet the operation was performed on.
+	ID int
+
+	// The message returned by the API, if the operation succeeded.
+	Message string
+
+	// true if the subnet was skipped, such as in the case of folders. Reason
+	// will be set with the cause.
+	Skipped bool
+
+	// The reason the subnet was skipped.
+	Reason string
+
+	// The error for this subnet, if the operation failed.
+	Error error
+}
+
+// IsFolderSubnet returns true if the subnet is a folder, rather than an actual
+// subnet. Folders have no address or mask, so they should not be treated as
+// subnets covering any address space.
+func (s Subnet) IsFolderSubnet() bool {
+	return bool(s.IsFolder)
+}
+
+// GatewayAddress returns the IP address of the subnet's gateway, as supplied
+// in the Gateway object. An empty string is returned if the subnet has no
+// gateway.
+func (s Subnet) GatewayAddress() string {
+	ip, _ := phpipam.CustomFields(s.Gateway).GetString("ip_addr")
+	return ip
+}
+
+// GatewayAddressID returns the address ID of the subnet's gateway. This is
+// taken from the Gateway object if it has one, otherwise from GatewayID, as
+// some versions of PHPIPAM only supply the latter. Zero is returned if the
+// subnet has no gateway.
+func (s Subnet) GatewayAddressID() int {
+	if id, ok := phpipam.CustomFields(s.Gateway).GetInt("id"); ok {
+		return id
+	}
+	id, _ := strconv.Atoi(s.GatewayID)
+	return id
+}
+
+// NameserverIPs returns the nameservers in the subnet's nameserver set. The
+// API returns these separated by semicolons, which are split here. nil is
+// returned if the subnet has no nameserver set.
+func (s Subnet) NameserverIPs() (out []string) {
+	servers, _ := phpipam.CustomFields(s.Nameservers).GetString("namesrv1")
+	for _, v := range strings.Split(servers, ";") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return
+}
+
+// NameserverName returns the name of the subnet's nameserver set. An empty
+// string is returned if the subnet has no nameserver set.
+func (s Subnet) NameserverName() string {
+	name, _ := phpipam.CustomFields(s.Nameservers).GetString("name")
+	return name
+}
+
+// EditDateTime parses the subnet's EditDate via phpipam.ParseDateTime, in the
+// server's time zone loc. If loc is nil, the server is assumed to be in the
+// local time zone. ok is false, with a zero time, if the subnet has no edit
+// date.
+func (s Subnet) EditDateTime(loc *time.Location) (t time.Time, ok bool, err error) {
+	return phpipam.ParseDateTime(s.EditDate, loc)
+}
+
+// WithoutFolders returns the subnets in list that are not folders.
+func WithoutFolders(list []Subnet) (out []Subnet) {
+	for _, v := range list {
+		if !v.IsFolderSubnet() {
+			out = append(out, v)
+		}
+	}
+	return
+}
+
+// OnlyFolders returns the subnets in list that are folders.
+func OnlyFolders(list []Subnet) (out []Subnet) {
+	for _, v := range list {
+		if v.IsFolderSubnet() {
+			out = append(out, v)
+		}
+	}
+	return
+}
+
+// PermissionsMap parses the subnet's Permissions via
+// phpipam.ParsePermissions, returning a map of group IDs to access levels.
+func (s Subnet) PermissionsMap() (map[int]int, error) {
+	return phpipam.ParsePermissions(s.Permissions)
 }
 
 // Controller is the base client for the Subnets controller.
@@ -117,6 +392,84 @@ func (c *Controller) CreateSubnet(in Subnet) (message string, err error) {
 	return
 }
 
+// CreateSubnetWithID creates a subnet by sending a POST request, returning the ID of
+// the new subnet. An error is returned if the API does not supply the ID.
+func (c *Controller) CreateSubnetWithID(in Subnet) (id int, err error) {
+	if id, _, err = c.SendCreateRequest("/subnets/", &in); err == nil && id == 0 {
+		err = errors.New("No ID returned for created subnet")
+	}
+	return
+}
+
+// CreateChildSubnet creates child as a nested subnet of the master subnet
+// matching masterCIDR in the section with the supplied ID, by sending a POST
+// request.
+//
+// The master subnet is looked up via GetSubnetByCIDRInSection and its ID is
+// set as child's MasterSubnetID, so callers can carve a subnet out of its
+// parent (i.e. 10.0.1.0/25 out of 10.0.1.0/24) without knowing the parent's
+// ID. If child has no SectionID, it is set to sectionID. A not found
+// *request.APIError is returned if the master subnet does not exist, and an
+// error is returned without sending the POST if child does not fit inside it.
+func (c *Controller) CreateChildSubnet(masterCIDR string, sectionID int, child Subnet) (message string, err error) {
+	var master Subnet
+	if master, err = c.GetSubnetByCIDRInSection(masterCIDR, sectionID); err != nil {
+		if request.IsNotFound(err) {
+			err = &request.APIError{
+				Code:    http.StatusNotFound,
+				Message: fmt.Sprintf("Master subnet %s not found in section %d", masterCIDR, sectionID),
+			}
+		}
+		return
+	}
+
+	_, parent, perr := net.ParseCIDR(fmt.Sprintf("%s/%d", master.SubnetAddress, master.Mask))
+	if perr != nil {
+		return "", fmt.Errorf("Invalid master subnet %s/%d: %s", master.SubnetAddress, master.Mask, perr)
+	}
+	ip, _, perr := net.ParseCIDR(fmt.Sprintf("%s/%d", child.SubnetAddress, child.Mask))
+	if perr != nil {
+		return "", fmt.Errorf("Invalid child subnet %s/%d: %s", child.SubnetAddress, child.Mask, perr)
+	}
+	if ones, _ := parent.Mask.Size(); int(child.Mask) <= ones || !parent.Contains(ip) {
+		return "", fmt.Errorf("Subnet %s/%d does not fit in master subnet %s", child.SubnetAddress, child.Mask, parent)
+	}
+
+	child.MasterSubnetID = master.ID
+	if child.SectionID == 0 {
+		child.SectionID = sectionID
+	}
+	message, err = c.CreateSubnet(child)
+	return
+}
+
+// CreateFolder creates a folder with the supplied name in the section with
+// the supplied ID, returning the ID of the new folder. The folder is nested
+// under the folder with masterFolderID, or created at the top level of the
+// section if masterFolderID is zero.
+//
+// Folders are subnets with IsFolder set and no address or mask - the name is
+// stored as the folder's Description. An error is returned without sending
+// the request if name is empty or is an IP address or CIDR, as that usually
+// means CreateSubnet was intended instead.
+func (c *Controller) CreateFolder(sectionID int, name string, masterFolderID int) (id int, err error) {
+	if strings.TrimSpace(name) == "" {
+		return 0, errors.New("Folder name cannot be empty")
+	}
+	if _, _, perr := net.ParseCIDR(name); perr == nil || net.ParseIP(name) != nil {
+		return 0, fmt.Errorf("Folder name %q is an address, use CreateSubnet to create a subnet", name)
+	}
+
+	in := Subnet{
+		SectionID:      sectionID,
+		Description:    name,
+		MasterSubnetID: masterFolderID,
+		IsFolder:       true,
+	}
+	id, err = c.CreateSubnetWithID(in)
+	return
+}
+
 // CreateFirstFreeSubnet creates a first free child subnet inside subnet with specified mask by sending a POST request.
 func (c *Controller) CreateFirstFreeSubnet(id int, mask int, in Subnet) (message string, err error) {
 	err = c.SendRequest("POST", fmt.Sprintf("/subnets/%d/first_subnet/%d/", id, mask), &in, &message)
@@ -125,7 +478,66 @@ func (c *Controller) CreateFirstFreeSubnet(id int, mask int, in Subnet) (message
 
 // GetSubnetByID GETs a subnet via its ID.
 func (c *Controller) GetSubnetByID(id int) (out Subnet, err error) {
-	err = c.SendRequest("GET", fmt.Sprintf("/subnets/%d/", id), &struct{}{}, &out)
+	return c.GetSubnetByIDContext(context.Background(), id)
+}
+
+// GetSubnetByIDContext works like GetSubnetByID, but binds the request to
+// the supplied context.
+func (c *Controller) GetSubnetByIDContext(ctx context.Context, id int) (out Subnet, err error) {
+	err = c.SendRequestContext(ctx, "GET", fmt.Sprintf("/subnets/%d/", id), &struct{}{}, &out)
+	return
+}
+
+// GetAllSubnets GETs every subnet in PHPIPAM, across all sections.
+//
+// The response is streamed via client.SendRequestStream, so the subnets are
+// decoded into the returned slice as the response body is read, without
+// holding a copy of the body in memory. The returned slice itself can still
+// be sizeable on large installations.
+func (c *Controller) GetAllSubnets() (out []Subnet, err error) {
+	err = c.SendRequestStream("GET", "/subnets/all/", &struct{}{}, &out)
+	return
+}
+
+// SearchSubnetsByDescription returns the subnets in all sections whose
+// description contains term, ignoring case. An empty list is returned if no
+// subnets match.
+//
+// PHPIPAM's search API only matches addresses and CIDRs, so the match is
+// performed client-side over the results of GetAllSubnets.
+func (c *Controller) SearchSubnetsByDescription(term string) (out []Subnet, err error) {
+	var all []Subnet
+	if all, err = c.GetAllSubnets(); err != nil {
+		return
+	}
+	term = strings.ToLower(term)
+	out = []Subnet{}
+	for _, v := range all {
+		if strings.Contains(strings.ToLower(v.Description), term) {
+			out = append(out, v)
+		}
+	}
+	return
+}
+
+// GetSubnetsByCustomField searches for subnets that have the supplied value
+// set in a custom field, using the filter_by and filter_value query
+// parameters.
+//
+// The field is validated against the schema returned by
+// GetSubnetCustomFieldsSchema first, and an error is returned if it does not
+// exist.
+func (c *Controller) GetSubnetsByCustomField(field, value string) (out []Subnet, err error) {
+	var schema map[string]phpipam.CustomField
+	if schema, err = c.GetSubnetCustomFieldsSchema(); err != nil {
+		return
+	}
+	if _, ok := schema[field]; !ok {
+		return nil, fmt.Errorf("Custom field %s not found in schema for controller subnets", field)
+	}
+
+	opts := phpipam.ListOptions{FilterBy: field, FilterValue: value}
+	err = c.SendRequestWithParams("GET", "/subnets/", opts.Values(), &struct{}{}, &out)
 	return
 }
 
@@ -136,8 +548,100 @@ func (c *Controller) GetSubnetByID(id int) (out Subnet, err error) {
 // method in a way that would return multiple results. Using a broader CIDR
 // will not return multiple results, and using the CIDR of a master subnet will
 // return that subnet only.
+//
+// The CIDR is validated and normalized via phpipam.PathCIDR before the request
+// is sent, so both compressed and expanded IPv6 forms are accepted.
 func (c *Controller) GetSubnetsByCIDR(cidr string) (out []Subnet, err error) {
-	err = c.SendRequest("GET", fmt.Sprintf("/subnets/cidr/%s/", cidr), &struct{}{}, &out)
+	var path string
+	if path, err = phpipam.PathCIDR(cidr); err != nil {
+		return
+	}
+	err = c.SendRequest("GET", fmt.Sprintf("/subnets/cidr/%s/", path), &struct{}{}, &out)
+	return
+}
+
+// GetSubnetsByCIDRAndSection GETs the subnets matching a CIDR via
+// GetSubnetsByCIDR, and returns the ones that are in the supplied section.
+func (c *Controller) GetSubnetsByCIDRAndSection(cidr string, sectionID int) (out []Subnet, err error) {
+	var list []Subnet
+	if list, err = c.GetSubnetsByCIDR(cidr); err != nil {
+		return
+	}
+	for _, v := range list {
+		if v.SectionID == sectionID {
+			out = append(out, v)
+		}
+	}
+	return
+}
+
+// GetSubnetByCIDRInSection GETs the single subnet matching a CIDR in the
+// supplied section. A not found *request.APIError is returned if there is no
+// such subnet, and an error is also returned if more than one subnet matches.
+func (c *Controller) GetSubnetByCIDRInSection(cidr string, sectionID int) (out Subnet, err error) {
+	var list []Subnet
+	if list, err = c.GetSubnetsByCIDRAndSection(cidr, sectionID); err != nil {
+		return
+	}
+	switch len(list) {
+	case 0:
+		err = &request.APIError{
+			Code:    http.StatusNotFound,
+			Message: fmt.Sprintf("No subnet %s found in section %d", cidr, sectionID),
+		}
+	case 1:
+		out = list[0]
+	default:
+		err = fmt.Errorf("Found %d subnets matching %s in section %d, expected 1", len(list), cidr, sectionID)
+	}
+	return
+}
+
+// GetSubnetSlaves GETs the direct children of a subnet.
+func (c *Controller) GetSubnetSlaves(id int) (out []Subnet, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/subnets/%d/slaves/", id), &struct{}{}, &out)
+	return
+}
+
+// GetSubnetSlavesRecursive GETs all the descendants of a subnet - its
+// children, their children, and so on.
+func (c *Controller) GetSubnetSlavesRecursive(id int) (out []Subnet, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/subnets/%d/slaves_recursive/", id), &struct{}{}, &out)
+	return
+}
+
+// GetSubnetAncestors GETs the ancestors of a subnet by following the
+// MasterSubnetID of each subnet upwards, starting with the subnet with the
+// supplied ID. The ancestors are returned in order from the root down to the
+// subnet's direct parent, so the result is empty for a top-level subnet.
+//
+// If a parent can't be fetched, or the chain loops back on itself, the walk
+// stops and the ancestors found so far are returned along with an error.
+func (c *Controller) GetSubnetAncestors(id int) (out []Subnet, err error) {
+	var sn Subnet
+	if sn, err = c.GetSubnetByID(id); err != nil {
+		return
+	}
+
+	var chain []Subnet
+	seen := map[int]bool{sn.ID: true}
+	for sn.MasterSubnetID != 0 {
+		parent := sn.MasterSubnetID
+		if seen[parent] {
+			err = fmt.Errorf("Loop in master subnets of subnet %d at subnet %d", id, parent)
+			break
+		}
+		seen[parent] = true
+		if sn, err = c.GetSubnetByID(parent); err != nil {
+			err = fmt.Errorf("Error getting master subnet %d of subnet %d: %w", parent, id, err)
+			break
+		}
+		chain = append(chain, sn)
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		out = append(out, chain[i])
+	}
 	return
 }
 
@@ -147,6 +651,210 @@ func (c *Controller) GetFirstFreeSubnet(id int, mask int) (message string, err e
 	return
 }
 
+// ErrNoFreeSubnet is returned by GetFirstFreeSubnetCIDR and
+// GetBiggestFreeSubnet when there is no free child subnet of the requested
+// size.
+var ErrNoFreeSubnet = errors.New("No free subnet of the requested size")
+
+// GetFirstFreeSubnetCIDR GETs the first free child subnet with the supplied
+// mask inside the subnet with the supplied ID, via GetFirstFreeSubnet. Unlike
+// GetFirstFreeSubnet, the CIDR returned by the API is validated and
+// normalized, and ErrNoFreeSubnet is returned if there is no free subnet
+// instead of a blank string.
+func (c *Controller) GetFirstFreeSubnetCIDR(id int, mask int) (cidr string, err error) {
+	var out string
+	if out, err = c.GetFirstFreeSubnet(id, mask); err != nil {
+		return
+	}
+	if out == "" {
+		return "", ErrNoFreeSubnet
+	}
+	ip, n, perr := net.ParseCIDR(out)
+	if perr != nil {
+		return "", fmt.Errorf("Invalid CIDR %q returned for first free subnet in subnet %d: %s", out, id, perr)
+	}
+	if ones, _ := n.Mask.Size(); !ip.Equal(n.IP) || ones != mask {
+		return "", fmt.Errorf("Invalid CIDR %q returned for first free /%d subnet in subnet %d", out, mask, id)
+	}
+	cidr = n.String()
+	return
+}
+
+// CreateFirstFreeSubnetWithResult creates the first free child subnet with
+// the supplied mask inside the subnet with the supplied ID, like
+// CreateFirstFreeSubnet, and returns the full created subnet. The subnet is
+// looked up by the ID supplied in the create response, or if there is none,
+// by the assigned CIDR among the children of the master subnet.
+func (c *Controller) CreateFirstFreeSubnetWithResult(id int, mask int, in Subnet) (out Subnet, err error) {
+	var subnetID int
+	var cidr string
+	if subnetID, cidr, err = c.SendCreateRequest(fmt.Sprintf("/subnets/%d/first_subnet/%d/", id, mask), &in); err != nil {
+		return
+	}
+	if subnetID != 0 {
+		out, err = c.GetSubnetByID(subnetID)
+		return
+	}
+
+	var list []Subnet
+	if list, err = c.GetSubnetsByCIDR(cidr); err != nil {
+		return
+	}
+	for _, v := range list {
+		if v.MasterSubnetID == id {
+			out = v
+			return
+		}
+	}
+	err = fmt.Errorf("Could not find created subnet %s in subnet %d", cidr, id)
+	return
+}
+
+// GetBiggestFreeSubnet returns the CIDR of a free child subnet with the
+// supplied mask inside the subnet with the supplied ID, placed at the start of
+// the largest free block in the subnet. Unlike GetFirstFreeSubnetCIDR, which
+// uses the first gap that fits, this keeps small gaps free for small subnets,
+// limiting fragmentation. ErrNoFreeSubnet is returned if no free block is big
+// enough.
+//
+// PHPIPAM does not provide an API method for this, so the free blocks are
+// computed client-side from the direct children of the subnet, via
+// GetSubnetSlaves. Folders are ignored. Free blocks are aligned CIDR blocks,
+// and if several are the same size, the one with the lowest address is used.
+//
+// Note that the subnet is not reserved, and may be taken by another client
+// before it is created.
+func (c *Controller) GetBiggestFreeSubnet(masterID int, mask int) (cidr string, err error) {
+	var master Subnet
+	if master, err = c.GetSubnetByID(masterID); err != nil {
+		return
+	}
+	_, network, perr := net.ParseCIDR(fmt.Sprintf("%s/%d", master.SubnetAddress, master.Mask))
+	if perr != nil {
+		return "", fmt.Errorf("Invalid subnet %s/%d: %s", master.SubnetAddress, master.Mask, perr)
+	}
+	ones, bits := network.Mask.Size()
+	if mask <= ones || mask > bits {
+		return "", fmt.Errorf("Mask /%d is not valid for child subnets of %s", mask, network)
+	}
+
+	var children []Subnet
+	if children, err = c.GetSubnetSlaves(masterID); err != nil {
+		// The API returns a 404 error if the subnet has no children.
+		if !request.IsNotFound(err) {
+			return
+		}
+		err = nil
+	}
+	var used []ipRange
+	for _, v := range WithoutFolders(children) {
+		_, n, perr := net.ParseCIDR(fmt.Sprintf("%s/%d", v.SubnetAddress, v.Mask))
+		if perr != nil {
+			return "", fmt.Errorf("Invalid child subnet %s/%d: %s", v.SubnetAddress, v.Mask, perr)
+		}
+		if nbits := len(n.IP) * 8; nbits == bits {
+			used = append(used, cidrRange(n, bits))
+		}
+	}
+
+	best, bestPrefix := (*big.Int)(nil), bits+1
+	for _, free := range freeRanges(cidrRange(network, bits), used) {
+		for _, block := range rangeCIDRs(free, bits) {
+			if block.prefix <= mask && block.prefix < bestPrefix {
+				best, bestPrefix = block.start, block.prefix
+			}
+		}
+	}
+	if best == nil {
+		return "", ErrNoFreeSubnet
+	}
+	out := net.IPNet{IP: intToIP(best, bits), Mask: net.CIDRMask(mask, bits)}
+	cidr = out.String()
+	return
+}
+
+// ipRange is an inclusive range of IP addresses, as integers.
+type ipRange struct {
+	start, end *big.Int
+}
+
+// ipBlock is an aligned CIDR block of IP addresses, as integers.
+type ipBlock struct {
+	start  *big.Int
+	prefix int
+}
+
+// cidrRange returns the range of addresses in a network with the supplied
+// address length in bits.
+func cidrRange(n *net.IPNet, bits int) ipRange {
+	ones, _ := n.Mask.Size()
+	start := ipToInt(n.IP, bits)
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	return ipRange{start: start, end: new(big.Int).Sub(new(big.Int).Add(start, size), big.NewInt(1))}
+}
+
+// freeRanges returns the parts of r not covered by any of the ranges in used,
+// in ascending order.
+func freeRanges(r ipRange, used []ipRange) (out []ipRange) {
+	sort.Slice(used, func(i, j int) bool { return used[i].start.Cmp(used[j].start) < 0 })
+	next := new(big.Int).Set(r.start)
+	for _, u := range used {
+		if u.end.Cmp(next) < 0 || u.start.Cmp(r.end) > 0 {
+			continue
+		}
+		if u.start.Cmp(next) > 0 {
+			out = append(out, ipRange{start: next, end: new(big.Int).Sub(u.start, big.NewInt(1))})
+		}
+		next = new(big.Int).Add(u.end, big.NewInt(1))
+	}
+	if next.Cmp(r.end) <= 0 {
+		out = append(out, ipRange{start: next, end: r.end})
+	}
+	return
+}
+
+// rangeCIDRs splits r into the fewest aligned CIDR blocks that cover it, for
+// addresses of the supplied length in bits.
+func rangeCIDRs(r ipRange, bits int) (out []ipBlock) {
+	start := new(big.Int).Set(r.start)
+	for start.Cmp(r.end) <= 0 {
+		// The block size is limited by the alignment of start, and by the
+		// number of addresses left in the range.
+		size := bits
+		if start.Sign() != 0 {
+			size = int(start.TrailingZeroBits())
+		}
+		left := new(big.Int).Sub(r.end, start)
+		left.Add(left, big.NewInt(1))
+		for size > 0 && new(big.Int).Lsh(big.NewInt(1), uint(size)).Cmp(left) > 0 {
+			size--
+		}
+		out = append(out, ipBlock{start: new(big.Int).Set(start), prefix: bits - size})
+		start.Add(start, new(big.Int).Lsh(big.NewInt(1), uint(size)))
+	}
+	return
+}
+
+// ipToInt returns ip as an integer, for addresses of the supplied length in
+// bits.
+func ipToInt(ip net.IP, bits int) *big.Int {
+	if bits == 32 {
+		ip = ip.To4()
+	} else {
+		ip = ip.To16()
+	}
+	return new(big.Int).SetBytes(ip)
+}
+
+// intToIP returns the IP address for n, for addresses of the supplied length
+// in bits.
+func intToIP(n *big.Int, bits int) net.IP {
+	b := n.Bytes()
+	ip := make(net.IP, bits/8)
+	copy(ip[len(ip)-len(b):], b)
+	return ip
+}
+
 // GetFirstFreeAddress GETs the first free IP address in a subnet and returns
 // it as a string. This can be used to automatically determine the next address
 // you should use. If there are no more available addresses, the string will be
@@ -155,14 +863,409 @@ func (c *Controller) GetFirstFreeSubnet(id int, mask int) (message string, err e
 // Note that marking a subnet as used does not prevent this function from
 // returning data.
 func (c *Controller) GetFirstFreeAddress(id int) (out string, err error) {
-	err = c.SendRequest("GET", fmt.Sprintf("/subnets/%d/first_free/", id), &struct{}{}, &out)
+	return c.GetFirstFreeAddressContext(context.Background(), id)
+}
+
+// GetFirstFreeAddressContext works like GetFirstFreeAddress, but binds the
+// request to the supplied context.
+func (c *Controller) GetFirstFreeAddressContext(ctx context.Context, id int) (out string, err error) {
+	err = c.SendRequestContext(ctx, "GET", fmt.Sprintf("/subnets/%d/first_free/", id), &struct{}{}, &out)
 	return
 }
 
+// ErrNotEnoughFreeAddresses is returned by GetFirstFreeAddresses when the
+// subnet has fewer free addresses than were requested.
+var ErrNotEnoughFreeAddresses = errors.New("Not enough free addresses in subnet")
+
+// GetFirstFreeAddresses returns up to count free IP addresses in a subnet, in
+// ascending order. The API can only supply one free address at a time, so the
+// addresses are determined from the subnet's range and the addresses already
+// in use, as returned by GetAddressesInSubnet. For IPv4 subnets with a mask
+// shorter than /31, the network and broadcast addresses are skipped. For IPv6
+// subnets, the subnet-router anycast (network) address is skipped.
+//
+// If fewer than count addresses are free, the free addresses are returned
+// along with an error wrapping ErrNotEnoughFreeAddresses.
+//
+// Note that the addresses are not reserved, and may be taken by another client
+// before they are created.
+func (c *Controller) GetFirstFreeAddresses(id int, count int) (out []string, err error) {
+	var subnet Subnet
+	if subnet, err = c.GetSubnetByID(id); err != nil {
+		return
+	}
+	var network *net.IPNet
+	if _, network, err = net.ParseCIDR(fmt.Sprintf("%s/%d", subnet.SubnetAddress, subnet.Mask)); err != nil {
+		return
+	}
+
+	var used []addresses.Address
+	if used, err = c.GetAddressesInSubnet(id); err != nil {
+		// The API returns a 404 error if the subnet has no addresses.
+		if !request.IsNotFound(err) {
+			return
+		}
+		err = nil
+	}
+	inUse := make(map[string]bool)
+	for _, v := range used {
+		if ip := net.ParseIP(v.IPAddress); ip != nil {
+			inUse[ip.String()] = true
+		}
+	}
+
+	ip := network.IP
+	ones, bits := network.Mask.Size()
+	skipEnds := bits-ones > 1
+	if skipEnds {
+		ip = nextIP(ip)
+	}
+	for ; network.Contains(ip) && len(out) < count; ip = nextIP(ip) {
+		if bits == 32 && skipEnds && !network.Contains(nextIP(ip)) {
+			// Broadcast address.
+			break
+		}
+		if !inUse[ip.String()] {
+			out = append(out, ip.String())
+		}
+	}
+	if len(out) < count {
+		err = fmt.Errorf("%w: %d of %d requested addresses available", ErrNotEnoughFreeAddresses, len(out), count)
+	}
+	return
+}
+
+// nextIP returns the IP address following ip. The result wraps around to all
+// zeros after the last address.
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
 // GetAddressesInSubnet GETs the IP addresses for a specific subnet, via a
 // supplied subnet ID.
 func (c *Controller) GetAddressesInSubnet(id int) (out []addresses.Address, err error) {
-	err = c.SendRequest("GET", fmt.Sprintf("/subnets/%d/addresses/", id), &struct{}{}, &out)
+	return c.GetAddressesInSubnetContext(context.Background(), id)
+}
+
+// GetAddressesInSubnetContext works like GetAddressesInSubnet, but binds the
+// request to the supplied context.
+func (c *Controller) GetAddressesInSubnetContext(ctx context.Context, id int) (out []addresses.Address, err error) {
+	err = c.SendRequestContext(ctx, "GET", fmt.Sprintf("/subnets/%d/addresses/", id), &struct{}{}, &out)
+	return
+}
+
+// GetAddressesInSubnetWithCustomFields GETs the IP addresses for a specific
+// subnet, like GetAddressesInSubnet, and ensures that each address carries
+// its custom fields.
+//
+// The custom fields are only nested in the address list if the "Nest custom
+// fields" flag is set on the API integration. For addresses that come back
+// without them, the fields are fetched via
+// addresses.GetAddressCustomFields, with at most 8 requests made at once.
+// Each of these also fetches the custom field schema, unless the session's
+// SchemaCacheTTL is set.
+//
+// All addresses in the subnet are returned. If any custom field lookups
+// fail, the returned error is a phpipam.MultiError containing the error for
+// each failed address ID, and those addresses are returned without their
+// custom fields.
+func (c *Controller) GetAddressesInSubnetWithCustomFields(id int) (out []addresses.Address, err error) {
+	if out, err = c.GetAddressesInSubnet(id); err != nil {
+		return
+	}
+
+	var missing []int
+	for i, v := range out {
+		if len(v.CustomFields) == 0 {
+			missing = append(missing, i)
+		}
+	}
+
+	ac := addresses.NewController(c.Session)
+	errs := make([]error, len(out))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < batchWorkers && w < len(missing); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				var fields map[string]interface{}
+				if fields, errs[i] = ac.GetAddressCustomFields(out[i].ID); errs[i] == nil {
+					out[i].CustomFields = fields
+				}
+			}
+		}()
+	}
+	for _, i := range missing {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	merr := phpipam.MultiError{}
+	for i, e := range errs {
+		if e != nil {
+			merr[out[i].ID] = e
+		}
+	}
+	if len(merr) > 0 {
+		err = merr
+	}
+	return
+}
+
+// GetAddressesInSubnetByTag GETs the IP addresses in a subnet that have been
+// assigned the tag with the supplied ID. An empty list is returned if no
+// addresses match.
+func (c *Controller) GetAddressesInSubnetByTag(subnetID, tagID int) (out []addresses.Address, err error) {
+	return c.filterAddressesInSubnet(subnetID, "tag", strconv.Itoa(tagID), func(a addresses.Address) bool {
+		return a.Tag == tagID
+	})
+}
+
+// GetSubnetGatewayAddress GETs the address marked as the gateway of a subnet.
+// A not found *request.APIError is returned if the subnet has no gateway
+// address.
+func (c *Controller) GetSubnetGatewayAddress(subnetID int) (out addresses.Address, err error) {
+	var list []addresses.Address
+	if list, err = c.gatewayAddresses(subnetID); err != nil {
+		return
+	}
+	if len(list) == 0 {
+		err = &request.APIError{
+			Code:    http.StatusNotFound,
+			Message: fmt.Sprintf("No gateway address found in subnet %d", subnetID),
+		}
+		return
+	}
+	out = list[0]
+	return
+}
+
+// SetSubnetGateway makes the address with the supplied ID the gateway of a
+// subnet, returning the message from the update of the address.
+//
+// PHPIPAM has no separate gateway endpoint - the Gateway and GatewayID of a
+// subnet are derived from the address flagged with is_gateway. This flags the
+// address via addresses.PatchAddress, then clears the flag on any other
+// gateway addresses in the subnet, so the subnet is left with a single,
+// current gateway. An error is returned if the address is not in the subnet.
+func (c *Controller) SetSubnetGateway(subnetID, addressID int) (message string, err error) {
+	ac := addresses.NewController(c.Session)
+	var addr addresses.Address
+	if addr, err = ac.GetAddressByID(addressID); err != nil {
+		return
+	}
+	if addr.SubnetID != subnetID {
+		err = fmt.Errorf("Address %d is in subnet %d, not subnet %d", addressID, addr.SubnetID, subnetID)
+		return
+	}
+
+	if message, err = ac.PatchAddress(addressID, map[string]interface{}{"is_gateway": phpipam.BoolIntString(true)}); err != nil {
+		return
+	}
+	err = c.clearGateways(ac, subnetID, addressID)
+	return
+}
+
+// UnsetSubnetGateway clears the gateway of a subnet, by clearing the
+// is_gateway flag on every gateway address in it. See SetSubnetGateway for
+// how PHPIPAM tracks gateways. Nothing is changed if the subnet has no
+// gateway.
+func (c *Controller) UnsetSubnetGateway(subnetID int) (err error) {
+	err = c.clearGateways(addresses.NewController(c.Session), subnetID, 0)
+	return
+}
+
+// clearGateways clears the is_gateway flag on the gateway addresses in a
+// subnet, other than the address with the ID keep.
+func (c *Controller) clearGateways(ac *addresses.Controller, subnetID, keep int) (err error) {
+	var list []addresses.Address
+	if list, err = c.gatewayAddresses(subnetID); err != nil {
+		return
+	}
+	for _, v := range list {
+		if v.ID == keep {
+			continue
+		}
+		if _, err = ac.PatchAddress(v.ID, map[string]interface{}{"is_gateway": phpipam.BoolIntString(false)}); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// gatewayAddresses GETs the addresses in a subnet flagged as gateways. There
+// is normally at most one.
+func (c *Controller) gatewayAddresses(subnetID int) ([]addresses.Address, error) {
+	return c.filterAddressesInSubnet(subnetID, "is_gateway", "1", func(a addresses.Address) bool {
+		return bool(a.IsGateway)
+	})
+}
+
+// GetOfflineAddresses GETs the addresses in a subnet that are tagged as
+// offline (addresses.TagOffline) via GetAddressesInSubnetByTag.
+//
+// The tag is the administrative state of an address. For the addresses that
+// PHPIPAM's ping scans have not seen recently, use GetAddressesNotSeenSince.
+func (c *Controller) GetOfflineAddresses(subnetID int) (out []addresses.Address, err error) {
+	return c.GetAddressesInSubnetByTag(subnetID, addresses.TagOffline)
+}
+
+// GetAddressesNotSeenSince GETs the addresses in a subnet that have not been
+// seen by PHPIPAM's ping scans since the supplied time, including addresses
+// that have never been seen. The PHPIPAM UI shows an address as offline once
+// it has not been seen for longer than the offline ping status threshold in
+// its settings, which is one hour by default.
+//
+// LastSeen timestamps are in the server's local time, so they are
+// interpreted in the location of since. Addresses that are excluded from ping
+// scans are left out, as they are never seen. The filtering is performed
+// client-side over the results of GetAddressesInSubnet.
+func (c *Controller) GetAddressesNotSeenSince(subnetID int, since time.Time) (out []addresses.Address, err error) {
+	var all []addresses.Address
+	if all, err = c.GetAddressesInSubnet(subnetID); err != nil {
+		return
+	}
+	out = []addresses.Address{}
+	for _, v := range all {
+		if v.ExcludePing {
+			continue
+		}
+		seen, ok, perr := v.LastSeenTime(since.Location())
+		if perr != nil {
+			return nil, fmt.Errorf("Invalid last seen time %q for address %d: %s", v.LastSeen, v.ID, perr)
+		}
+		if !ok || seen.Before(since) {
+			out = append(out, v)
+		}
+	}
+	return
+}
+
+// filterAddressesInSubnet GETs the IP addresses in a subnet for which field
+// is value, using the filter_by and filter_value query parameters. The result
+// is filtered again with match, as older PHPIPAM versions ignore the filter
+// parameters. A not found error is treated as an empty result.
+func (c *Controller) filterAddressesInSubnet(subnetID int, field, value string, match func(addresses.Address) bool) (out []addresses.Address, err error) {
+	var all []addresses.Address
+	opts := phpipam.ListOptions{FilterBy: field, FilterValue: value}
+	err = c.SendRequestWithParams("GET", fmt.Sprintf("/subnets/%d/addresses/", subnetID), opts.Values(), &struct{}{}, &all)
+	if err != nil {
+		if !request.IsNotFound(err) {
+			return
+		}
+		err = nil
+	}
+	out = []addresses.Address{}
+	for _, v := range all {
+		if match(v) {
+			out = append(out, v)
+		}
+	}
+	return
+}
+
+// GetAddressesInSubnetPage GETs a page of the IP addresses for a specific
+// subnet, returning the page along with the total number of addresses in the
+// subnet. A limit of zero or less returns all addresses after offset.
+//
+// PHPIPAM does not paginate this endpoint server-side, so the full address
+// list is still fetched and decoded - the page is taken client-side, and only
+// it is retained once the method returns.
+func (c *Controller) GetAddressesInSubnetPage(id, limit, offset int) (out []addresses.Address, total int, err error) {
+	var all []addresses.Address
+	if all, err = c.GetAddressesInSubnet(id); err != nil {
+		return
+	}
+	total = len(all)
+	start, end := pageBounds(total, limit, offset)
+	out = append([]addresses.Address(nil), all[start:end]...)
+	return
+}
+
+// GetAllSubnetsPage GETs a page of all subnets, returning the page along with
+// the total number of subnets. A limit of zero or less returns all subnets
+// after offset.
+//
+// As with GetAddressesInSubnetPage, the page is taken client-side, as PHPIPAM
+// does not paginate this endpoint server-side.
+func (c *Controller) GetAllSubnetsPage(limit, offset int) (out []Subnet, total int, err error) {
+	var all []Subnet
+	if all, err = c.GetAllSubnets(); err != nil {
+		return
+	}
+	total = len(all)
+	start, end := pageBounds(total, limit, offset)
+	out = append([]Subnet(nil), all[start:end]...)
+	return
+}
+
+// pageBounds returns the slice bounds of the page described by limit and
+// offset in a list of total items.
+func pageBounds(total, limit, offset int) (start, end int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+	end = total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	return offset, end
+}
+
+// GetSubnetByIDWithCustomFields GETs a subnet via its ID, ensuring its custom
+// fields are populated.
+//
+// If the API integration does not have the "Nest custom fields" flag set, the
+// subnet's CustomFields map is empty after the GET, so the custom fields are
+// fetched separately via GetSubnetCustomFields and merged into it. This gives
+// a consistent result regardless of the flag.
+func (c *Controller) GetSubnetByIDWithCustomFields(id int) (out Subnet, err error) {
+	if out, err = c.GetSubnetByID(id); err != nil || len(out.CustomFields) > 0 {
+		return
+	}
+
+	var fields map[string]interface{}
+	if fields, err = c.GetSubnetCustomFields(id); err != nil {
+		return
+	}
+	if len(fields) > 0 {
+		out.CustomFields = fields
+	}
+	return
+}
+
+// GetSubnetWithCalculation GETs a subnet via its ID, along with the network
+// calculation PHPIPAM supplies with it. This is the same calculation shown in
+// the UI, and should be used instead of working out network and host ranges
+// locally. An error is returned if the API did not supply a calculation, such
+// as for folders.
+func (c *Controller) GetSubnetWithCalculation(id int) (out SubnetWithCalculation, err error) {
+	if err = c.SendRequest("GET", fmt.Sprintf("/subnets/%d/", id), &struct{}{}, &out); err != nil {
+		return
+	}
+	if out.Calculation.Type == "" {
+		err = fmt.Errorf("No calculation returned for subnet %d", id)
+	}
+	return
+}
+
+// GetSubnetChangelog GETs the changelog of a subnet via its ID.
+func (c *Controller) GetSubnetChangelog(id int) (out []phpipam.ChangelogEntry, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/subnets/%d/changelog/", id), &struct{}{}, &out)
 	return
 }
 
@@ -180,23 +1283,197 @@ func (c *Controller) GetSubnetCustomFields(id int) (out map[string]interface{},
 	return
 }
 
-// UpdateSubnet updates a subnet by sending a PATCH request.
+// UpdateSubnet updates a subnet by sending a PATCH request. Fields with zero
+// values are not sent, so they can't be used to clear a field - use
+// PatchSubnet for that.
 //
-// Note you cannot use this function to update a subnet's CIDR - to split,
-// grow, or renumber a subnet, you need to use other methods that are currently
-// not implemented in this SDK. See the API spec for more details.
+// Note you cannot use this function to update a subnet's CIDR. To split a
+// subnet, use SplitSubnet, and to grow or shrink it, use ResizeSubnet.
+// Renumbering a subnet needs other methods that are currently not implemented
+// in this SDK. See the API spec for more details.
 func (c *Controller) UpdateSubnet(in Subnet) (message string, err error) {
 	err = c.SendRequest("PATCH", "/subnets/", &in, &message)
 	return
 }
 
+// UpdateSubnetByID works like UpdateSubnet, but sends the subnet's ID in the
+// request path (ie: PATCH /subnets/8/) instead of the body. This is needed by
+// some PHPIPAM versions and proxies that reject the body-only form. Any ID set
+// in in is ignored.
+func (c *Controller) UpdateSubnetByID(id int, in Subnet) (message string, err error) {
+	in.ID = 0
+	err = c.SendRequest("PATCH", fmt.Sprintf("/subnets/%d/", id), &in, &message)
+	return
+}
+
+// PatchSubnet PATCHes only the supplied fields of a subnet, via
+// client.PatchFields. Unlike UpdateSubnet, which leaves out zero values, this
+// can be used to set a field to zero or to clear it.
+//
+// The keys of fields are the subnet's JSON field names, as used in the tags of
+// Subnet (ie: vlanId, vrfId, or description). Fields that are not supplied are
+// left unchanged. A nil value is sent as null, which clears the field, so
+// this removes a subnet from its VLAN:
+//
+//   c.PatchSubnet(id, map[string]interface{}{"vlanId": nil})
+//
+// An error is returned if any of the keys are not fields of Subnet.
+func (c *Controller) PatchSubnet(id int, fields map[string]interface{}) (message string, err error) {
+	message, err = c.Client.PatchFields("subnets", id, Subnet{}, fields)
+	return
+}
+
+// EnsureSubnet makes sure that a subnet matching in exists, and returns it
+// along with whether or not it was created.
+//
+// The subnet is looked up by its CIDR (SubnetAddress and Mask) and SectionID
+// via GetSubnetByCIDRInSection. If it does not exist, it is created.
+// Otherwise, any fields set in in that differ from the existing subnet are
+// updated via PatchSubnet - see phpipam.ChangedFields for how differences are
+// determined. Fields with zero values are left as they are. If the API
+// rejects the create because another client created the subnet in the
+// meantime, the existing subnet is used instead. If the API does not supply
+// the ID of a created subnet, it is looked up again.
+//
+// Note that CustomFields is compared as well, which requires the "Nest custom
+// fields" flag to be set on the API integration.
+func (c *Controller) EnsureSubnet(in Subnet) (out Subnet, created bool, err error) {
+	cidr := fmt.Sprintf("%s/%d", in.SubnetAddress, in.Mask)
+	if out, err = c.GetSubnetByCIDRInSection(cidr, in.SectionID); request.IsNotFound(err) {
+		var id int
+		if id, _, err = c.SendCreateRequest("/subnets/", &in); err == nil {
+			if id != 0 {
+				out, err = c.GetSubnetByID(id)
+			} else {
+				out, err = c.GetSubnetByCIDRInSection(cidr, in.SectionID)
+			}
+			return out, true, err
+		}
+		var apiErr *request.APIError
+		if !errors.As(err, &apiErr) {
+			return
+		}
+		var lerr error
+		if out, lerr = c.GetSubnetByCIDRInSection(cidr, in.SectionID); lerr != nil {
+			return
+		}
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+
+	var fields map[string]interface{}
+	if fields, err = phpipam.ChangedFields(in, out, "subnet", "mask", "sectionId"); err != nil || len(fields) == 0 {
+		return
+	}
+	if _, err = c.PatchSubnet(out.ID, fields); err != nil {
+		return
+	}
+	out, err = c.GetSubnetByID(out.ID)
+	return
+}
+
+// SplitSubnet splits a subnet into number equally sized child subnets by
+// sending a PATCH request to the subnet's split method.
+//
+// Note that PHPIPAM only returns a message for this request. Use
+// SplitSubnetWithIDs if you need the IDs of the new subnets.
+func (c *Controller) SplitSubnet(id int, number int) (message string, err error) {
+	err = c.SendRequest("PATCH", fmt.Sprintf("/subnets/%d/split/%d/", id, number), &struct{}{}, &message)
+	return
+}
+
+// SplitSubnetWithIDs splits a subnet via SplitSubnet, and then returns the IDs
+// of the new child subnets, found by looking up the subnet's slaves with
+// GetSubnetSlaves.
+func (c *Controller) SplitSubnetWithIDs(id int, number int) (ids []int, err error) {
+	if _, err = c.SplitSubnet(id, number); err != nil {
+		return
+	}
+	var slaves []Subnet
+	if slaves, err = c.GetSubnetSlaves(id); err != nil {
+		return
+	}
+	for _, v := range slaves {
+		ids = append(ids, v.ID)
+	}
+	return
+}
+
+// ResizeSubnet changes the mask of a subnet by sending a PATCH request to the
+// subnet's resize method.
+//
+// PHPIPAM will refuse to shrink a subnet if existing addresses or child
+// subnets would fall outside of it. The error returned in this case is a
+// *request.APIError carrying the server's message.
+func (c *Controller) ResizeSubnet(id int, mask int) (message string, err error) {
+	in := struct {
+		Mask phpipam.JSONIntString `json:"mask"`
+	}{
+		Mask: phpipam.JSONIntString(mask),
+	}
+	err = c.SendRequest("PATCH", fmt.Sprintf("/subnets/%d/resize/", id), &in, &message)
+	return
+}
+
+// UpdateSubnetDNSFlags PATCHes only the DNS-related flags of a subnet.
+//
+// Unlike UpdateSubnet, false values are sent explicitly, so this can be used
+// to turn the flags off as well as on.
+func (c *Controller) UpdateSubnetDNSFlags(id int, recursive, records, resolve bool) (message string, err error) {
+	in := struct {
+		ID           int                   `json:"id,string"`
+		DNSRecursive phpipam.BoolIntString `json:"DNSrecursive"`
+		DNSRecords   phpipam.BoolIntString `json:"DNSrecords"`
+		ResolveDNS   phpipam.BoolIntString `json:"resolveDNS"`
+	}{
+		ID:           id,
+		DNSRecursive: phpipam.BoolIntString(recursive),
+		DNSRecords:   phpipam.BoolIntString(records),
+		ResolveDNS:   phpipam.BoolIntString(resolve),
+	}
+	err = c.SendRequest("PATCH", "/subnets/", &in, &message)
+	return
+}
+
 // UpdateSubnetCustomFields PATCHes the subnet's custom fields via
-// client.UpdateCustomFields.
+// client.UpdateCustomFields. The fields are validated against the schema first,
+// and an error is returned if any of them are not defined.
 func (c *Controller) UpdateSubnetCustomFields(id int, in map[string]interface{}) (message string, err error) {
 	message, err = c.Client.UpdateCustomFields(id, in, "subnets")
 	return
 }
 
+// TruncateSubnet deletes all addresses in a subnet by its ID, leaving the
+// subnet itself in place. RemoveDNS can be set to true if you want to have any
+// related DNS records deleted as well.
+func (c *Controller) TruncateSubnet(id int, RemoveDNS phpipam.BoolIntString) (message string, err error) {
+	in := struct {
+		RemoveDNS phpipam.BoolIntString `json:"remove_dns,omitempty"`
+	}{
+		RemoveDNS: RemoveDNS,
+	}
+	err = c.SendRequest("DELETE", fmt.Sprintf("/subnets/%d/truncate/", id), &in, &message)
+	return
+}
+
+// DeleteFolder deletes a folder by its ID. The folder is checked first, and
+// an error is returned without deleting anything if the ID is that of an
+// actual subnet.
+func (c *Controller) DeleteFolder(id int) (message string, err error) {
+	var folder Subnet
+	if folder, err = c.GetSubnetByID(id); err != nil {
+		return
+	}
+	if !folder.IsFolderSubnet() {
+		err = fmt.Errorf("Subnet %d is not a folder", id)
+		return
+	}
+	message, err = c.DeleteSubnet(id)
+	return
+}
+
 // DeleteSubnet deletes a subnet by its ID.
 func (c *Controller) DeleteSubnet(id int) (message string, err error) {
 	err = c.SendRequest("DELETE", fmt.Sprintf("/subnets/%d/", id), &struct{}{}, &message)
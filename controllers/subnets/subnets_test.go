@@ -1,16 +1,23 @@
 package subnets
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/pavel-z1/phpipam-sdk-go/controllers/addresses"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/request"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
 	"github.com/pavel-z1/phpipam-sdk-go/testacc"
 )
@@ -54,6 +61,96 @@ var testGetSubnetByIDOutputExpected = Subnet{
 	MasterSubnetID: 2,
 }
 
+var testGetSubnetByIDNullIDsOutputExpected = Subnet{
+	ID:            9,
+	SubnetAddress: "10.10.4.0",
+	Mask:          24,
+	SectionID:     1,
+}
+
+const testGetSubnetByIDNullIDsOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "9",
+    "subnet": "10.10.4.0",
+    "mask": "24",
+    "sectionId": "1",
+    "linked_subnet": null,
+    "vrfId": null,
+    "masterSubnetId": "",
+    "vlanId": null
+  }
+}
+`
+
+var testGetSubnetByIDWithCustomFieldsExpected = Subnet{
+	ID:            8,
+	SubnetAddress: "10.10.3.0",
+	Mask:          24,
+	SectionID:     1,
+	CustomFields: map[string]interface{}{
+		"CustomTestSubnets": "foo",
+	},
+}
+
+const testGetSubnetByIDWithCustomFieldsJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "8",
+    "subnet": "10.10.3.0",
+    "mask": "24",
+    "sectionId": "1",
+    "CustomTestSubnets": "foo"
+  }
+}
+`
+
+const testGetSubnetByIDNestedCustomFieldsJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "8",
+    "subnet": "10.10.3.0",
+    "mask": "24",
+    "sectionId": "1",
+    "custom_fields": {
+      "CustomTestSubnets": "foo"
+    }
+  }
+}
+`
+
+var testGetSubnetByIDFolderOutputExpected = Subnet{
+	ID:          10,
+	Description: "Customer folder",
+	SectionID:   1,
+	IsFolder:    true,
+}
+
+const testGetSubnetByIDFolderOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "10",
+    "subnet": null,
+    "mask": "",
+    "sectionId": "1",
+    "description": "Customer folder",
+    "vrfId": null,
+    "masterSubnetId": "0",
+    "vlanId": null,
+    "isFolder": "1",
+    "isFull": "0"
+  }
+}
+`
+
 const testGetSubnetByIDOutputJSON = `
 {
   "code": 200,
@@ -204,6 +301,54 @@ const testGetSubnetsByCIDROutputJSON = `
 }
 `
 
+var testGetSubnetSlavesOutputExpected = []Subnet{
+	Subnet{
+		ID:             3,
+		SubnetAddress:  "10.10.1.0",
+		Mask:           24,
+		SectionID:      1,
+		MasterSubnetID: 2,
+	},
+	Subnet{
+		ID:             4,
+		SubnetAddress:  "10.10.2.0",
+		Mask:           24,
+		SectionID:      1,
+		MasterSubnetID: 2,
+	},
+}
+
+const testGetSubnetSlavesOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "3",
+      "subnet": "10.10.1.0",
+      "mask": "24",
+      "sectionId": "1",
+      "description": null,
+      "vrfId": null,
+      "masterSubnetId": "2",
+      "vlanId": null,
+      "isFolder": "0"
+    },
+    {
+      "id": "4",
+      "subnet": "10.10.2.0",
+      "mask": "24",
+      "sectionId": "1",
+      "description": null,
+      "vrfId": null,
+      "masterSubnetId": "2",
+      "vlanId": null,
+      "isFolder": "0"
+    }
+  ]
+}
+`
+
 const testGetFirstFreeSubnetOutputExpected = "10.10.4.0/25"
 const testGetFirstFreeSubnetOutputJSON = `
 {
@@ -467,6 +612,43 @@ const testUpdateSubnetOutputJSON = `
 }
 `
 
+const testUpdateSubnetDNSFlagsInputExpected = `{"id":"8","DNSrecursive":"1","DNSrecords":"0","resolveDNS":"0"}`
+
+const testSplitSubnetOutputExpected = `Subnet splitted`
+const testSplitSubnetOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Subnet splitted"
+}
+`
+
+const testResizeSubnetOutputExpected = `Subnet resized`
+const testResizeSubnetOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Subnet resized"
+}
+`
+
+const testResizeSubnetErrorJSON = `
+{
+  "code": 409,
+  "success": false,
+  "message": "Subnet resize would truncate existing addresses"
+}
+`
+
+const testTruncateSubnetOutputExpected = `Subnet truncated`
+const testTruncateSubnetOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Subnet truncated"
+}
+`
+
 const testDeleteSubnetOutputExpected = `Subnet deleted`
 const testDeleteSubnetOutputJSON = `
 {
@@ -476,6 +658,16 @@ const testDeleteSubnetOutputJSON = `
 }
 `
 
+const testCreateSubnetWithIDOutputJSON = `
+{
+  "code": 201,
+  "success": true,
+  "message": "Subnet created",
+  "id": "42",
+  "data": "Subnet created"
+}
+`
+
 func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
 	ts := httptest.NewServer(http.HandlerFunc(f))
 	return ts
@@ -495,6 +687,21 @@ func httpCreatedTestServer(output string) *httptest.Server {
 	})
 }
 
+// httpSearchByCustomFieldTestServer returns a server that responds with the
+// subnet custom field schema, or the subnets in output, recording the path and
+// query string of the last search in uri.
+func httpSearchByCustomFieldTestServer(output string, uri *string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/custom_fields/") {
+			http.Error(w, testGetSubnetCustomFieldsSchemaJSON, http.StatusOK)
+			return
+		}
+		*uri = r.URL.RequestURI()
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
 func fullSessionConfig() *session.Session {
 	return &session.Session{
 		Config: phpipam.Config{
@@ -527,6 +734,140 @@ func TestCreateSubnet(t *testing.T) {
 	}
 }
 
+func TestCreateSubnetOmitsUnsetIDs(t *testing.T) {
+	var body string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testCreateSubnetOutputJSON, http.StatusCreated)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := Subnet{
+		SubnetAddress: "10.10.3.0",
+		Mask:          24,
+		SectionID:     1,
+	}
+	if _, err := client.CreateSubnet(in); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	expected := `{"subnet":"10.10.3.0","mask":"24","sectionId":"1"}`
+	if body != expected {
+		t.Fatalf("Expected body %s, got %s", expected, body)
+	}
+}
+
+func TestCreateSubnetWithID(t *testing.T) {
+	ts := httpCreatedTestServer(testCreateSubnetWithIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := 42
+	actual, err := client.CreateSubnetWithID(testCreateSubnetInput)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if expected != actual {
+		t.Fatalf("Expected %d, got %d", expected, actual)
+	}
+}
+
+func TestCreateFolder(t *testing.T) {
+	var body string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, `{"code":201,"success":true,"id":"12","data":"Subnet created"}`, http.StatusCreated)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	id, err := client.CreateFolder(1, "site-a", 4)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if id != 12 {
+		t.Fatalf("Expected ID 12, got %d", id)
+	}
+	expected := `{"description":"site-a","sectionId":"1","masterSubnetId":"4","isFolder":"1"}`
+	if body != expected {
+		t.Fatalf("Expected body %s, got %s", expected, body)
+	}
+}
+
+func TestCreateFolderInvalidName(t *testing.T) {
+	sess := fullSessionConfig()
+	client := NewController(sess)
+
+	for _, name := range []string{"", " ", "10.10.1.0/24", "10.10.1.0", "2001:db8::/64"} {
+		if _, err := client.CreateFolder(1, name, 0); err == nil {
+			t.Fatalf("Expected error for folder name %q", name)
+		}
+	}
+}
+
+func TestDeleteFolder(t *testing.T) {
+	cases := []struct {
+		name          string
+		subnet        string
+		expectedErr   string
+		expectedCalls []string
+	}{
+		{
+			name:          "folder",
+			subnet:        `{"id":"12","description":"site-a","isFolder":"1"}`,
+			expectedCalls: []string{"GET /subnets/12/", "DELETE /subnets/12/"},
+		},
+		{
+			name:          "subnet",
+			subnet:        `{"id":"12","subnet":"10.10.1.0","mask":"24","isFolder":"0"}`,
+			expectedErr:   "Subnet 12 is not a folder",
+			expectedCalls: []string{"GET /subnets/12/"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Content-Type", "application/json")
+				calls = append(calls, r.Method+" "+strings.TrimPrefix(r.URL.Path, "/0123456789abcdefgh"))
+				if r.Method == "DELETE" {
+					http.Error(w, testDeleteSubnetOutputJSON, http.StatusOK)
+					return
+				}
+				http.Error(w, `{"code":200,"success":true,"data":`+tc.subnet+`}`, http.StatusOK)
+			})
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewController(sess)
+
+			_, err := client.DeleteFolder(12)
+			switch {
+			case tc.expectedErr == "" && err != nil:
+				t.Fatalf("Bad: %s", err)
+			case tc.expectedErr != "" && (err == nil || err.Error() != tc.expectedErr):
+				t.Fatalf("Expected error %q, got %v", tc.expectedErr, err)
+			}
+			if !reflect.DeepEqual(tc.expectedCalls, calls) {
+				t.Fatalf("Expected calls %v, got %v", tc.expectedCalls, calls)
+			}
+		})
+	}
+}
+
 func TestCreateFirstFreeSubnet(t *testing.T){
 	ts := httpCreatedTestServer(testCreateFirstFreeSubnetOutputJSON)
 	defer ts.Close()
@@ -566,15 +907,16 @@ func TestGetSubnetByID(t *testing.T) {
 	}
 }
 
-func TestGetSubnetsByCIDR(t *testing.T) {
-	ts := httpOKTestServer(testGetSubnetsByCIDROutputJSON)
+func TestGetSubnetsByCustomField(t *testing.T) {
+	var uri string
+	ts := httpSearchByCustomFieldTestServer(testGetSubnetsByCIDROutputJSON, &uri)
 	defer ts.Close()
 	sess := fullSessionConfig()
 	sess.Config.Endpoint = ts.URL
 	client := NewController(sess)
 
 	expected := testGetSubnetsByCIDROutputExpected
-	actual, err := client.GetSubnetsByCIDR("10.10.3.0/24")
+	actual, err := client.GetSubnetsByCustomField("CustomTestSubnets", "production")
 	if err != nil {
 		t.Fatalf("Bad: %s", err)
 	}
@@ -582,92 +924,211 @@ func TestGetSubnetsByCIDR(t *testing.T) {
 	if !reflect.DeepEqual(expected, actual) {
 		t.Fatalf("Expected %#v, got %#v", expected, actual)
 	}
+
+	expectedURI := "/0123456789abcdefgh/subnets/?filter_by=CustomTestSubnets&filter_value=production"
+	if uri != expectedURI {
+		t.Fatalf("Expected URI %s, got %s", expectedURI, uri)
+	}
 }
 
-func TestGetFirstFreeSubnet(t *testing.T) {
-	ts := httpOKTestServer(testGetFirstFreeSubnetOutputJSON)
+func TestGetSubnetsByCustomFieldInvalidField(t *testing.T) {
+	var uri string
+	ts := httpSearchByCustomFieldTestServer(testGetSubnetsByCIDROutputJSON, &uri)
 	defer ts.Close()
 	sess := fullSessionConfig()
 	sess.Config.Endpoint = ts.URL
 	client := NewController(sess)
 
-	id := 2
-	mask := 25
-	expected := testGetFirstFreeSubnetOutputExpected
-	actual, err := client.GetFirstFreeSubnet(id, mask)
-	if err != nil {
-		t.Fatalf("Bad: %s", err)
+	_, err := client.GetSubnetsByCustomField("Environment", "production")
+	if err == nil {
+		t.Fatalf("Expected error, got none")
 	}
 
-	if !reflect.DeepEqual(expected, actual) {
-		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	expected := "Custom field Environment not found in schema for controller subnets"
+	if err.Error() != expected {
+		t.Fatalf("Expected %q, got %q", expected, err.Error())
 	}
 }
 
-func TestGetFirstFreeAddress(t *testing.T) {
-	ts := httpOKTestServer(testGetFirstFreeAddressOutputJSON)
+func TestGetSubnetByIDNullIDs(t *testing.T) {
+	ts := httpOKTestServer(testGetSubnetByIDNullIDsOutputJSON)
 	defer ts.Close()
 	sess := fullSessionConfig()
 	sess.Config.Endpoint = ts.URL
 	client := NewController(sess)
 
-	expected := testGetFirstFreeAddressOutputExpected
-	actual, err := client.GetFirstFreeAddress(3)
+	expected := testGetSubnetByIDNullIDsOutputExpected
+	actual, err := client.GetSubnetByID(9)
 	if err != nil {
 		t.Fatalf("Bad: %s", err)
 	}
 
-	if expected != actual {
+	if !reflect.DeepEqual(expected, actual) {
 		t.Fatalf("Expected %#v, got %#v", expected, actual)
 	}
 }
 
-func TestGetAddressesInSubnet(t *testing.T) {
-	ts := httpOKTestServer(testGetAddressesInSubnetJSON)
-	defer ts.Close()
-	sess := fullSessionConfig()
-	sess.Config.Endpoint = ts.URL
-	client := NewController(sess)
+func TestSubnetJSONNumberIDs(t *testing.T) {
+	var actual Subnet
+	in := `{"id":"9","vlanId":3,"vrfId":"2","masterSubnetId":7,"linked_subnet":""}`
+	if err := json.Unmarshal([]byte(in), &actual); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	expected := Subnet{ID: 9, VLANID: 3, VRFID: 2, MasterSubnetID: 7}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
 
-	expected := testGetAddressesInSubnetExpected
-	actual, err := client.GetAddressesInSubnet(3)
+	b, err := json.Marshal(actual)
 	if err != nil {
 		t.Fatalf("Bad: %s", err)
 	}
+	if expected := `{"id":"9","vlanId":"3","vrfId":"2","masterSubnetId":"7"}`; string(b) != expected {
+		t.Fatalf("Expected %s, got %s", expected, b)
+	}
+}
 
-	if !reflect.DeepEqual(expected, actual) {
-		t.Fatalf("Expected %#v, got %#v", expected, actual)
+func TestGetSubnetWithCalculation(t *testing.T) {
+	cases := []struct {
+		name     string
+		output   string
+		expected SubnetWithCalculation
+		hosts    string
+	}{
+		{
+			name: "IPv4",
+			output: `{"id":"3","subnet":"10.10.1.0","mask":"24","calculation":{` +
+				`"Type":"IPv4","IP address":"10.10.1.0","Network":"10.10.1.0",` +
+				`"Broadcast":"10.10.1.255","Subnet bitmask":"24","Subnet netmask":"255.255.255.0",` +
+				`"Subnet netmask (hex)":"ffffff00","Min host IP":"10.10.1.1","Max host IP":"10.10.1.254",` +
+				`"Number of hosts":254,"Subnet Class":"false"}}`,
+			expected: SubnetWithCalculation{
+				Subnet: Subnet{ID: 3, SubnetAddress: "10.10.1.0", Mask: 24},
+				Calculation: SubnetCalculation{
+					Type:          "IPv4",
+					Network:       "10.10.1.0",
+					Bitmask:       24,
+					Netmask:       "255.255.255.0",
+					Broadcast:     "10.10.1.255",
+					MinHostIP:     "10.10.1.1",
+					MaxHostIP:     "10.10.1.254",
+					NumberOfHosts: "254",
+				},
+			},
+			hosts: "254",
+		},
+		{
+			name: "IPv6",
+			output: `{"id":"4","subnet":"2001:db8::","mask":"64","calculation":{` +
+				`"Type":"IPv6","Host address":"2001:db8::","Host address (uncompressed)":"2001:0db8:0000:0000:0000:0000:0000:0000",` +
+				`"Subnet prefix":"2001:db8::/64","Prefix length":"64","Subnet Reverse DNS":"0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa",` +
+				`"Min host IP":"2001:db8::","Max host IP":"2001:db8::ffff:ffff:ffff:ffff",` +
+				`"Number of hosts":"18446744073709551616","Address type":"NET_IPV6"}}`,
+			expected: SubnetWithCalculation{
+				Subnet: Subnet{ID: 4, SubnetAddress: "2001:db8::", Mask: 64},
+				Calculation: SubnetCalculation{
+					Type:          "IPv6",
+					Network:       "2001:db8::",
+					Bitmask:       64,
+					MinHostIP:     "2001:db8::",
+					MaxHostIP:     "2001:db8::ffff:ffff:ffff:ffff",
+					NumberOfHosts: "18446744073709551616",
+				},
+			},
+			hosts: "18446744073709551616",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var path string
+			ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				w.Header().Add("Content-Type", "application/json")
+				http.Error(w, `{"code":200,"success":true,"data":`+tc.output+`}`, http.StatusOK)
+			})
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewController(sess)
+
+			actual, err := client.GetSubnetWithCalculation(tc.expected.ID)
+			if err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+			if expected := fmt.Sprintf("/0123456789abcdefgh/subnets/%d/", tc.expected.ID); path != expected {
+				t.Fatalf("Expected path %s, got %s", expected, path)
+			}
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Fatalf("Expected %#v, got %#v", tc.expected, actual)
+			}
+			if hosts := actual.Calculation.HostCount(); hosts == nil || hosts.String() != tc.hosts {
+				t.Fatalf("Expected host count %s, got %v", tc.hosts, hosts)
+			}
+		})
 	}
 }
 
-func TestGetSubnetCustomFieldsSchema(t *testing.T) {
-	ts := httpOKTestServer(testGetSubnetCustomFieldsSchemaJSON)
+func TestGetSubnetWithCalculationMissing(t *testing.T) {
+	ts := httpOKTestServer(`{"code":200,"success":true,"data":{"id":"5","isFolder":"1"}}`)
 	defer ts.Close()
 	sess := fullSessionConfig()
 	sess.Config.Endpoint = ts.URL
 	client := NewController(sess)
 
-	expected := testGetSubnetCustomFieldsSchemaExpected
-	actual, err := client.GetSubnetCustomFieldsSchema()
-	if err != nil {
-		t.Fatalf("Bad: %s", err)
+	_, err := client.GetSubnetWithCalculation(5)
+	expected := "No calculation returned for subnet 5"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Expected error %q, got %v", expected, err)
+	}
+}
+
+func TestGetSubnetByIDWithCustomFields(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Output string
+	}{
+		{Name: "not nested", Output: testGetSubnetByIDWithCustomFieldsJSON},
+		{Name: "nested", Output: testGetSubnetByIDNestedCustomFieldsJSON},
 	}
 
-	if !reflect.DeepEqual(expected, actual) {
-		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Content-Type", "application/json")
+				if strings.HasSuffix(r.URL.Path, "/custom_fields/") {
+					http.Error(w, testGetSubnetCustomFieldsSchemaJSON, http.StatusOK)
+					return
+				}
+				http.Error(w, tc.Output, http.StatusOK)
+			})
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewController(sess)
+
+			expected := testGetSubnetByIDWithCustomFieldsExpected
+			actual, err := client.GetSubnetByIDWithCustomFields(8)
+			if err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+
+			if !reflect.DeepEqual(expected, actual) {
+				t.Fatalf("Expected %#v, got %#v", expected, actual)
+			}
+		})
 	}
 }
 
-func TestUpdateSubnet(t *testing.T) {
-	ts := httpOKTestServer(testUpdateSubnetOutputJSON)
+func TestGetSubnetByIDFolder(t *testing.T) {
+	ts := httpOKTestServer(testGetSubnetByIDFolderOutputJSON)
 	defer ts.Close()
 	sess := fullSessionConfig()
 	sess.Config.Endpoint = ts.URL
 	client := NewController(sess)
 
-	in := testUpdateSubnetInput
-	expected := testUpdateSubnetOutputExpected
-	actual, err := client.UpdateSubnet(in)
+	expected := testGetSubnetByIDFolderOutputExpected
+	actual, err := client.GetSubnetByID(10)
 	if err != nil {
 		t.Fatalf("Bad: %s", err)
 	}
@@ -675,6 +1136,1706 @@ func TestUpdateSubnet(t *testing.T) {
 	if !reflect.DeepEqual(expected, actual) {
 		t.Fatalf("Expected %#v, got %#v", expected, actual)
 	}
+
+	if !actual.IsFolderSubnet() {
+		t.Fatalf("Expected subnet to be a folder")
+	}
+}
+
+func TestSubnetGateway(t *testing.T) {
+	cases := []struct {
+		name       string
+		in         string
+		expectedIP string
+		expectedID int
+	}{
+		{
+			name:       "nested object",
+			in:         `{"gateway":{"ip_addr":"10.10.1.1","id":"5"},"gatewayId":"5"}`,
+			expectedIP: "10.10.1.1",
+			expectedID: 5,
+		},
+		{
+			name:       "numeric ID",
+			in:         `{"gateway":{"ip_addr":"10.10.1.1","id":5}}`,
+			expectedIP: "10.10.1.1",
+			expectedID: 5,
+		},
+		{
+			name:       "flat ID",
+			in:         `{"gatewayId":"5"}`,
+			expectedID: 5,
+		},
+		{
+			name: "no gateway",
+			in:   `{}`,
+		},
+		{
+			name: "null gateway",
+			in:   `{"gateway":null,"gatewayId":""}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var subnet Subnet
+			if err := json.Unmarshal([]byte(tc.in), &subnet); err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+
+			if actual := subnet.GatewayAddress(); actual != tc.expectedIP {
+				t.Fatalf("Expected gateway address %q, got %q", tc.expectedIP, actual)
+			}
+			if actual := subnet.GatewayAddressID(); actual != tc.expectedID {
+				t.Fatalf("Expected gateway address ID %d, got %d", tc.expectedID, actual)
+			}
+		})
+	}
+}
+
+func TestSubnetNameservers(t *testing.T) {
+	cases := []struct {
+		name         string
+		in           string
+		expectedIPs  []string
+		expectedName string
+	}{
+		{
+			name:         "nameserver set",
+			in:           `{"nameserverId":"1","nameservers":{"id":"1","name":"Google NS","namesrv1":"8.8.8.8;8.8.4.4","description":"Google public nameservers","permissions":"1;2","editDate":null}}`,
+			expectedIPs:  []string{"8.8.8.8", "8.8.4.4"},
+			expectedName: "Google NS",
+		},
+		{
+			name:         "trailing separator",
+			in:           `{"nameservers":{"name":"Internal","namesrv1":"10.0.0.53; "}}`,
+			expectedIPs:  []string{"10.0.0.53"},
+			expectedName: "Internal",
+		},
+		{
+			name: "no nameservers",
+			in:   `{"nameserverId":"0"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var subnet Subnet
+			if err := json.Unmarshal([]byte(tc.in), &subnet); err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+
+			if actual := subnet.NameserverIPs(); !reflect.DeepEqual(tc.expectedIPs, actual) {
+				t.Fatalf("Expected nameserver IPs %#v, got %#v", tc.expectedIPs, actual)
+			}
+			if actual := subnet.NameserverName(); actual != tc.expectedName {
+				t.Fatalf("Expected nameserver name %q, got %q", tc.expectedName, actual)
+			}
+		})
+	}
+}
+
+func TestSubnetEditDateTime(t *testing.T) {
+	loc := time.FixedZone("UTC-5", -5*60*60)
+	actual, ok, err := Subnet{EditDate: "2023-01-02 15:04:05"}.EditDateTime(loc)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if expected := time.Date(2023, 1, 2, 20, 4, 5, 0, time.UTC); !ok || !actual.Equal(expected) {
+		t.Fatalf("Expected %s, got %s (%t)", expected, actual, ok)
+	}
+
+	if _, ok, err := (Subnet{}).EditDateTime(loc); ok || err != nil {
+		t.Fatalf("Expected no edit date, got %t, %v", ok, err)
+	}
+}
+
+func TestWithoutFolders(t *testing.T) {
+	list := []Subnet{
+		testGetSubnetByIDFolderOutputExpected,
+		testGetSubnetByIDOutputExpected,
+	}
+
+	expected := []Subnet{testGetSubnetByIDOutputExpected}
+	actual := WithoutFolders(list)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestOnlyFolders(t *testing.T) {
+	list := []Subnet{
+		testGetSubnetByIDFolderOutputExpected,
+		testGetSubnetByIDOutputExpected,
+	}
+
+	expected := []Subnet{testGetSubnetByIDFolderOutputExpected}
+	actual := OnlyFolders(list)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetAllSubnets(t *testing.T) {
+	ts := httpOKTestServer(testGetSubnetSlavesOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSubnetSlavesOutputExpected
+	actual, err := client.GetAllSubnets()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+// httpLinksTestServer returns a server that responds to subnet listings with
+// the subnet in testGetSubnetByIDOutputJSON, which carries the full set of
+// links that PHPIPAM returns for a subnet. The response is compacted, and the
+// links are left out if the request sets links=false. The size of each
+// response body is recorded in sizes.
+func httpLinksTestServer(t *testing.T, sizes *[]int) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		var resp struct {
+			Code    int                        `json:"code"`
+			Success bool                       `json:"success"`
+			Data    map[string]json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(testGetSubnetByIDOutputJSON), &resp); err != nil {
+			t.Errorf("Bad fixture: %s", err)
+		}
+		if r.URL.Query().Get("links") == "false" {
+			delete(resp.Data, "links")
+		}
+		bs, err := json.Marshal(map[string]interface{}{
+			"code":    resp.Code,
+			"success": resp.Success,
+			"data":    []interface{}{resp.Data},
+		})
+		if err != nil {
+			t.Errorf("Bad: %s", err)
+		}
+		*sizes = append(*sizes, len(bs))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write(bs)
+	})
+}
+
+func TestGetAllSubnetsDisableLinks(t *testing.T) {
+	var sizes []int
+	ts := httpLinksTestServer(t, &sizes)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	withLinks, err := client.GetAllSubnets()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	sess.DisableLinks = true
+	withoutLinks, err := client.GetAllSubnets()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(withLinks, withoutLinks) {
+		t.Fatalf("Expected %#v, got %#v", withLinks, withoutLinks)
+	}
+	if len(sizes) != 2 || sizes[1] >= sizes[0] {
+		t.Fatalf("Expected smaller response without links, got sizes %v", sizes)
+	}
+	t.Logf("Response size with links: %d bytes, without: %d bytes (%.0f%% smaller)",
+		sizes[0], sizes[1], 100*float64(sizes[0]-sizes[1])/float64(sizes[0]))
+}
+
+const testSearchSubnetsByDescriptionJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {"id": "3", "subnet": "10.10.1.0", "mask": "24", "description": "dc1:prod:web"},
+    {"id": "4", "subnet": "10.10.2.0", "mask": "24", "description": "DC1:Prod:db"},
+    {"id": "5", "subnet": "10.10.3.0", "mask": "24", "description": "dc2:test:web"},
+    {"id": "6", "subnet": "10.10.4.0", "mask": "24", "description": null}
+  ]
+}
+`
+
+func TestSearchSubnetsByDescription(t *testing.T) {
+	ts := httpOKTestServer(testSearchSubnetsByDescriptionJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	cases := []struct {
+		term     string
+		expected []int
+	}{
+		{term: "dc1:prod", expected: []int{3, 4}},
+		{term: "WEB", expected: []int{3, 5}},
+		{term: "dc3", expected: []int{}},
+	}
+	for _, tc := range cases {
+		list, err := client.SearchSubnetsByDescription(tc.term)
+		if err != nil {
+			t.Fatalf("Bad: %s", err)
+		}
+		if list == nil {
+			t.Fatalf("Expected an empty list for %q, got nil", tc.term)
+		}
+		actual := make([]int, 0, len(list))
+		for _, v := range list {
+			actual = append(actual, v.ID)
+		}
+		if !reflect.DeepEqual(tc.expected, actual) {
+			t.Fatalf("Expected IDs %v for %q, got %v", tc.expected, tc.term, actual)
+		}
+	}
+}
+
+func TestGetSubnetsByCIDR(t *testing.T) {
+	ts := httpOKTestServer(testGetSubnetsByCIDROutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSubnetsByCIDROutputExpected
+	actual, err := client.GetSubnetsByCIDR("10.10.3.0/24")
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetSubnetsByCIDRPath(t *testing.T) {
+	cases := []struct {
+		in           string
+		expectedPath string
+		err          bool
+	}{
+		{in: "10.10.3.0/24", expectedPath: "/0123456789abcdefgh/subnets/cidr/10.10.3.0/24/"},
+		{in: "2001:db8:1::/48", expectedPath: "/0123456789abcdefgh/subnets/cidr/2001:db8:1::/48/"},
+		{in: "2001:0db8:0001:0000:0000:0000:0000:0000/48", expectedPath: "/0123456789abcdefgh/subnets/cidr/2001:db8:1::/48/"},
+		{in: "10.10.3.0", err: true},
+		{in: "10.10.3.0/40", err: true},
+		{in: "2001:db8:::/48", err: true},
+	}
+
+	for _, tc := range cases {
+		var path string
+		ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			w.Header().Add("Content-Type", "application/json")
+			http.Error(w, testGetSubnetsByCIDROutputJSON, http.StatusOK)
+		})
+		sess := fullSessionConfig()
+		sess.Config.Endpoint = ts.URL
+		client := NewController(sess)
+
+		_, err := client.GetSubnetsByCIDR(tc.in)
+		ts.Close()
+		if tc.err {
+			if err == nil {
+				t.Fatalf("%s: expected error", tc.in)
+			}
+			if path != "" {
+				t.Fatalf("%s: expected no request, got %s", tc.in, path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Bad: %s", tc.in, err)
+		}
+		if path != tc.expectedPath {
+			t.Fatalf("%s: expected path %s, got %s", tc.in, tc.expectedPath, path)
+		}
+	}
+}
+
+func TestGetSubnetByCIDRInSection(t *testing.T) {
+	ts := httpOKTestServer(testGetSubnetsByCIDROutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSubnetsByCIDROutputExpected[0]
+	actual, err := client.GetSubnetByCIDRInSection("10.10.3.0/24", 1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetSubnetByCIDRInSectionNotFound(t *testing.T) {
+	ts := httpOKTestServer(testGetSubnetsByCIDROutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	_, err := client.GetSubnetByCIDRInSection("10.10.3.0/24", 2)
+	if !request.IsNotFound(err) {
+		t.Fatalf("Expected not found error, got %v", err)
+	}
+}
+
+func TestGetSubnetSlaves(t *testing.T) {
+	ts := httpOKTestServer(testGetSubnetSlavesOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSubnetSlavesOutputExpected
+	actual, err := client.GetSubnetSlaves(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetSubnetSlavesRecursive(t *testing.T) {
+	ts := httpOKTestServer(testGetSubnetSlavesOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSubnetSlavesOutputExpected
+	actual, err := client.GetSubnetSlavesRecursive(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+// httpSubnetChainTestServer returns a server that responds to subnet lookups
+// by ID with a subnet with that ID and the master subnet in masters, or a 404
+// if the ID is not in masters.
+func httpSubnetChainTestServer(masters map[int]int) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		var id int
+		fmt.Sscanf(r.URL.Path, "/0123456789abcdefgh/subnets/%d/", &id)
+		master, ok := masters[id]
+		if !ok {
+			http.Error(w, `{"code":404,"success":false,"message":"Subnet does not exist"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf(`{"code":200,"success":true,"data":{"id":"%d","masterSubnetId":"%d"}}`, id, master), http.StatusOK)
+	})
+}
+
+func TestGetSubnetAncestors(t *testing.T) {
+	cases := []struct {
+		name      string
+		masters   map[int]int
+		expected  []int
+		expectErr bool
+	}{
+		{name: "top-level", masters: map[int]int{4: 0}, expected: []int{}},
+		{name: "nested", masters: map[int]int{4: 3, 3: 2, 2: 1, 1: 0}, expected: []int{1, 2, 3}},
+		{name: "missing parent", masters: map[int]int{4: 3, 3: 2}, expected: []int{3}, expectErr: true},
+		{name: "loop", masters: map[int]int{4: 3, 3: 2, 2: 3}, expected: []int{2, 3}, expectErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httpSubnetChainTestServer(tc.masters)
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewController(sess)
+
+			out, err := client.GetSubnetAncestors(4)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("Expected error %t, got %v", tc.expectErr, err)
+			}
+			actual := []int{}
+			for _, v := range out {
+				actual = append(actual, v.ID)
+			}
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Fatalf("Expected ancestors %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetFirstFreeSubnet(t *testing.T) {
+	ts := httpOKTestServer(testGetFirstFreeSubnetOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	id := 2
+	mask := 25
+	expected := testGetFirstFreeSubnetOutputExpected
+	actual, err := client.GetFirstFreeSubnet(id, mask)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestCreateChildSubnet(t *testing.T) {
+	cases := []struct {
+		name          string
+		masterCIDR    string
+		child         Subnet
+		expectedErr   string
+		expectedCalls []string
+		expectedBody  string
+	}{
+		{
+			name:          "fits",
+			masterCIDR:    "10.10.1.0/24",
+			child:         Subnet{SubnetAddress: "10.10.1.128", Mask: 25, Description: "web"},
+			expectedCalls: []string{"GET /subnets/cidr/10.10.1.0/24/", "POST /subnets/"},
+			expectedBody:  `{"subnet":"10.10.1.128","mask":"25","description":"web","sectionId":"1","masterSubnetId":"3"}`,
+		},
+		{
+			name:          "outside master",
+			masterCIDR:    "10.10.1.0/24",
+			child:         Subnet{SubnetAddress: "10.10.2.0", Mask: 25},
+			expectedErr:   "Subnet 10.10.2.0/25 does not fit in master subnet 10.10.1.0/24",
+			expectedCalls: []string{"GET /subnets/cidr/10.10.1.0/24/"},
+		},
+		{
+			name:          "same size as master",
+			masterCIDR:    "10.10.1.0/24",
+			child:         Subnet{SubnetAddress: "10.10.1.0", Mask: 24},
+			expectedErr:   "Subnet 10.10.1.0/24 does not fit in master subnet 10.10.1.0/24",
+			expectedCalls: []string{"GET /subnets/cidr/10.10.1.0/24/"},
+		},
+		{
+			name:          "master not found",
+			masterCIDR:    "10.10.9.0/24",
+			child:         Subnet{SubnetAddress: "10.10.9.0", Mask: 25},
+			expectedErr:   "Error from API (404): Master subnet 10.10.9.0/24 not found in section 1",
+			expectedCalls: []string{"GET /subnets/cidr/10.10.9.0/24/"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			var body string
+			ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Content-Type", "application/json")
+				path := strings.TrimPrefix(r.URL.Path, "/0123456789abcdefgh")
+				calls = append(calls, r.Method+" "+path)
+				switch {
+				case r.Method == "POST":
+					b, _ := ioutil.ReadAll(r.Body)
+					body = string(b)
+					http.Error(w, testCreateSubnetOutputJSON, http.StatusCreated)
+				case path == "/subnets/cidr/10.10.1.0/24/":
+					http.Error(w, `{"code":200,"success":true,"data":[{"id":"3","subnet":"10.10.1.0","mask":"24","sectionId":"1"}]}`, http.StatusOK)
+				default:
+					http.Error(w, `{"code":404,"success":false,"message":"No subnets found"}`, http.StatusNotFound)
+				}
+			})
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewController(sess)
+
+			_, err := client.CreateChildSubnet(tc.masterCIDR, 1, tc.child)
+			switch {
+			case tc.expectedErr == "" && err != nil:
+				t.Fatalf("Bad: %s", err)
+			case tc.expectedErr != "" && (err == nil || err.Error() != tc.expectedErr):
+				t.Fatalf("Expected error %q, got %v", tc.expectedErr, err)
+			}
+			if !reflect.DeepEqual(tc.expectedCalls, calls) {
+				t.Fatalf("Expected calls %v, got %v", tc.expectedCalls, calls)
+			}
+			if body != tc.expectedBody {
+				t.Fatalf("Expected body %s, got %s", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestGetFirstFreeSubnetCIDR(t *testing.T) {
+	cases := []struct {
+		name        string
+		data        string
+		mask        int
+		expected    string
+		expectedErr error
+		expectErr   bool
+	}{
+		{name: "IPv4", data: `"10.10.4.0/25"`, expected: "10.10.4.0/25"},
+		{name: "IPv6", data: `"2001:0db8:0000:0000:0000:0000:0000:0000/64"`, mask: 64, expected: "2001:db8::/64"},
+		{name: "none free", data: `""`, expectedErr: ErrNoFreeSubnet, expectErr: true},
+		{name: "none free null", data: `null`, expectedErr: ErrNoFreeSubnet, expectErr: true},
+		{name: "invalid", data: `"foo"`, expectErr: true},
+		{name: "host bits set", data: `"10.10.4.1/25"`, expectErr: true},
+		{name: "wrong mask", data: `"10.10.4.0/26"`, expectErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httpOKTestServer(`{"code":200,"success":true,"data":` + tc.data + `}`)
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewController(sess)
+
+			mask := tc.mask
+			if mask == 0 {
+				mask = 25
+			}
+			actual, err := client.GetFirstFreeSubnetCIDR(2, mask)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("Expected error %t, got %v", tc.expectErr, err)
+			}
+			if tc.expectedErr != nil && !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if actual != tc.expected {
+				t.Fatalf("Expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetBiggestFreeSubnet(t *testing.T) {
+	cases := []struct {
+		name        string
+		master      string
+		slaves      string
+		mask        int
+		expected    string
+		expectedErr error
+		expectErr   bool
+	}{
+		{
+			name:     "no children",
+			master:   `{"id":"2","subnet":"10.10.0.0","mask":"22"}`,
+			mask:     26,
+			expected: "10.10.0.0/26",
+		},
+		{
+			name:   "largest gap",
+			master: `{"id":"2","subnet":"10.10.0.0","mask":"22"}`,
+			slaves: `[{"id":"3","subnet":"10.10.0.0","mask":"25"},` +
+				`{"id":"4","subnet":"10.10.1.0","mask":"24"},` +
+				`{"id":"5","subnet":"10.10.3.0","mask":"26"},` +
+				`{"id":"6","description":"site","isFolder":"1"}]`,
+			mask:     26,
+			expected: "10.10.2.0/26",
+		},
+		{
+			name:   "only a small gap fits",
+			master: `{"id":"2","subnet":"10.10.0.0","mask":"24"}`,
+			slaves: `[{"id":"3","subnet":"10.10.0.0","mask":"25"},` +
+				`{"id":"4","subnet":"10.10.0.128","mask":"26"},` +
+				`{"id":"5","subnet":"10.10.0.224","mask":"27"}]`,
+			mask:     28,
+			expected: "10.10.0.192/28",
+		},
+		{
+			name:     "IPv6",
+			master:   `{"id":"2","subnet":"2001:db8::","mask":"48"}`,
+			slaves:   `[{"id":"3","subnet":"2001:db8::","mask":"64"}]`,
+			mask:     64,
+			expected: "2001:db8:0:8000::/64",
+		},
+		{
+			name:        "full",
+			master:      `{"id":"2","subnet":"10.10.0.0","mask":"24"}`,
+			slaves:      `[{"id":"3","subnet":"10.10.0.0","mask":"25"},{"id":"4","subnet":"10.10.0.128","mask":"25"}]`,
+			mask:        26,
+			expectedErr: ErrNoFreeSubnet,
+			expectErr:   true,
+		},
+		{
+			name:      "mask too big",
+			master:    `{"id":"2","subnet":"10.10.0.0","mask":"24"}`,
+			mask:      24,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Content-Type", "application/json")
+				switch {
+				case strings.HasSuffix(r.URL.Path, "/slaves/") && tc.slaves == "":
+					http.Error(w, `{"code":404,"success":false,"message":"No slaves"}`, http.StatusNotFound)
+				case strings.HasSuffix(r.URL.Path, "/slaves/"):
+					http.Error(w, `{"code":200,"success":true,"data":`+tc.slaves+`}`, http.StatusOK)
+				default:
+					http.Error(w, `{"code":200,"success":true,"data":`+tc.master+`}`, http.StatusOK)
+				}
+			})
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewController(sess)
+
+			actual, err := client.GetBiggestFreeSubnet(2, tc.mask)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("Expected error %t, got %v", tc.expectErr, err)
+			}
+			if tc.expectedErr != nil && !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if actual != tc.expected {
+				t.Fatalf("Expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCreateFirstFreeSubnetWithResult(t *testing.T) {
+	cases := []struct {
+		name          string
+		create        string
+		expectedCalls []string
+	}{
+		{
+			name:          "ID in response",
+			create:        testCreateFirstFreeSubnetOutputJSON,
+			expectedCalls: []string{"POST /subnets/2/first_subnet/25/", "GET /subnets/10/"},
+		},
+		{
+			name:          "lookup by CIDR",
+			create:        `{"code":201,"success":true,"data":"10.10.4.0/25"}`,
+			expectedCalls: []string{"POST /subnets/2/first_subnet/25/", "GET /subnets/cidr/10.10.4.0/25/"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Content-Type", "application/json")
+				path := strings.TrimPrefix(r.URL.Path, "/0123456789abcdefgh")
+				calls = append(calls, r.Method+" "+path)
+				switch {
+				case r.Method == "POST":
+					http.Error(w, tc.create, http.StatusCreated)
+				case strings.HasPrefix(path, "/subnets/cidr/"):
+					http.Error(w, `{"code":200,"success":true,"data":[`+
+						`{"id":"9","subnet":"10.10.4.0","mask":"25","masterSubnetId":"5"},`+
+						`{"id":"10","subnet":"10.10.4.0","mask":"25","masterSubnetId":"2"}]}`, http.StatusOK)
+				default:
+					http.Error(w, `{"code":200,"success":true,"data":{"id":"10","subnet":"10.10.4.0","mask":"25","masterSubnetId":"2"}}`, http.StatusOK)
+				}
+			})
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewController(sess)
+
+			out, err := client.CreateFirstFreeSubnetWithResult(2, 25, Subnet{Description: "web"})
+			if err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+			if out.ID != 10 {
+				t.Fatalf("Expected subnet 10, got %d", out.ID)
+			}
+			if !reflect.DeepEqual(tc.expectedCalls, calls) {
+				t.Fatalf("Expected calls %v, got %v", tc.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestGetFirstFreeAddress(t *testing.T) {
+	ts := httpOKTestServer(testGetFirstFreeAddressOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetFirstFreeAddressOutputExpected
+	actual, err := client.GetFirstFreeAddress(3)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if expected != actual {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+const testFirstFreeAddressesNotFoundJSON = `
+{
+  "code": 404,
+  "success": false,
+  "message": "No addresses found"
+}
+`
+
+// httpGetFirstFreeAddressesTestServer returns a server that responds to
+// subnet lookups with a subnet with the supplied address and mask, and to
+// address lookups with the supplied IPs, or a 404 if there are none.
+func httpGetFirstFreeAddressesTestServer(subnet string, mask int, ips []string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		if !strings.HasSuffix(r.URL.Path, "/addresses/") {
+			http.Error(w, fmt.Sprintf(`{"code":200,"success":true,"data":{"id":"3","subnet":"%s","mask":"%d"}}`, subnet, mask), http.StatusOK)
+			return
+		}
+		if len(ips) == 0 {
+			http.Error(w, testFirstFreeAddressesNotFoundJSON, http.StatusNotFound)
+			return
+		}
+		data := make([]string, 0, len(ips))
+		for i, ip := range ips {
+			data = append(data, fmt.Sprintf(`{"id":"%d","subnetId":"3","ip":"%s"}`, i+1, ip))
+		}
+		http.Error(w, fmt.Sprintf(`{"code":200,"success":true,"data":[%s]}`, strings.Join(data, ",")), http.StatusOK)
+	})
+}
+
+func TestGetFirstFreeAddresses(t *testing.T) {
+	cases := []struct {
+		name     string
+		subnet   string
+		mask     int
+		used     []string
+		count    int
+		expected []string
+		short    bool
+	}{
+		{
+			name:     "IPv4",
+			subnet:   "10.10.1.0",
+			mask:     29,
+			used:     []string{"10.10.1.1", "10.10.1.3"},
+			count:    3,
+			expected: []string{"10.10.1.2", "10.10.1.4", "10.10.1.5"},
+		},
+		{
+			name:     "IPv4 shortfall",
+			subnet:   "10.10.1.0",
+			mask:     29,
+			used:     []string{"10.10.1.1", "10.10.1.3"},
+			count:    8,
+			expected: []string{"10.10.1.2", "10.10.1.4", "10.10.1.5", "10.10.1.6"},
+			short:    true,
+		},
+		{
+			name:     "IPv4 /31",
+			subnet:   "10.10.1.0",
+			mask:     31,
+			count:    2,
+			expected: []string{"10.10.1.0", "10.10.1.1"},
+		},
+		{
+			name:     "IPv6",
+			subnet:   "2001:db8::",
+			mask:     64,
+			used:     []string{"2001:db8::1", "2001:0db8::0002"},
+			count:    2,
+			expected: []string{"2001:db8::3", "2001:db8::4"},
+		},
+		{
+			name:   "full",
+			subnet: "10.10.1.0",
+			mask:   30,
+			used:   []string{"10.10.1.1", "10.10.1.2"},
+			count:  1,
+			short:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httpGetFirstFreeAddressesTestServer(tc.subnet, tc.mask, tc.used)
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewController(sess)
+
+			actual, err := client.GetFirstFreeAddresses(3, tc.count)
+			switch {
+			case tc.short && !errors.Is(err, ErrNotEnoughFreeAddresses):
+				t.Fatalf("Expected ErrNotEnoughFreeAddresses, got %v", err)
+			case !tc.short && err != nil:
+				t.Fatalf("Bad: %s", err)
+			}
+
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Fatalf("Expected %#v, got %#v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetAddressesInSubnet(t *testing.T) {
+	ts := httpOKTestServer(testGetAddressesInSubnetJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetAddressesInSubnetExpected
+	actual, err := client.GetAddressesInSubnet(3)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+const testAddressCustomFieldsSchemaJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "custom_AssetTag": {
+      "name": "custom_AssetTag",
+      "type": "varchar(255)",
+      "Comment": "Asset tag",
+      "Null": "YES",
+      "Default": null
+    }
+  }
+}
+`
+
+// httpAddressesWithCustomFieldsTestServer returns a server that responds to
+// the address list of subnet 3 with one address carrying nested custom fields
+// and two without, to the address custom field schema, and to lookups of
+// address 2 with its custom fields. Lookups of other addresses fail with a 404.
+// The number of address lookups is recorded in lookups.
+func httpAddressesWithCustomFieldsTestServer(lookups *int32) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/0123456789abcdefgh/subnets/3/addresses/":
+			http.Error(w, `{"code":200,"success":true,"data":[`+
+				`{"id":"1","subnetId":"3","ip":"10.10.1.3","custom_fields":{"custom_AssetTag":"A-1"}},`+
+				`{"id":"2","subnetId":"3","ip":"10.10.1.4"},`+
+				`{"id":"3","subnetId":"3","ip":"10.10.1.5"}]}`, http.StatusOK)
+		case "/0123456789abcdefgh/addresses/custom_fields/":
+			http.Error(w, testAddressCustomFieldsSchemaJSON, http.StatusOK)
+		case "/0123456789abcdefgh/addresses/2/":
+			atomic.AddInt32(lookups, 1)
+			http.Error(w, `{"code":200,"success":true,"data":{"id":"2","ip":"10.10.1.4","custom_AssetTag":"A-2"}}`, http.StatusOK)
+		default:
+			atomic.AddInt32(lookups, 1)
+			http.Error(w, `{"code":404,"success":false,"message":"Address does not exist"}`, http.StatusNotFound)
+		}
+	})
+}
+
+func TestGetAddressesInSubnetWithCustomFields(t *testing.T) {
+	var lookups int32
+	ts := httpAddressesWithCustomFieldsTestServer(&lookups)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	out, err := client.GetAddressesInSubnetWithCustomFields(3)
+	var merr phpipam.MultiError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Expected phpipam.MultiError, got %v", err)
+	}
+	if len(merr) != 1 || !request.IsNotFound(merr[3]) {
+		t.Fatalf("Expected a not found error for address 3 only, got %v", merr)
+	}
+	if n := atomic.LoadInt32(&lookups); n != 2 {
+		t.Fatalf("Expected 2 address lookups, got %d", n)
+	}
+
+	expected := []string{"A-1", "A-2", ""}
+	if len(out) != len(expected) {
+		t.Fatalf("Expected %d addresses, got %d", len(expected), len(out))
+	}
+	for i, v := range out {
+		actual, _ := v.CustomFields.GetString("custom_AssetTag")
+		if actual != expected[i] {
+			t.Fatalf("Expected custom_AssetTag %q for address %d, got %q", expected[i], v.ID, actual)
+		}
+	}
+}
+
+// httpFilterAddressesTestServer returns a server that responds with output,
+// recording the path and query string of the last request in uri.
+func httpFilterAddressesTestServer(output string, code int, uri *string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		*uri = r.URL.RequestURI()
+		http.Error(w, output, code)
+	})
+}
+
+func TestGetAddressesInSubnetByTag(t *testing.T) {
+	var uri string
+	ts := httpFilterAddressesTestServer(testGetAddressesInSubnetJSON, http.StatusOK, &uri)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := []addresses.Address{
+		testGetAddressesInSubnetExpected[2],
+		testGetAddressesInSubnetExpected[3],
+	}
+	actual, err := client.GetAddressesInSubnetByTag(3, 3)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+	if expectedURI := "/0123456789abcdefgh/subnets/3/addresses/?filter_by=tag&filter_value=3"; uri != expectedURI {
+		t.Fatalf("Expected URI %s, got %s", expectedURI, uri)
+	}
+}
+
+func TestGetAddressesInSubnetByTagNotFound(t *testing.T) {
+	var uri string
+	ts := httpFilterAddressesTestServer(testFirstFreeAddressesNotFoundJSON, http.StatusNotFound, &uri)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	actual, err := client.GetAddressesInSubnetByTag(3, 4)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if len(actual) != 0 {
+		t.Fatalf("Expected no addresses, got %#v", actual)
+	}
+}
+
+func TestGetSubnetGatewayAddress(t *testing.T) {
+	var uri string
+	output := `{"code":200,"success":true,"data":[` +
+		`{"id":"5","subnetId":"3","ip":"10.10.1.245","is_gateway":"0"},` +
+		`{"id":"6","subnetId":"3","ip":"10.10.1.1","is_gateway":"1"}]}`
+	ts := httpFilterAddressesTestServer(output, http.StatusOK, &uri)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	actual, err := client.GetSubnetGatewayAddress(3)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if actual.ID != 6 || actual.IPAddress != "10.10.1.1" {
+		t.Fatalf("Expected gateway 10.10.1.1 (ID 6), got %#v", actual)
+	}
+	if expectedURI := "/0123456789abcdefgh/subnets/3/addresses/?filter_by=is_gateway&filter_value=1"; uri != expectedURI {
+		t.Fatalf("Expected URI %s, got %s", expectedURI, uri)
+	}
+}
+
+func TestGetSubnetGatewayAddressNotFound(t *testing.T) {
+	var uri string
+	ts := httpFilterAddressesTestServer(testGetAddressesInSubnetJSON, http.StatusOK, &uri)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	_, err := client.GetSubnetGatewayAddress(3)
+	if !request.IsNotFound(err) {
+		t.Fatalf("Expected not found error, got %v", err)
+	}
+}
+
+// httpGatewayTestServer returns a server that responds to address lookups by
+// ID with the address in subnet 3 with that ID, and to gateway lookups in
+// subnet 3 with gateways. The method and path of each request are recorded in
+// calls, and the body of each PATCH in bodies.
+func httpGatewayTestServer(gateways string, calls, bodies *[]string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/0123456789abcdefgh")
+		*calls = append(*calls, r.Method+" "+path)
+		switch {
+		case r.Method == "PATCH":
+			b, _ := ioutil.ReadAll(r.Body)
+			*bodies = append(*bodies, string(b))
+			http.Error(w, `{"code":200,"success":true,"data":"Address updated"}`, http.StatusOK)
+		case strings.HasPrefix(path, "/subnets/3/addresses/"):
+			http.Error(w, `{"code":200,"success":true,"data":`+gateways+`}`, http.StatusOK)
+		case strings.HasPrefix(path, "/addresses/"):
+			id := strings.Trim(strings.TrimPrefix(path, "/addresses/"), "/")
+			http.Error(w, `{"code":200,"success":true,"data":{"id":"`+id+`","subnetId":"3","ip":"10.10.1.`+id+`"}}`, http.StatusOK)
+		default:
+			http.Error(w, `{"code":404,"success":false,"message":"Not found"}`, http.StatusNotFound)
+		}
+	})
+}
+
+func TestSetSubnetGateway(t *testing.T) {
+	var calls, bodies []string
+	ts := httpGatewayTestServer(`[{"id":"5","subnetId":"3","ip":"10.10.1.5","is_gateway":"1"}]`, &calls, &bodies)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	if _, err := client.SetSubnetGateway(3, 1); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	expectedCalls := []string{
+		"GET /addresses/1/",
+		"PATCH /addresses/",
+		"GET /subnets/3/addresses/",
+		"PATCH /addresses/",
+	}
+	if !reflect.DeepEqual(expectedCalls, calls) {
+		t.Fatalf("Expected calls %v, got %v", expectedCalls, calls)
+	}
+	expectedBodies := []string{`{"id":1,"is_gateway":"1"}`, `{"id":5,"is_gateway":"0"}`}
+	if !reflect.DeepEqual(expectedBodies, bodies) {
+		t.Fatalf("Expected bodies %v, got %v", expectedBodies, bodies)
+	}
+}
+
+func TestSetSubnetGatewayWrongSubnet(t *testing.T) {
+	var calls, bodies []string
+	ts := httpGatewayTestServer(`[]`, &calls, &bodies)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	_, err := client.SetSubnetGateway(4, 1)
+	expected := "Address 1 is in subnet 3, not subnet 4"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Expected error %q, got %v", expected, err)
+	}
+	if len(bodies) != 0 {
+		t.Fatalf("Expected no updates, got %v", bodies)
+	}
+}
+
+func TestUnsetSubnetGateway(t *testing.T) {
+	var calls, bodies []string
+	ts := httpGatewayTestServer(`[`+
+		`{"id":"1","subnetId":"3","ip":"10.10.1.1","is_gateway":"1"},`+
+		`{"id":"5","subnetId":"3","ip":"10.10.1.5","is_gateway":"1"}]`, &calls, &bodies)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	if err := client.UnsetSubnetGateway(3); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	expectedBodies := []string{`{"id":1,"is_gateway":"0"}`, `{"id":5,"is_gateway":"0"}`}
+	if !reflect.DeepEqual(expectedBodies, bodies) {
+		t.Fatalf("Expected bodies %v, got %v", expectedBodies, bodies)
+	}
+}
+
+func TestGetOfflineAddresses(t *testing.T) {
+	var uri string
+	ts := httpFilterAddressesTestServer(testGetAddressesInSubnetJSON, http.StatusOK, &uri)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	actual, err := client.GetOfflineAddresses(3)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if len(actual) != 0 {
+		t.Fatalf("Expected no addresses, got %#v", actual)
+	}
+	if expectedURI := "/0123456789abcdefgh/subnets/3/addresses/?filter_by=tag&filter_value=1"; uri != expectedURI {
+		t.Fatalf("Expected URI %s, got %s", expectedURI, uri)
+	}
+}
+
+func TestGetAddressesNotSeenSince(t *testing.T) {
+	ts := httpOKTestServer(`{"code":200,"success":true,"data":[` +
+		`{"id":"1","ip":"10.10.1.3","lastSeen":"2023-01-02 15:04:05"},` +
+		`{"id":"2","ip":"10.10.1.4","lastSeen":"2023-01-02 16:30:00"},` +
+		`{"id":"3","ip":"10.10.1.5","lastSeen":"0000-00-00 00:00:00"},` +
+		`{"id":"4","ip":"10.10.1.6","lastSeen":null},` +
+		`{"id":"5","ip":"10.10.1.7","lastSeen":null,"excludePing":"1"}]}`)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	since := time.Date(2023, 1, 2, 16, 0, 0, 0, time.FixedZone("CET", 3600))
+	out, err := client.GetAddressesNotSeenSince(3, since)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	expected := []int{1, 3, 4}
+	actual := []int{}
+	for _, v := range out {
+		actual = append(actual, v.ID)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected addresses %v, got %v", expected, actual)
+	}
+}
+
+func TestGetAddressesInSubnetPage(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Limit    int
+		Offset   int
+		Expected []addresses.Address
+	}{
+		{Name: "first page", Limit: 2, Offset: 0, Expected: testGetAddressesInSubnetExpected[0:2]},
+		{Name: "middle page", Limit: 2, Offset: 2, Expected: testGetAddressesInSubnetExpected[2:4]},
+		{Name: "last page", Limit: 2, Offset: 4, Expected: testGetAddressesInSubnetExpected[4:]},
+		{Name: "no limit", Limit: 0, Offset: 1, Expected: testGetAddressesInSubnetExpected[1:]},
+		{Name: "past end", Limit: 2, Offset: 10, Expected: []addresses.Address{}},
+	}
+
+	ts := httpOKTestServer(testGetAddressesInSubnetJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, total, err := client.GetAddressesInSubnetPage(3, tc.Limit, tc.Offset)
+			if err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+
+			if len(tc.Expected) != len(actual) || (len(actual) > 0 && !reflect.DeepEqual(tc.Expected, actual)) {
+				t.Fatalf("Expected %#v, got %#v", tc.Expected, actual)
+			}
+
+			if total != len(testGetAddressesInSubnetExpected) {
+				t.Fatalf("Expected total %d, got %d", len(testGetAddressesInSubnetExpected), total)
+			}
+		})
+	}
+}
+
+func TestGetAllSubnetsPage(t *testing.T) {
+	ts := httpOKTestServer(testGetSubnetSlavesOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSubnetSlavesOutputExpected[1:2]
+	actual, total, err := client.GetAllSubnetsPage(1, 1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	if total != len(testGetSubnetSlavesOutputExpected) {
+		t.Fatalf("Expected total %d, got %d", len(testGetSubnetSlavesOutputExpected), total)
+	}
+}
+
+const testGetSubnetChangelogOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "user": "Admin",
+      "action": "edit",
+      "result": "success",
+      "date": "2026-01-15 10:42:07",
+      "diff": {
+        "description": "[old] foo\r\n[new] bar"
+      }
+    }
+  ]
+}
+`
+
+var testGetSubnetChangelogOutputExpected = []phpipam.ChangelogEntry{
+	phpipam.ChangelogEntry{
+		User:   "Admin",
+		Action: "edit",
+		Result: "success",
+		Date:   "2026-01-15 10:42:07",
+		Diff: map[string]interface{}{
+			"description": "[old] foo\r\n[new] bar",
+		},
+	},
+}
+
+func TestGetSubnetChangelog(t *testing.T) {
+	var path string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testGetSubnetChangelogOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSubnetChangelogOutputExpected
+	actual, err := client.GetSubnetChangelog(3)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	expectedPath := "/0123456789abcdefgh/subnets/3/changelog/"
+	if path != expectedPath {
+		t.Fatalf("Expected path %s, got %s", expectedPath, path)
+	}
+}
+
+func TestGetSubnetCustomFieldsSchema(t *testing.T) {
+	ts := httpOKTestServer(testGetSubnetCustomFieldsSchemaJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSubnetCustomFieldsSchemaExpected
+	actual, err := client.GetSubnetCustomFieldsSchema()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestUpdateSubnet(t *testing.T) {
+	ts := httpOKTestServer(testUpdateSubnetOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testUpdateSubnetInput
+	expected := testUpdateSubnetOutputExpected
+	actual, err := client.UpdateSubnet(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestUpdateSubnetByID(t *testing.T) {
+	var method, path, body string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		path = r.URL.Path
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testUpdateSubnetOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testUpdateSubnetInput
+	expected := testUpdateSubnetOutputExpected
+	actual, err := client.UpdateSubnetByID(8, in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+	if method != "PATCH" {
+		t.Fatalf("Expected method PATCH, got %s", method)
+	}
+	if expectedPath := "/0123456789abcdefgh/subnets/8/"; path != expectedPath {
+		t.Fatalf("Expected path %s, got %s", expectedPath, path)
+	}
+	if expectedBody := `{"description":"foobat"}`; body != expectedBody {
+		t.Fatalf("Expected body %s, got %s", expectedBody, body)
+	}
+}
+
+// httpEnsureSubnetTestServer returns a server that responds to CIDR lookups
+// with existing, or a 404 if existing is empty, creates subnets with ID 9,
+// and responds to lookups by ID with existing, or the created subnet. The
+// method and path of each request are recorded in calls, and the body of any
+// PATCH in body.
+func httpEnsureSubnetTestServer(existing string, calls *[]string, body *string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/0123456789abcdefgh")
+		*calls = append(*calls, r.Method+" "+path)
+		switch {
+		case r.Method == "POST":
+			http.Error(w, `{"code":201,"success":true,"id":"9","data":"Subnet created"}`, http.StatusCreated)
+		case r.Method == "PATCH":
+			b, _ := ioutil.ReadAll(r.Body)
+			*body = string(b)
+			http.Error(w, testUpdateSubnetOutputJSON, http.StatusOK)
+		case strings.HasPrefix(path, "/subnets/cidr/") && existing == "":
+			http.Error(w, `{"code":404,"success":false,"message":"No subnets found"}`, http.StatusNotFound)
+		case strings.HasPrefix(path, "/subnets/cidr/"):
+			http.Error(w, `{"code":200,"success":true,"data":[`+existing+`]}`, http.StatusOK)
+		case existing == "":
+			http.Error(w, `{"code":200,"success":true,"data":{"id":"9","subnet":"10.10.5.0","mask":"24","sectionId":"1","description":"web"}}`, http.StatusOK)
+		default:
+			http.Error(w, `{"code":200,"success":true,"data":`+existing+`}`, http.StatusOK)
+		}
+	})
+}
+
+func TestEnsureSubnet(t *testing.T) {
+	in := Subnet{
+		SubnetAddress: "10.10.5.0",
+		Mask:          24,
+		SectionID:     1,
+		Description:   "web",
+	}
+
+	cases := []struct {
+		name            string
+		existing        string
+		expectedCreated bool
+		expectedCalls   []string
+		expectedBody    string
+	}{
+		{
+			name:            "create",
+			expectedCreated: true,
+			expectedCalls:   []string{"GET /subnets/cidr/10.10.5.0/24/", "POST /subnets/", "GET /subnets/9/"},
+		},
+		{
+			name:          "unchanged",
+			existing:      `{"id":"7","subnet":"10.10.5.0","mask":"24","sectionId":"1","description":"web","vlanId":"2"}`,
+			expectedCalls: []string{"GET /subnets/cidr/10.10.5.0/24/"},
+		},
+		{
+			name:          "drifted",
+			existing:      `{"id":"7","subnet":"10.10.5.0","mask":"24","sectionId":"1","description":"old"}`,
+			expectedCalls: []string{"GET /subnets/cidr/10.10.5.0/24/", "PATCH /subnets/", "GET /subnets/7/"},
+			expectedBody:  `{"description":"web","id":7}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			var body string
+			ts := httpEnsureSubnetTestServer(tc.existing, &calls, &body)
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewController(sess)
+
+			_, created, err := client.EnsureSubnet(in)
+			if err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+			if created != tc.expectedCreated {
+				t.Fatalf("Expected created to be %t, got %t", tc.expectedCreated, created)
+			}
+			if !reflect.DeepEqual(tc.expectedCalls, calls) {
+				t.Fatalf("Expected calls %v, got %v", tc.expectedCalls, calls)
+			}
+			if body != tc.expectedBody {
+				t.Fatalf("Expected body %s, got %s", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestEnsureSubnetCreatedWithoutID(t *testing.T) {
+	var calls []string
+	var created bool
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		calls = append(calls, r.Method+" "+strings.TrimPrefix(r.URL.Path, "/0123456789abcdefgh"))
+		switch {
+		case r.Method == "POST":
+			created = true
+			http.Error(w, `{"code":201,"success":true,"data":"Subnet created"}`, http.StatusCreated)
+		case created:
+			http.Error(w, `{"code":200,"success":true,"data":[{"id":"9","subnet":"10.10.5.0","mask":"24","sectionId":"1","description":"web"}]}`, http.StatusOK)
+		default:
+			http.Error(w, `{"code":404,"success":false,"message":"No subnets found"}`, http.StatusNotFound)
+		}
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	out, ok, err := client.EnsureSubnet(Subnet{SubnetAddress: "10.10.5.0", Mask: 24, SectionID: 1, Description: "web"})
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if !ok || out.ID != 9 {
+		t.Fatalf("Expected created subnet 9, got subnet %d (created: %t)", out.ID, ok)
+	}
+	expected := []string{"GET /subnets/cidr/10.10.5.0/24/", "POST /subnets/", "GET /subnets/cidr/10.10.5.0/24/"}
+	if !reflect.DeepEqual(expected, calls) {
+		t.Fatalf("Expected calls %v, got %v", expected, calls)
+	}
+}
+
+func TestSplitSubnet(t *testing.T) {
+	var path string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testSplitSubnetOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testSplitSubnetOutputExpected
+	actual, err := client.SplitSubnet(2, 4)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	expectedPath := "/0123456789abcdefgh/subnets/2/split/4/"
+	if path != expectedPath {
+		t.Fatalf("Expected path %s, got %s", expectedPath, path)
+	}
+}
+
+func TestSplitSubnetWithIDs(t *testing.T) {
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		switch r.Method {
+		case "PATCH":
+			http.Error(w, testSplitSubnetOutputJSON, http.StatusOK)
+		default:
+			http.Error(w, testGetSubnetSlavesOutputJSON, http.StatusOK)
+		}
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := []int{3, 4}
+	actual, err := client.SplitSubnetWithIDs(2, 2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestResizeSubnet(t *testing.T) {
+	var path string
+	var body []byte
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testResizeSubnetOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testResizeSubnetOutputExpected
+	actual, err := client.ResizeSubnet(8, 23)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	if path != "/0123456789abcdefgh/subnets/8/resize/" {
+		t.Fatalf("Unexpected path %s", path)
+	}
+
+	if string(body) != `{"mask":"23"}` {
+		t.Fatalf("Unexpected request body %s", body)
+	}
+}
+
+func TestResizeSubnetError(t *testing.T) {
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testResizeSubnetErrorJSON, http.StatusConflict)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	_, err := client.ResizeSubnet(8, 25)
+	if err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+
+	if !request.IsConflict(err) {
+		t.Fatalf("Expected conflict error, got %s", err)
+	}
+}
+
+func TestPatchSubnet(t *testing.T) {
+	var body string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testUpdateSubnetOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	fields := map[string]interface{}{
+		"vlanId":      nil,
+		"vrfId":       nil,
+		"description": "",
+	}
+	if _, err := client.PatchSubnet(3, fields); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	expected := `{"description":"","id":3,"vlanId":null,"vrfId":null}`
+	if body != expected {
+		t.Fatalf("Expected body %s, got %s", expected, body)
+	}
+}
+
+func TestPatchSubnetUnknownField(t *testing.T) {
+	sess := fullSessionConfig()
+	client := NewController(sess)
+
+	for _, k := range []string{"vlan", "id"} {
+		_, err := client.PatchSubnet(3, map[string]interface{}{k: nil})
+		expected := fmt.Sprintf("Field %s is not a known field for controller subnets", k)
+		if err == nil || err.Error() != expected {
+			t.Fatalf("Expected error %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestUpdateSubnetDNSFlags(t *testing.T) {
+	var body []byte
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testUpdateSubnetOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testUpdateSubnetOutputExpected
+	actual, err := client.UpdateSubnetDNSFlags(8, true, false, false)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	if string(body) != testUpdateSubnetDNSFlagsInputExpected {
+		t.Fatalf("Expected request body %s, got %s", testUpdateSubnetDNSFlagsInputExpected, body)
+	}
+}
+
+func TestTruncateSubnet(t *testing.T) {
+	var path, body string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testTruncateSubnetOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testTruncateSubnetOutputExpected
+	actual, err := client.TruncateSubnet(8, true)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	expectedPath := "/0123456789abcdefgh/subnets/8/truncate/"
+	if path != expectedPath {
+		t.Fatalf("Expected path %s, got %s", expectedPath, path)
+	}
+
+	expectedBody := `{"remove_dns":"1"}`
+	if body != expectedBody {
+		t.Fatalf("Expected body %s, got %s", expectedBody, body)
+	}
 }
 
 func TestDeleteSubnet(t *testing.T) {
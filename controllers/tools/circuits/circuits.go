@@ -0,0 +1,106 @@
+// Package circuits provides types and methods for working with the circuits
+// tools controller.
+package circuits
+
+import (
+	"fmt"
+
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+// Circuit represents a PHPIPAM circuit.
+type Circuit struct {
+	// The circuit ID.
+	ID int `json:"id,string,omitempty"`
+
+	// The circuit's identifier, as assigned by the provider.
+	CID string `json:"cid,omitempty"`
+
+	// The ID of the circuit's provider.
+	ProviderID int `json:"provider,string,omitempty"`
+
+	// The ID of the circuit's type.
+	Type int `json:"type,string,omitempty"`
+
+	// The capacity of the circuit (ie: 1 Gbps).
+	Capacity string `json:"capacity,omitempty"`
+
+	// The status of the circuit. Should be one of Active, Inactive, or
+	// Reserved.
+	Status string `json:"status,omitempty"`
+
+	// The ID of the device at the circuit's A end.
+	Device1 int `json:"device1,string,omitempty"`
+
+	// The ID of the location of the circuit's A end.
+	Location1 int `json:"location1,string,omitempty"`
+
+	// The ID of the device at the circuit's B end.
+	Device2 int `json:"device2,string,omitempty"`
+
+	// The ID of the location of the circuit's B end.
+	Location2 int `json:"location2,string,omitempty"`
+
+	// A comment for the circuit.
+	Comment string `json:"comment,omitempty"`
+}
+
+// Controller is the base client for the circuits controller.
+type Controller struct {
+	client.Client
+}
+
+// NewController returns a new instance of the client for the circuits
+// controller.
+func NewController(sess *session.Session) *Controller {
+	c := &Controller{
+		Client: *client.NewClient(sess),
+	}
+	return c
+}
+
+// ListCircuits lists all circuits.
+func (c *Controller) ListCircuits() (out []Circuit, err error) {
+	err = c.SendRequest("GET", "/tools/circuits/", &struct{}{}, &out)
+	return
+}
+
+// CreateCircuit creates a circuit by sending a POST request.
+func (c *Controller) CreateCircuit(in Circuit) (message string, err error) {
+	err = c.SendRequest("POST", "/tools/circuits/", &in, &message)
+	return
+}
+
+// GetCircuitByID GETs a circuit via its ID.
+func (c *Controller) GetCircuitByID(id int) (out Circuit, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/tools/circuits/%d/", id), &struct{}{}, &out)
+	return
+}
+
+// GetCircuitsByProvider lists all circuits via ListCircuits, and returns the
+// ones belonging to the provider with the supplied ID.
+func (c *Controller) GetCircuitsByProvider(id int) (out []Circuit, err error) {
+	var list []Circuit
+	if list, err = c.ListCircuits(); err != nil {
+		return
+	}
+	for _, v := range list {
+		if v.ProviderID == id {
+			out = append(out, v)
+		}
+	}
+	return
+}
+
+// UpdateCircuit updates a circuit by sending a PATCH request.
+func (c *Controller) UpdateCircuit(in Circuit) (message string, err error) {
+	err = c.SendRequest("PATCH", fmt.Sprintf("/tools/circuits/%d/", in.ID), &in, &message)
+	return
+}
+
+// DeleteCircuit deletes a circuit by its ID.
+func (c *Controller) DeleteCircuit(id int) (message string, err error) {
+	err = c.SendRequest("DELETE", fmt.Sprintf("/tools/circuits/%d/", id), &struct{}{}, &message)
+	return
+}
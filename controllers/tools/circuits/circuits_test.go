@@ -0,0 +1,297 @@
+package circuits
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+var testListCircuitsOutputExpected = []Circuit{
+	Circuit{
+		ID:         1,
+		CID:        "WAN-0001",
+		ProviderID: 1,
+		Type:       1,
+		Capacity:   "1 Gbps",
+		Status:     "Active",
+		Device1:    3,
+		Location1:  1,
+		Comment:    "Head office uplink",
+	},
+	Circuit{
+		ID:         2,
+		CID:        "WAN-0002",
+		ProviderID: 2,
+		Type:       1,
+		Capacity:   "500 Mbps",
+		Status:     "Reserved",
+	},
+	Circuit{
+		ID:         3,
+		CID:        "WAN-0003",
+		ProviderID: 1,
+		Type:       2,
+		Capacity:   "10 Gbps",
+		Status:     "Inactive",
+	},
+}
+
+const testListCircuitsOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "1",
+      "cid": "WAN-0001",
+      "provider": "1",
+      "type": "1",
+      "capacity": "1 Gbps",
+      "status": "Active",
+      "device1": "3",
+      "location1": "1",
+      "device2": "0",
+      "location2": "0",
+      "comment": "Head office uplink"
+    },
+    {
+      "id": "2",
+      "cid": "WAN-0002",
+      "provider": "2",
+      "type": "1",
+      "capacity": "500 Mbps",
+      "status": "Reserved",
+      "device1": "0",
+      "location1": "0",
+      "device2": "0",
+      "location2": "0",
+      "comment": null
+    },
+    {
+      "id": "3",
+      "cid": "WAN-0003",
+      "provider": "1",
+      "type": "2",
+      "capacity": "10 Gbps",
+      "status": "Inactive",
+      "device1": "0",
+      "location1": "0",
+      "device2": "0",
+      "location2": "0",
+      "comment": null
+    }
+  ]
+}
+`
+
+var testGetCircuitsByProviderOutputExpected = []Circuit{
+	testListCircuitsOutputExpected[0],
+	testListCircuitsOutputExpected[2],
+}
+
+const testGetCircuitsByProviderOutputJSON = testListCircuitsOutputJSON
+
+var testCreateCircuitInput = Circuit{
+	CID:        "WAN-0004",
+	ProviderID: 2,
+}
+
+const testCreateCircuitOutputExpected = `Circuit created`
+const testCreateCircuitOutputJSON = `
+{
+  "code": 201,
+  "success": true,
+  "data": "Circuit created"
+}
+`
+
+var testGetCircuitByIDOutputExpected = testListCircuitsOutputExpected[1]
+
+const testGetCircuitByIDOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "2",
+    "cid": "WAN-0002",
+    "provider": "2",
+    "type": "1",
+    "capacity": "500 Mbps",
+    "status": "Reserved",
+    "device1": "0",
+    "location1": "0",
+    "device2": "0",
+    "location2": "0",
+    "comment": null
+  }
+}
+`
+
+var testUpdateCircuitInput = Circuit{
+	ID:     2,
+	Status: "Active",
+}
+
+const testUpdateCircuitOutputExpected = `Circuit updated`
+const testUpdateCircuitOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Circuit updated"
+}
+`
+
+const testDeleteCircuitOutputExpected = `Circuit deleted`
+const testDeleteCircuitOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Circuit deleted"
+}
+`
+
+func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(f))
+	return ts
+}
+
+func httpOKTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
+func httpCreatedTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusCreated)
+	})
+}
+
+func fullSessionConfig() *session.Session {
+	return &session.Session{
+		Config: phpipam.Config{
+			AppID:    "0123456789abcdefgh",
+			Password: "changeit",
+			Username: "nobody",
+		},
+		Token: session.Token{
+			String: "foobarbazboop",
+		},
+	}
+}
+
+func TestListCircuits(t *testing.T) {
+	ts := httpOKTestServer(testListCircuitsOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testListCircuitsOutputExpected
+	actual, err := client.ListCircuits()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestCreateCircuit(t *testing.T) {
+	ts := httpCreatedTestServer(testCreateCircuitOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testCreateCircuitInput
+	expected := testCreateCircuitOutputExpected
+	actual, err := client.CreateCircuit(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetCircuitByID(t *testing.T) {
+	ts := httpOKTestServer(testGetCircuitByIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetCircuitByIDOutputExpected
+	actual, err := client.GetCircuitByID(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetCircuitsByProvider(t *testing.T) {
+	ts := httpOKTestServer(testGetCircuitsByProviderOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetCircuitsByProviderOutputExpected
+	actual, err := client.GetCircuitsByProvider(1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestUpdateCircuit(t *testing.T) {
+	ts := httpOKTestServer(testUpdateCircuitOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testUpdateCircuitInput
+	expected := testUpdateCircuitOutputExpected
+	actual, err := client.UpdateCircuit(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestDeleteCircuit(t *testing.T) {
+	ts := httpOKTestServer(testDeleteCircuitOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testDeleteCircuitOutputExpected
+	actual, err := client.DeleteCircuit(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
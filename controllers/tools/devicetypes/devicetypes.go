@@ -0,0 +1,67 @@
+// Package devicetypes provides types and methods for working with the device
+// types tools controller.
+package devicetypes
+
+import (
+	"fmt"
+
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+// DeviceType represents a PHPIPAM device type.
+type DeviceType struct {
+	// The device type ID. This is what is referenced by the type field of a
+	// device.
+	ID int `json:"tid,string,omitempty"`
+
+	// The device type's name.
+	Name string `json:"tname,omitempty"`
+
+	// The device type's description.
+	Description string `json:"tdescription,omitempty"`
+}
+
+// Controller is the base client for the device types controller.
+type Controller struct {
+	client.Client
+}
+
+// NewController returns a new instance of the client for the device types
+// controller.
+func NewController(sess *session.Session) *Controller {
+	c := &Controller{
+		Client: *client.NewClient(sess),
+	}
+	return c
+}
+
+// ListDeviceTypes lists all device types.
+func (c *Controller) ListDeviceTypes() (out []DeviceType, err error) {
+	err = c.SendRequest("GET", "/tools/device_types/", &struct{}{}, &out)
+	return
+}
+
+// CreateDeviceType creates a device type by sending a POST request.
+func (c *Controller) CreateDeviceType(in DeviceType) (message string, err error) {
+	err = c.SendRequest("POST", "/tools/device_types/", &in, &message)
+	return
+}
+
+// GetDeviceTypeByID GETs a device type via its ID.
+func (c *Controller) GetDeviceTypeByID(id int) (out DeviceType, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/tools/device_types/%d/", id), &struct{}{}, &out)
+	return
+}
+
+// UpdateDeviceType updates a device type by sending a PATCH request.
+func (c *Controller) UpdateDeviceType(in DeviceType) (message string, err error) {
+	err = c.SendRequest("PATCH", fmt.Sprintf("/tools/device_types/%d/", in.ID), &in, &message)
+	return
+}
+
+// DeleteDeviceType deletes a device type by its ID.
+func (c *Controller) DeleteDeviceType(id int) (message string, err error) {
+	err = c.SendRequest("DELETE", fmt.Sprintf("/tools/device_types/%d/", id), &struct{}{}, &message)
+	return
+}
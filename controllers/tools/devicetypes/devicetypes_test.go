@@ -0,0 +1,222 @@
+package devicetypes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+var testListDeviceTypesOutputExpected = []DeviceType{
+	DeviceType{
+		ID:          1,
+		Name:        "Switch",
+		Description: "Switch",
+	},
+	DeviceType{
+		ID:          2,
+		Name:        "Router",
+		Description: "Router",
+	},
+}
+
+const testListDeviceTypesOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "tid": "1",
+      "tname": "Switch",
+      "tdescription": "Switch"
+    },
+    {
+      "tid": "2",
+      "tname": "Router",
+      "tdescription": "Router"
+    }
+  ]
+}
+`
+
+var testCreateDeviceTypeInput = DeviceType{
+	Name:        "Load balancer",
+	Description: "Load balancer",
+}
+
+const testCreateDeviceTypeOutputExpected = `Device type created`
+const testCreateDeviceTypeOutputJSON = `
+{
+  "code": 201,
+  "success": true,
+  "data": "Device type created"
+}
+`
+
+var testGetDeviceTypeByIDOutputExpected = DeviceType{
+	ID:          2,
+	Name:        "Router",
+	Description: "Router",
+}
+
+const testGetDeviceTypeByIDOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "tid": "2",
+    "tname": "Router",
+    "tdescription": "Router"
+  }
+}
+`
+
+var testUpdateDeviceTypeInput = DeviceType{
+	ID:          2,
+	Description: "Core router",
+}
+
+const testUpdateDeviceTypeOutputExpected = `Device type updated`
+const testUpdateDeviceTypeOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Device type updated"
+}
+`
+
+const testDeleteDeviceTypeOutputExpected = `Device type deleted`
+const testDeleteDeviceTypeOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Device type deleted"
+}
+`
+
+func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(f))
+	return ts
+}
+
+func httpOKTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
+func httpCreatedTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusCreated)
+	})
+}
+
+func fullSessionConfig() *session.Session {
+	return &session.Session{
+		Config: phpipam.Config{
+			AppID:    "0123456789abcdefgh",
+			Password: "changeit",
+			Username: "nobody",
+		},
+		Token: session.Token{
+			String: "foobarbazboop",
+		},
+	}
+}
+
+func TestListDeviceTypes(t *testing.T) {
+	ts := httpOKTestServer(testListDeviceTypesOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testListDeviceTypesOutputExpected
+	actual, err := client.ListDeviceTypes()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestCreateDeviceType(t *testing.T) {
+	ts := httpCreatedTestServer(testCreateDeviceTypeOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testCreateDeviceTypeInput
+	expected := testCreateDeviceTypeOutputExpected
+	actual, err := client.CreateDeviceType(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetDeviceTypeByID(t *testing.T) {
+	ts := httpOKTestServer(testGetDeviceTypeByIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetDeviceTypeByIDOutputExpected
+	actual, err := client.GetDeviceTypeByID(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestUpdateDeviceType(t *testing.T) {
+	ts := httpOKTestServer(testUpdateDeviceTypeOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testUpdateDeviceTypeInput
+	expected := testUpdateDeviceTypeOutputExpected
+	actual, err := client.UpdateDeviceType(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestDeleteDeviceType(t *testing.T) {
+	ts := httpOKTestServer(testDeleteDeviceTypeOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testDeleteDeviceTypeOutputExpected
+	actual, err := client.DeleteDeviceType(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
@@ -0,0 +1,91 @@
+// Package locations provides types and methods for working with the locations
+// tools controller.
+package locations
+
+import (
+	"fmt"
+
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/devices"
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/subnets"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+// Location represents a PHPIPAM location.
+type Location struct {
+	// The location ID. This is what is referenced by the Location field of
+	// subnets and devices.
+	ID int `json:"id,string,omitempty"`
+
+	// The location's name.
+	Name string `json:"name,omitempty"`
+
+	// A detailed description of the location.
+	Description string `json:"description,omitempty"`
+
+	// The street address of the location.
+	Address string `json:"address,omitempty"`
+
+	// The latitude of the location. PHPIPAM returns this as a string, so it's
+	// left as-is here to avoid parsing errors on blank or malformed values.
+	Lat string `json:"lat,omitempty"`
+
+	// The longitude of the location. See Lat for why this is a string.
+	Long string `json:"long,omitempty"`
+}
+
+// Controller is the base client for the locations controller.
+type Controller struct {
+	client.Client
+}
+
+// NewController returns a new instance of the client for the locations
+// controller.
+func NewController(sess *session.Session) *Controller {
+	c := &Controller{
+		Client: *client.NewClient(sess),
+	}
+	return c
+}
+
+// ListLocations lists all locations.
+func (c *Controller) ListLocations() (out []Location, err error) {
+	err = c.SendRequest("GET", "/tools/locations/", &struct{}{}, &out)
+	return
+}
+
+// CreateLocation creates a location by sending a POST request.
+func (c *Controller) CreateLocation(in Location) (message string, err error) {
+	err = c.SendRequest("POST", "/tools/locations/", &in, &message)
+	return
+}
+
+// GetLocationByID GETs a location via its ID.
+func (c *Controller) GetLocationByID(id int) (out Location, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/tools/locations/%d/", id), &struct{}{}, &out)
+	return
+}
+
+// GetSubnetsByLocation GETs the subnets assigned to a location.
+func (c *Controller) GetSubnetsByLocation(id int) (out []subnets.Subnet, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/tools/locations/%d/subnets/", id), &struct{}{}, &out)
+	return
+}
+
+// GetDevicesByLocation GETs the devices assigned to a location.
+func (c *Controller) GetDevicesByLocation(id int) (out []devices.Device, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/tools/locations/%d/devices/", id), &struct{}{}, &out)
+	return
+}
+
+// UpdateLocation updates a location by sending a PATCH request.
+func (c *Controller) UpdateLocation(in Location) (message string, err error) {
+	err = c.SendRequest("PATCH", fmt.Sprintf("/tools/locations/%d/", in.ID), &in, &message)
+	return
+}
+
+// DeleteLocation deletes a location by its ID.
+func (c *Controller) DeleteLocation(id int) (message string, err error) {
+	err = c.SendRequest("DELETE", fmt.Sprintf("/tools/locations/%d/", id), &struct{}{}, &message)
+	return
+}
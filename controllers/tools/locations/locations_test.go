@@ -0,0 +1,310 @@
+package locations
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/devices"
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/subnets"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+var testListLocationsOutputExpected = []Location{
+	Location{
+		ID:          1,
+		Name:        "DC1",
+		Description: "Primary datacenter",
+		Address:     "1 Main St, Vancouver",
+		Lat:         "49.2827291",
+		Long:        "-123.1207375",
+	},
+}
+
+const testListLocationsOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "1",
+      "name": "DC1",
+      "description": "Primary datacenter",
+      "address": "1 Main St, Vancouver",
+      "lat": "49.2827291",
+      "long": "-123.1207375"
+    }
+  ]
+}
+`
+
+var testCreateLocationInput = Location{
+	Name:    "DC2",
+	Address: "2 Main St, Vancouver",
+}
+
+const testCreateLocationOutputExpected = `Location created`
+const testCreateLocationOutputJSON = `
+{
+  "code": 201,
+  "success": true,
+  "data": "Location created"
+}
+`
+
+var testGetLocationByIDOutputExpected = Location{
+	ID:   2,
+	Name: "DC2",
+}
+
+const testGetLocationByIDOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "2",
+    "name": "DC2",
+    "description": null,
+    "address": null,
+    "lat": "",
+    "long": ""
+  }
+}
+`
+
+var testGetSubnetsByLocationOutputExpected = []subnets.Subnet{
+	subnets.Subnet{
+		ID:            3,
+		SubnetAddress: "10.10.1.0",
+		Mask:          24,
+		SectionID:     1,
+		Location:      1,
+	},
+}
+
+const testGetSubnetsByLocationOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "3",
+      "subnet": "10.10.1.0",
+      "mask": "24",
+      "sectionId": "1",
+      "masterSubnetId": "0",
+      "location": "1"
+    }
+  ]
+}
+`
+
+var testGetDevicesByLocationOutputExpected = []devices.Device{
+	devices.Device{
+		ID:        1,
+		Hostname:  "sw1.cust1.local",
+		IPAddress: "10.10.1.2",
+		Location:  1,
+	},
+}
+
+const testGetDevicesByLocationOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "1",
+      "hostname": "sw1.cust1.local",
+      "ip": "10.10.1.2",
+      "type": "0",
+      "location": "1"
+    }
+  ]
+}
+`
+
+var testUpdateLocationInput = Location{
+	ID:          2,
+	Description: "Secondary datacenter",
+}
+
+const testUpdateLocationOutputExpected = `Location updated`
+const testUpdateLocationOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Location updated"
+}
+`
+
+const testDeleteLocationOutputExpected = `Location deleted`
+const testDeleteLocationOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Location deleted"
+}
+`
+
+func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(f))
+	return ts
+}
+
+func httpOKTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
+func httpCreatedTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusCreated)
+	})
+}
+
+func fullSessionConfig() *session.Session {
+	return &session.Session{
+		Config: phpipam.Config{
+			AppID:    "0123456789abcdefgh",
+			Password: "changeit",
+			Username: "nobody",
+		},
+		Token: session.Token{
+			String: "foobarbazboop",
+		},
+	}
+}
+
+func TestListLocations(t *testing.T) {
+	ts := httpOKTestServer(testListLocationsOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testListLocationsOutputExpected
+	actual, err := client.ListLocations()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestCreateLocation(t *testing.T) {
+	ts := httpCreatedTestServer(testCreateLocationOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testCreateLocationInput
+	expected := testCreateLocationOutputExpected
+	actual, err := client.CreateLocation(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetLocationByID(t *testing.T) {
+	ts := httpOKTestServer(testGetLocationByIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetLocationByIDOutputExpected
+	actual, err := client.GetLocationByID(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetSubnetsByLocation(t *testing.T) {
+	ts := httpOKTestServer(testGetSubnetsByLocationOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSubnetsByLocationOutputExpected
+	actual, err := client.GetSubnetsByLocation(1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetDevicesByLocation(t *testing.T) {
+	ts := httpOKTestServer(testGetDevicesByLocationOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetDevicesByLocationOutputExpected
+	actual, err := client.GetDevicesByLocation(1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestUpdateLocation(t *testing.T) {
+	ts := httpOKTestServer(testUpdateLocationOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testUpdateLocationInput
+	expected := testUpdateLocationOutputExpected
+	actual, err := client.UpdateLocation(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestDeleteLocation(t *testing.T) {
+	ts := httpOKTestServer(testDeleteLocationOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testDeleteLocationOutputExpected
+	actual, err := client.DeleteLocation(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
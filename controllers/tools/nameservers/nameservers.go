@@ -0,0 +1,98 @@
+// Package nameservers provides types and methods for working with the
+// nameservers tools controller.
+package nameservers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/subnets"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+// Nameserver represents a PHPIPAM nameserver set.
+type Nameserver struct {
+	// The nameserver set ID. This is what is referenced by the NameserverID
+	// field of a subnet.
+	ID int `json:"id,string,omitempty"`
+
+	// The nameserver set's name.
+	Name string `json:"name,omitempty"`
+
+	// The nameservers in this set, separated by semicolons. Use Servers to
+	// get these as a slice.
+	NameServers string `json:"namesrv1,omitempty"`
+
+	// A detailed description of the nameserver set.
+	Description string `json:"description,omitempty"`
+
+	// The IDs of the sections this nameserver set is available in, separated by
+	// semicolons.
+	Permissions string `json:"permissions,omitempty"`
+
+	// The date of the last edit to this resource.
+	EditDate string `json:"editDate,omitempty"`
+}
+
+// Servers returns the nameservers in the set as a slice.
+func (n Nameserver) Servers() []string {
+	var out []string
+	for _, v := range strings.Split(n.NameServers, ";") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Controller is the base client for the nameservers controller.
+type Controller struct {
+	client.Client
+}
+
+// NewController returns a new instance of the client for the nameservers
+// controller.
+func NewController(sess *session.Session) *Controller {
+	c := &Controller{
+		Client: *client.NewClient(sess),
+	}
+	return c
+}
+
+// ListNameservers lists all nameserver sets.
+func (c *Controller) ListNameservers() (out []Nameserver, err error) {
+	err = c.SendRequest("GET", "/tools/nameservers/", &struct{}{}, &out)
+	return
+}
+
+// CreateNameserver creates a nameserver set by sending a POST request.
+func (c *Controller) CreateNameserver(in Nameserver) (message string, err error) {
+	err = c.SendRequest("POST", "/tools/nameservers/", &in, &message)
+	return
+}
+
+// GetNameserverByID GETs a nameserver set via its ID.
+func (c *Controller) GetNameserverByID(id int) (out Nameserver, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/tools/nameservers/%d/", id), &struct{}{}, &out)
+	return
+}
+
+// GetSubnetsByNameserver GETs the subnets that use the supplied nameserver
+// set.
+func (c *Controller) GetSubnetsByNameserver(id int) (out []subnets.Subnet, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/tools/nameservers/%d/subnets/", id), &struct{}{}, &out)
+	return
+}
+
+// UpdateNameserver updates a nameserver set by sending a PATCH request.
+func (c *Controller) UpdateNameserver(in Nameserver) (message string, err error) {
+	err = c.SendRequest("PATCH", fmt.Sprintf("/tools/nameservers/%d/", in.ID), &in, &message)
+	return
+}
+
+// DeleteNameserver deletes a nameserver set by its ID.
+func (c *Controller) DeleteNameserver(id int) (message string, err error) {
+	err = c.SendRequest("DELETE", fmt.Sprintf("/tools/nameservers/%d/", id), &struct{}{}, &message)
+	return
+}
@@ -0,0 +1,281 @@
+package nameservers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/subnets"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+var testListNameserversOutputExpected = []Nameserver{
+	Nameserver{
+		ID:          1,
+		Name:        "Google NS",
+		NameServers: "8.8.8.8;8.8.4.4",
+		Description: "Google public nameservers",
+		Permissions: "1;2",
+	},
+}
+
+const testListNameserversOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "1",
+      "name": "Google NS",
+      "namesrv1": "8.8.8.8;8.8.4.4",
+      "description": "Google public nameservers",
+      "permissions": "1;2",
+      "editDate": null
+    }
+  ]
+}
+`
+
+var testCreateNameserverInput = Nameserver{
+	Name:        "Internal NS",
+	NameServers: "10.10.1.2;10.10.1.3",
+	Permissions: "1",
+}
+
+const testCreateNameserverOutputExpected = `Nameserver created`
+const testCreateNameserverOutputJSON = `
+{
+  "code": 201,
+  "success": true,
+  "data": "Nameserver created"
+}
+`
+
+var testGetNameserverByIDOutputExpected = Nameserver{
+	ID:          1,
+	Name:        "Google NS",
+	NameServers: "8.8.8.8;8.8.4.4",
+	Description: "Google public nameservers",
+	Permissions: "1;2",
+}
+
+const testGetNameserverByIDOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "1",
+    "name": "Google NS",
+    "namesrv1": "8.8.8.8;8.8.4.4",
+    "description": "Google public nameservers",
+    "permissions": "1;2",
+    "editDate": null
+  }
+}
+`
+
+var testGetSubnetsByNameserverOutputExpected = []subnets.Subnet{
+	subnets.Subnet{
+		ID:            3,
+		SubnetAddress: "10.10.1.0",
+		Mask:          24,
+		SectionID:     1,
+		NameserverID:  1,
+	},
+}
+
+const testGetSubnetsByNameserverOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "3",
+      "subnet": "10.10.1.0",
+      "mask": "24",
+      "sectionId": "1",
+      "description": null,
+      "vrfId": null,
+      "masterSubnetId": "0",
+      "nameserverId": "1"
+    }
+  ]
+}
+`
+
+var testUpdateNameserverInput = Nameserver{
+	ID:          1,
+	Description: "Public nameservers",
+}
+
+const testUpdateNameserverOutputExpected = `Nameserver updated`
+const testUpdateNameserverOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Nameserver updated"
+}
+`
+
+const testDeleteNameserverOutputExpected = `Nameserver deleted`
+const testDeleteNameserverOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Nameserver deleted"
+}
+`
+
+func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(f))
+	return ts
+}
+
+func httpOKTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
+func httpCreatedTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusCreated)
+	})
+}
+
+func fullSessionConfig() *session.Session {
+	return &session.Session{
+		Config: phpipam.Config{
+			AppID:    "0123456789abcdefgh",
+			Password: "changeit",
+			Username: "nobody",
+		},
+		Token: session.Token{
+			String: "foobarbazboop",
+		},
+	}
+}
+
+func TestNameserverServers(t *testing.T) {
+	n := Nameserver{NameServers: "8.8.8.8; 8.8.4.4;"}
+	expected := []string{"8.8.8.8", "8.8.4.4"}
+	actual := n.Servers()
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestListNameservers(t *testing.T) {
+	ts := httpOKTestServer(testListNameserversOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testListNameserversOutputExpected
+	actual, err := client.ListNameservers()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestCreateNameserver(t *testing.T) {
+	ts := httpCreatedTestServer(testCreateNameserverOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testCreateNameserverInput
+	expected := testCreateNameserverOutputExpected
+	actual, err := client.CreateNameserver(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetNameserverByID(t *testing.T) {
+	ts := httpOKTestServer(testGetNameserverByIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetNameserverByIDOutputExpected
+	actual, err := client.GetNameserverByID(1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetSubnetsByNameserver(t *testing.T) {
+	ts := httpOKTestServer(testGetSubnetsByNameserverOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSubnetsByNameserverOutputExpected
+	actual, err := client.GetSubnetsByNameserver(1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestUpdateNameserver(t *testing.T) {
+	ts := httpOKTestServer(testUpdateNameserverOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testUpdateNameserverInput
+	expected := testUpdateNameserverOutputExpected
+	actual, err := client.UpdateNameserver(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestDeleteNameserver(t *testing.T) {
+	ts := httpOKTestServer(testDeleteNameserverOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testDeleteNameserverOutputExpected
+	actual, err := client.DeleteNameserver(1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
@@ -0,0 +1,92 @@
+// Package nat provides types and methods for working with the NAT tools
+// controller.
+package nat
+
+import (
+	"fmt"
+
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+// NAT represents a PHPIPAM NAT translation.
+type NAT struct {
+	// The NAT ID.
+	ID int `json:"id,string,omitempty"`
+
+	// The NAT's name.
+	Name string `json:"name,omitempty"`
+
+	// The type of NAT (ie: source, static, destination).
+	Type string `json:"type,omitempty"`
+
+	// The source objects of the translation. This is a JSON object containing
+	// lists of subnet and address IDs, keyed by object type.
+	Source map[string]interface{} `json:"src,omitempty"`
+
+	// The destination objects of the translation. See Source for the format.
+	Destination map[string]interface{} `json:"dst,omitempty"`
+
+	// The source port of the translation.
+	SourcePort string `json:"src_port,omitempty"`
+
+	// The destination port of the translation.
+	DestinationPort string `json:"dst_port,omitempty"`
+
+	// A detailed description of the NAT.
+	Description string `json:"description,omitempty"`
+
+	// Whether or not this is a policy NAT. Should be one of Yes or No.
+	Policy string `json:"policy,omitempty"`
+}
+
+// Controller is the base client for the NAT controller.
+type Controller struct {
+	client.Client
+}
+
+// NewController returns a new instance of the client for the NAT controller.
+func NewController(sess *session.Session) *Controller {
+	c := &Controller{
+		Client: *client.NewClient(sess),
+	}
+	return c
+}
+
+// ListNATs lists all NAT translations.
+func (c *Controller) ListNATs() (out []NAT, err error) {
+	err = c.SendRequest("GET", "/tools/nat/", &struct{}{}, &out)
+	return
+}
+
+// CreateNAT creates a NAT translation by sending a POST request.
+func (c *Controller) CreateNAT(in NAT) (message string, err error) {
+	err = c.SendRequest("POST", "/tools/nat/", &in, &message)
+	return
+}
+
+// GetNATByID GETs a NAT translation via its ID.
+func (c *Controller) GetNATByID(id int) (out NAT, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/tools/nat/%d/", id), &struct{}{}, &out)
+	return
+}
+
+// GetNATObjects GETs the subnet and address objects referenced by a NAT
+// translation. The shape of the returned data mirrors the Source and
+// Destination fields of NAT, with the referenced objects expanded.
+func (c *Controller) GetNATObjects(id int) (out map[string]interface{}, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/tools/nat/%d/objects/", id), &struct{}{}, &out)
+	return
+}
+
+// UpdateNAT updates a NAT translation by sending a PATCH request.
+func (c *Controller) UpdateNAT(in NAT) (message string, err error) {
+	err = c.SendRequest("PATCH", fmt.Sprintf("/tools/nat/%d/", in.ID), &in, &message)
+	return
+}
+
+// DeleteNAT deletes a NAT translation by its ID.
+func (c *Controller) DeleteNAT(id int) (message string, err error) {
+	err = c.SendRequest("DELETE", fmt.Sprintf("/tools/nat/%d/", id), &struct{}{}, &message)
+	return
+}
@@ -0,0 +1,288 @@
+package nat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+var testListNATsOutputExpected = []NAT{
+	NAT{
+		ID:   1,
+		Name: "web1",
+		Type: "static",
+		Source: map[string]interface{}{
+			"ipaddresses": []interface{}{"1"},
+		},
+		Destination: map[string]interface{}{
+			"ipaddresses": []interface{}{"5"},
+		},
+		SourcePort:      "443",
+		DestinationPort: "8443",
+		Description:     "Web server",
+		Policy:          "No",
+	},
+}
+
+const testListNATsOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "1",
+      "name": "web1",
+      "type": "static",
+      "src": {"ipaddresses": ["1"]},
+      "dst": {"ipaddresses": ["5"]},
+      "src_port": "443",
+      "dst_port": "8443",
+      "device": null,
+      "description": "Web server",
+      "policy": "No"
+    }
+  ]
+}
+`
+
+var testCreateNATInput = NAT{
+	Name: "web2",
+	Type: "static",
+}
+
+const testCreateNATOutputExpected = `NAT created`
+const testCreateNATOutputJSON = `
+{
+  "code": 201,
+  "success": true,
+  "data": "NAT created"
+}
+`
+
+var testGetNATByIDOutputExpected = NAT{
+	ID:   2,
+	Name: "web2",
+	Type: "static",
+	Source: map[string]interface{}{
+		"subnets": []interface{}{"3"},
+	},
+	Policy: "No",
+}
+
+const testGetNATByIDOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "2",
+    "name": "web2",
+    "type": "static",
+    "src": {"subnets": ["3"]},
+    "dst": null,
+    "src_port": null,
+    "dst_port": null,
+    "device": null,
+    "description": null,
+    "policy": "No"
+  }
+}
+`
+
+var testGetNATObjectsOutputExpected = map[string]interface{}{
+	"src": map[string]interface{}{
+		"subnets": []interface{}{
+			map[string]interface{}{
+				"id":     "3",
+				"subnet": "10.10.1.0",
+				"mask":   "24",
+			},
+		},
+	},
+}
+
+const testGetNATObjectsOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "src": {
+      "subnets": [
+        {
+          "id": "3",
+          "subnet": "10.10.1.0",
+          "mask": "24"
+        }
+      ]
+    }
+  }
+}
+`
+
+var testUpdateNATInput = NAT{
+	ID:          2,
+	Description: "Web server 2",
+}
+
+const testUpdateNATOutputExpected = `NAT updated`
+const testUpdateNATOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "NAT updated"
+}
+`
+
+const testDeleteNATOutputExpected = `NAT deleted`
+const testDeleteNATOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "NAT deleted"
+}
+`
+
+func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(f))
+	return ts
+}
+
+func httpOKTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
+func httpCreatedTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusCreated)
+	})
+}
+
+func fullSessionConfig() *session.Session {
+	return &session.Session{
+		Config: phpipam.Config{
+			AppID:    "0123456789abcdefgh",
+			Password: "changeit",
+			Username: "nobody",
+		},
+		Token: session.Token{
+			String: "foobarbazboop",
+		},
+	}
+}
+
+func TestListNATs(t *testing.T) {
+	ts := httpOKTestServer(testListNATsOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testListNATsOutputExpected
+	actual, err := client.ListNATs()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestCreateNAT(t *testing.T) {
+	ts := httpCreatedTestServer(testCreateNATOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testCreateNATInput
+	expected := testCreateNATOutputExpected
+	actual, err := client.CreateNAT(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetNATByID(t *testing.T) {
+	ts := httpOKTestServer(testGetNATByIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetNATByIDOutputExpected
+	actual, err := client.GetNATByID(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetNATObjects(t *testing.T) {
+	ts := httpOKTestServer(testGetNATObjectsOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetNATObjectsOutputExpected
+	actual, err := client.GetNATObjects(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestUpdateNAT(t *testing.T) {
+	ts := httpOKTestServer(testUpdateNATOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testUpdateNATInput
+	expected := testUpdateNATOutputExpected
+	actual, err := client.UpdateNAT(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestDeleteNAT(t *testing.T) {
+	ts := httpOKTestServer(testDeleteNATOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testDeleteNATOutputExpected
+	actual, err := client.DeleteNAT(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
@@ -0,0 +1,70 @@
+// Package providers provides types and methods for working with the circuit
+// providers tools controller.
+package providers
+
+import (
+	"fmt"
+
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+// Provider represents a PHPIPAM circuit provider.
+type Provider struct {
+	// The provider ID. This is what is referenced by the Provider field of
+	// circuits.
+	ID int `json:"id,string,omitempty"`
+
+	// The provider's name.
+	Name string `json:"name,omitempty"`
+
+	// A detailed description of the provider.
+	Description string `json:"description,omitempty"`
+
+	// Contact details for the provider.
+	Contact string `json:"contact,omitempty"`
+}
+
+// Controller is the base client for the providers controller.
+type Controller struct {
+	client.Client
+}
+
+// NewController returns a new instance of the client for the providers
+// controller.
+func NewController(sess *session.Session) *Controller {
+	c := &Controller{
+		Client: *client.NewClient(sess),
+	}
+	return c
+}
+
+// ListProviders lists all circuit providers.
+func (c *Controller) ListProviders() (out []Provider, err error) {
+	err = c.SendRequest("GET", "/tools/providers/", &struct{}{}, &out)
+	return
+}
+
+// CreateProvider creates a circuit provider by sending a POST request.
+func (c *Controller) CreateProvider(in Provider) (message string, err error) {
+	err = c.SendRequest("POST", "/tools/providers/", &in, &message)
+	return
+}
+
+// GetProviderByID GETs a circuit provider via its ID.
+func (c *Controller) GetProviderByID(id int) (out Provider, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/tools/providers/%d/", id), &struct{}{}, &out)
+	return
+}
+
+// UpdateProvider updates a circuit provider by sending a PATCH request.
+func (c *Controller) UpdateProvider(in Provider) (message string, err error) {
+	err = c.SendRequest("PATCH", fmt.Sprintf("/tools/providers/%d/", in.ID), &in, &message)
+	return
+}
+
+// DeleteProvider deletes a circuit provider by its ID.
+func (c *Controller) DeleteProvider(id int) (message string, err error) {
+	err = c.SendRequest("DELETE", fmt.Sprintf("/tools/providers/%d/", id), &struct{}{}, &message)
+	return
+}
@@ -0,0 +1,213 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+var testListProvidersOutputExpected = []Provider{
+	Provider{
+		ID:          1,
+		Name:        "Telco A",
+		Description: "Primary WAN carrier",
+		Contact:     "noc@telco-a.example",
+	},
+}
+
+const testListProvidersOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "1",
+      "name": "Telco A",
+      "description": "Primary WAN carrier",
+      "contact": "noc@telco-a.example"
+    }
+  ]
+}
+`
+
+var testCreateProviderInput = Provider{
+	Name: "Telco B",
+}
+
+const testCreateProviderOutputExpected = `Provider created`
+const testCreateProviderOutputJSON = `
+{
+  "code": 201,
+  "success": true,
+  "data": "Provider created"
+}
+`
+
+var testGetProviderByIDOutputExpected = Provider{
+	ID:   2,
+	Name: "Telco B",
+}
+
+const testGetProviderByIDOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "2",
+    "name": "Telco B",
+    "description": null,
+    "contact": null
+  }
+}
+`
+
+var testUpdateProviderInput = Provider{
+	ID:          2,
+	Description: "Backup WAN carrier",
+}
+
+const testUpdateProviderOutputExpected = `Provider updated`
+const testUpdateProviderOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Provider updated"
+}
+`
+
+const testDeleteProviderOutputExpected = `Provider deleted`
+const testDeleteProviderOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Provider deleted"
+}
+`
+
+func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(f))
+	return ts
+}
+
+func httpOKTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
+func httpCreatedTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusCreated)
+	})
+}
+
+func fullSessionConfig() *session.Session {
+	return &session.Session{
+		Config: phpipam.Config{
+			AppID:    "0123456789abcdefgh",
+			Password: "changeit",
+			Username: "nobody",
+		},
+		Token: session.Token{
+			String: "foobarbazboop",
+		},
+	}
+}
+
+func TestListProviders(t *testing.T) {
+	ts := httpOKTestServer(testListProvidersOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testListProvidersOutputExpected
+	actual, err := client.ListProviders()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestCreateProvider(t *testing.T) {
+	ts := httpCreatedTestServer(testCreateProviderOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testCreateProviderInput
+	expected := testCreateProviderOutputExpected
+	actual, err := client.CreateProvider(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetProviderByID(t *testing.T) {
+	ts := httpOKTestServer(testGetProviderByIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetProviderByIDOutputExpected
+	actual, err := client.GetProviderByID(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestUpdateProvider(t *testing.T) {
+	ts := httpOKTestServer(testUpdateProviderOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testUpdateProviderInput
+	expected := testUpdateProviderOutputExpected
+	actual, err := client.UpdateProvider(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestDeleteProvider(t *testing.T) {
+	ts := httpOKTestServer(testDeleteProviderOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testDeleteProviderOutputExpected
+	actual, err := client.DeleteProvider(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
@@ -0,0 +1,83 @@
+// Package racks provides types and methods for working with the racks tools
+// controller.
+package racks
+
+import (
+	"fmt"
+
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/devices"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+// Rack represents a PHPIPAM rack.
+type Rack struct {
+	// The rack ID. This is what is referenced by the Rack field of a device.
+	ID int `json:"id,string,omitempty"`
+
+	// The rack's name.
+	Name string `json:"name,omitempty"`
+
+	// The size of the rack, in rack units.
+	Size int `json:"size,string,omitempty"`
+
+	// The location index of the rack.
+	Location int `json:"location,string,omitempty"`
+
+	// A detailed description of the rack.
+	Description string `json:"description,omitempty"`
+
+	// true if devices can be mounted on the back side of the rack.
+	HasBack phpipam.BoolIntString `json:"hasBack,omitempty"`
+}
+
+// Controller is the base client for the racks controller.
+type Controller struct {
+	client.Client
+}
+
+// NewController returns a new instance of the client for the racks controller.
+func NewController(sess *session.Session) *Controller {
+	c := &Controller{
+		Client: *client.NewClient(sess),
+	}
+	return c
+}
+
+// ListRacks lists all racks.
+func (c *Controller) ListRacks() (out []Rack, err error) {
+	err = c.SendRequest("GET", "/tools/racks/", &struct{}{}, &out)
+	return
+}
+
+// CreateRack creates a rack by sending a POST request.
+func (c *Controller) CreateRack(in Rack) (message string, err error) {
+	err = c.SendRequest("POST", "/tools/racks/", &in, &message)
+	return
+}
+
+// GetRackByID GETs a rack via its ID.
+func (c *Controller) GetRackByID(id int) (out Rack, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/tools/racks/%d/", id), &struct{}{}, &out)
+	return
+}
+
+// GetRackDevices GETs the devices placed in a rack. The position of each
+// device in the rack is available in its RackStart and RackSize fields.
+func (c *Controller) GetRackDevices(id int) (out []devices.Device, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/tools/racks/%d/devices/", id), &struct{}{}, &out)
+	return
+}
+
+// UpdateRack updates a rack by sending a PATCH request.
+func (c *Controller) UpdateRack(in Rack) (message string, err error) {
+	err = c.SendRequest("PATCH", fmt.Sprintf("/tools/racks/%d/", in.ID), &in, &message)
+	return
+}
+
+// DeleteRack deletes a rack by its ID.
+func (c *Controller) DeleteRack(id int) (message string, err error) {
+	err = c.SendRequest("DELETE", fmt.Sprintf("/tools/racks/%d/", id), &struct{}{}, &message)
+	return
+}
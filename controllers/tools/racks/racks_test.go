@@ -0,0 +1,290 @@
+package racks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/devices"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+var testListRacksOutputExpected = []Rack{
+	Rack{
+		ID:          1,
+		Name:        "R1",
+		Size:        42,
+		Location:    1,
+		Description: "Row 1, rack 1",
+		HasBack:     true,
+	},
+}
+
+const testListRacksOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "1",
+      "name": "R1",
+      "size": "42",
+      "location": "1",
+      "row": "1",
+      "hasBack": "1",
+      "description": "Row 1, rack 1"
+    }
+  ]
+}
+`
+
+var testCreateRackInput = Rack{
+	Name:     "R2",
+	Size:     42,
+	Location: 1,
+}
+
+const testCreateRackOutputExpected = `Rack created`
+const testCreateRackOutputJSON = `
+{
+  "code": 201,
+  "success": true,
+  "data": "Rack created"
+}
+`
+
+var testGetRackByIDOutputExpected = Rack{
+	ID:       2,
+	Name:     "R2",
+	Size:     42,
+	Location: 1,
+}
+
+const testGetRackByIDOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "2",
+    "name": "R2",
+    "size": "42",
+    "location": "1",
+    "row": "1",
+    "hasBack": "0",
+    "description": null
+  }
+}
+`
+
+var testGetRackDevicesOutputExpected = []devices.Device{
+	devices.Device{
+		ID:        1,
+		Hostname:  "sw1.cust1.local",
+		IPAddress: "10.10.1.2",
+		Rack:      1,
+		RackStart: 40,
+		RackSize:  1,
+	},
+	devices.Device{
+		ID:        3,
+		Hostname:  "srv1.cust1.local",
+		IPAddress: "10.10.1.10",
+		Rack:      1,
+		RackStart: 20,
+		RackSize:  2,
+	},
+}
+
+const testGetRackDevicesOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "1",
+      "hostname": "sw1.cust1.local",
+      "ip": "10.10.1.2",
+      "type": "0",
+      "rack": "1",
+      "rack_start": "40",
+      "rack_size": "1"
+    },
+    {
+      "id": "3",
+      "hostname": "srv1.cust1.local",
+      "ip": "10.10.1.10",
+      "type": "0",
+      "rack": "1",
+      "rack_start": "20",
+      "rack_size": "2"
+    }
+  ]
+}
+`
+
+var testUpdateRackInput = Rack{
+	ID:      2,
+	HasBack: true,
+}
+
+const testUpdateRackOutputExpected = `Rack updated`
+const testUpdateRackOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Rack updated"
+}
+`
+
+const testDeleteRackOutputExpected = `Rack deleted`
+const testDeleteRackOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Rack deleted"
+}
+`
+
+func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(f))
+	return ts
+}
+
+func httpOKTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
+func httpCreatedTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusCreated)
+	})
+}
+
+func fullSessionConfig() *session.Session {
+	return &session.Session{
+		Config: phpipam.Config{
+			AppID:    "0123456789abcdefgh",
+			Password: "changeit",
+			Username: "nobody",
+		},
+		Token: session.Token{
+			String: "foobarbazboop",
+		},
+	}
+}
+
+func TestListRacks(t *testing.T) {
+	ts := httpOKTestServer(testListRacksOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testListRacksOutputExpected
+	actual, err := client.ListRacks()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestCreateRack(t *testing.T) {
+	ts := httpCreatedTestServer(testCreateRackOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testCreateRackInput
+	expected := testCreateRackOutputExpected
+	actual, err := client.CreateRack(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetRackByID(t *testing.T) {
+	ts := httpOKTestServer(testGetRackByIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetRackByIDOutputExpected
+	actual, err := client.GetRackByID(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetRackDevices(t *testing.T) {
+	ts := httpOKTestServer(testGetRackDevicesOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetRackDevicesOutputExpected
+	actual, err := client.GetRackDevices(1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestUpdateRack(t *testing.T) {
+	ts := httpOKTestServer(testUpdateRackOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testUpdateRackInput
+	expected := testUpdateRackOutputExpected
+	actual, err := client.UpdateRack(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestDeleteRack(t *testing.T) {
+	ts := httpOKTestServer(testDeleteRackOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testDeleteRackOutputExpected
+	actual, err := client.DeleteRack(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
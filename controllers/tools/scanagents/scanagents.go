@@ -0,0 +1,90 @@
+// Package scanagents provides types and methods for working with the scan
+// agents tools controller.
+package scanagents
+
+import (
+	"fmt"
+
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+// ScanAgent represents a PHPIPAM scan agent.
+type ScanAgent struct {
+	// The scan agent ID. This is what is referenced by the ScanAgent field of a
+	// subnet.
+	ID int `json:"id,string,omitempty"`
+
+	// The scan agent's name.
+	Name string `json:"name,omitempty"`
+
+	// The type of scan agent (ie: direct, mysql).
+	Type string `json:"type,omitempty"`
+
+	// The agent's access code. This is used by remote agents to authenticate
+	// against PHPIPAM. An empty code is not sent, so updating an agent leaves
+	// its code unchanged unless a new one is supplied. Use ClearScanAgentCode
+	// to clear it.
+	Code string `json:"code,omitempty"`
+
+	// A detailed description of the scan agent.
+	Description string `json:"description,omitempty"`
+
+	// The last time the agent checked in with PHPIPAM.
+	LastAccess string `json:"last_access,omitempty"`
+}
+
+// Controller is the base client for the scan agents controller.
+type Controller struct {
+	client.Client
+}
+
+// NewController returns a new instance of the client for the scan agents
+// controller.
+func NewController(sess *session.Session) *Controller {
+	c := &Controller{
+		Client: *client.NewClient(sess),
+	}
+	return c
+}
+
+// ListScanAgents lists all scan agents.
+func (c *Controller) ListScanAgents() (out []ScanAgent, err error) {
+	err = c.SendRequest("GET", "/tools/scanagents/", &struct{}{}, &out)
+	return
+}
+
+// CreateScanAgent creates a scan agent by sending a POST request.
+func (c *Controller) CreateScanAgent(in ScanAgent) (message string, err error) {
+	err = c.SendRequest("POST", "/tools/scanagents/", &in, &message)
+	return
+}
+
+// GetScanAgentByID GETs a scan agent via its ID.
+func (c *Controller) GetScanAgentByID(id int) (out ScanAgent, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/tools/scanagents/%d/", id), &struct{}{}, &out)
+	return
+}
+
+// UpdateScanAgent updates a scan agent by sending a PATCH request.
+func (c *Controller) UpdateScanAgent(in ScanAgent) (message string, err error) {
+	err = c.SendRequest("PATCH", fmt.Sprintf("/tools/scanagents/%d/", in.ID), &in, &message)
+	return
+}
+
+// ClearScanAgentCode clears the access code of the scan agent with the
+// supplied ID by sending a PATCH request.
+func (c *Controller) ClearScanAgentCode(id int) (message string, err error) {
+	in := struct {
+		ID   int    `json:"id,string"`
+		Code string `json:"code"`
+	}{ID: id}
+	err = c.SendRequest("PATCH", fmt.Sprintf("/tools/scanagents/%d/", id), &in, &message)
+	return
+}
+
+// DeleteScanAgent deletes a scan agent by its ID.
+func (c *Controller) DeleteScanAgent(id int) (message string, err error) {
+	err = c.SendRequest("DELETE", fmt.Sprintf("/tools/scanagents/%d/", id), &struct{}{}, &message)
+	return
+}
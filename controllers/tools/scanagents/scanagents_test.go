@@ -0,0 +1,270 @@
+package scanagents
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+var testListScanAgentsOutputExpected = []ScanAgent{
+	ScanAgent{
+		ID:          1,
+		Name:        "localhost",
+		Type:        "direct",
+		Description: "Scanning from local machine",
+		LastAccess:  "2017-03-03 00:56:34",
+	},
+}
+
+const testListScanAgentsOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "1",
+      "name": "localhost",
+      "description": "Scanning from local machine",
+      "type": "direct",
+      "code": null,
+      "last_access": "2017-03-03 00:56:34"
+    }
+  ]
+}
+`
+
+var testCreateScanAgentInput = ScanAgent{
+	Name: "remote1",
+	Type: "mysql",
+	Code: "abcdefgh0123456789",
+}
+
+const testCreateScanAgentOutputExpected = `Scanagent created`
+const testCreateScanAgentOutputJSON = `
+{
+  "code": 201,
+  "success": true,
+  "data": "Scanagent created"
+}
+`
+
+var testGetScanAgentByIDOutputExpected = ScanAgent{
+	ID:          2,
+	Name:        "remote1",
+	Type:        "mysql",
+	Code:        "abcdefgh0123456789",
+	Description: "Remote agent",
+}
+
+const testGetScanAgentByIDOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "2",
+    "name": "remote1",
+    "description": "Remote agent",
+    "type": "mysql",
+    "code": "abcdefgh0123456789",
+    "last_access": null
+  }
+}
+`
+
+var testUpdateScanAgentInput = ScanAgent{
+	ID:          2,
+	Description: "Remote agent",
+}
+
+const testUpdateScanAgentInputExpected = `{"id":"2","description":"Remote agent"}`
+
+const testClearScanAgentCodeInputExpected = `{"id":"2","code":""}`
+
+const testUpdateScanAgentOutputExpected = `Scanagent updated`
+const testUpdateScanAgentOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Scanagent updated"
+}
+`
+
+const testDeleteScanAgentOutputExpected = `Scanagent deleted`
+const testDeleteScanAgentOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "Scanagent deleted"
+}
+`
+
+func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(f))
+	return ts
+}
+
+func httpOKTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
+func httpCreatedTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusCreated)
+	})
+}
+
+func fullSessionConfig() *session.Session {
+	return &session.Session{
+		Config: phpipam.Config{
+			AppID:    "0123456789abcdefgh",
+			Password: "changeit",
+			Username: "nobody",
+		},
+		Token: session.Token{
+			String: "foobarbazboop",
+		},
+	}
+}
+
+func TestListScanAgents(t *testing.T) {
+	ts := httpOKTestServer(testListScanAgentsOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testListScanAgentsOutputExpected
+	actual, err := client.ListScanAgents()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestCreateScanAgent(t *testing.T) {
+	ts := httpCreatedTestServer(testCreateScanAgentOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testCreateScanAgentInput
+	expected := testCreateScanAgentOutputExpected
+	actual, err := client.CreateScanAgent(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetScanAgentByID(t *testing.T) {
+	ts := httpOKTestServer(testGetScanAgentByIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetScanAgentByIDOutputExpected
+	actual, err := client.GetScanAgentByID(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestUpdateScanAgent(t *testing.T) {
+	var body []byte
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testUpdateScanAgentOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	in := testUpdateScanAgentInput
+	expected := testUpdateScanAgentOutputExpected
+	actual, err := client.UpdateScanAgent(in)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	if string(body) != testUpdateScanAgentInputExpected {
+		t.Fatalf("Expected request body %s, got %s", testUpdateScanAgentInputExpected, body)
+	}
+}
+
+func TestClearScanAgentCode(t *testing.T) {
+	var path string
+	var body []byte
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		path = r.Method + " " + r.URL.Path
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testUpdateScanAgentOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testUpdateScanAgentOutputExpected
+	actual, err := client.ClearScanAgentCode(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	if expectedPath := "PATCH /0123456789abcdefgh/tools/scanagents/2/"; path != expectedPath {
+		t.Fatalf("Expected request %s, got %s", expectedPath, path)
+	}
+
+	if string(body) != testClearScanAgentCodeInputExpected {
+		t.Fatalf("Expected request body %s, got %s", testClearScanAgentCodeInputExpected, body)
+	}
+}
+
+func TestDeleteScanAgent(t *testing.T) {
+	ts := httpOKTestServer(testDeleteScanAgentOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testDeleteScanAgentOutputExpected
+	actual, err := client.DeleteScanAgent(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
@@ -0,0 +1,62 @@
+// Package tags provides types and methods for working with the address tags
+// tools controller.
+package tags
+
+import (
+	"fmt"
+
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/addresses"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+// Tag represents a PHPIPAM address tag. Tags are used to represent the state
+// of an IP address, and are referenced by the Tag field of an address.
+type Tag struct {
+	// The tag ID.
+	ID int `json:"id,string,omitempty"`
+
+	// The tag's name (ie: Offline, Used, Reserved, DHCP).
+	Type string `json:"type,omitempty"`
+
+	// true if the tag should be displayed in address listings.
+	ShowTag phpipam.BoolIntString `json:"showtag,omitempty"`
+
+	// The background color of the tag, in hex format (ie: #f59c99).
+	BGColor string `json:"bgcolor,omitempty"`
+
+	// The foreground color of the tag, in hex format (ie: #ffffff).
+	FGColor string `json:"fgcolor,omitempty"`
+}
+
+// Controller is the base client for the tags controller.
+type Controller struct {
+	client.Client
+}
+
+// NewController returns a new instance of the client for the tags controller.
+func NewController(sess *session.Session) *Controller {
+	c := &Controller{
+		Client: *client.NewClient(sess),
+	}
+	return c
+}
+
+// ListTags lists all address tags.
+func (c *Controller) ListTags() (out []Tag, err error) {
+	err = c.SendRequest("GET", "/tools/tags/", &struct{}{}, &out)
+	return
+}
+
+// GetTagByID GETs an address tag via its ID.
+func (c *Controller) GetTagByID(id int) (out Tag, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/tools/tags/%d/", id), &struct{}{}, &out)
+	return
+}
+
+// GetAddressesByTag GETs all addresses that have the supplied tag ID.
+func (c *Controller) GetAddressesByTag(id int) (out []addresses.Address, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/tools/tags/%d/addresses/", id), &struct{}{}, &out)
+	return
+}
@@ -0,0 +1,200 @@
+package tags
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/addresses"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+var testListTagsOutputExpected = []Tag{
+	Tag{
+		ID:      1,
+		Type:    "Offline",
+		ShowTag: true,
+		BGColor: "#f59c99",
+		FGColor: "#ffffff",
+	},
+	Tag{
+		ID:      2,
+		Type:    "Used",
+		BGColor: "#a9c9a4",
+		FGColor: "#ffffff",
+	},
+}
+
+const testListTagsOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "1",
+      "type": "Offline",
+      "showtag": "1",
+      "bgcolor": "#f59c99",
+      "fgcolor": "#ffffff",
+      "compress": "No",
+      "locked": "No",
+      "updateTag": "1"
+    },
+    {
+      "id": "2",
+      "type": "Used",
+      "showtag": "0",
+      "bgcolor": "#a9c9a4",
+      "fgcolor": "#ffffff",
+      "compress": "No",
+      "locked": "No",
+      "updateTag": "1"
+    }
+  ]
+}
+`
+
+var testGetTagByIDOutputExpected = Tag{
+	ID:      3,
+	Type:    "Reserved",
+	ShowTag: true,
+	BGColor: "#9ac0cd",
+	FGColor: "#ffffff",
+}
+
+const testGetTagByIDOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "3",
+    "type": "Reserved",
+    "showtag": "1",
+    "bgcolor": "#9ac0cd",
+    "fgcolor": "#ffffff",
+    "compress": "No",
+    "locked": "No",
+    "updateTag": "1"
+  }
+}
+`
+
+var testGetAddressesByTagOutputExpected = []addresses.Address{
+	addresses.Address{
+		ID:          11,
+		SubnetID:    3,
+		IPAddress:   "10.10.1.10",
+		Description: "Reserved for foo",
+		Tag:         3,
+	},
+}
+
+const testGetAddressesByTagOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "11",
+      "subnetId": "3",
+      "ip": "10.10.1.10",
+      "is_gateway": "0",
+      "description": "Reserved for foo",
+      "hostname": null,
+      "mac": null,
+      "owner": null,
+      "tag": "3",
+      "deviceId": null,
+      "port": null,
+      "note": null,
+      "lastSeen": null,
+      "excludePing": "0",
+      "PTRignore": "0",
+      "PTR": "0",
+      "firewallAddressObject": null,
+      "editDate": null
+    }
+  ]
+}
+`
+
+func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(f))
+	return ts
+}
+
+func httpOKTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
+func fullSessionConfig() *session.Session {
+	return &session.Session{
+		Config: phpipam.Config{
+			AppID:    "0123456789abcdefgh",
+			Password: "changeit",
+			Username: "nobody",
+		},
+		Token: session.Token{
+			String: "foobarbazboop",
+		},
+	}
+}
+
+func TestListTags(t *testing.T) {
+	ts := httpOKTestServer(testListTagsOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testListTagsOutputExpected
+	actual, err := client.ListTags()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetTagByID(t *testing.T) {
+	ts := httpOKTestServer(testGetTagByIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetTagByIDOutputExpected
+	actual, err := client.GetTagByID(3)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetAddressesByTag(t *testing.T) {
+	ts := httpOKTestServer(testGetAddressesByTagOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetAddressesByTagOutputExpected
+	actual, err := client.GetAddressesByTag(3)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
@@ -0,0 +1,53 @@
+// Package user provides methods for working with the user controller, which
+// manages the token of the authenticated session.
+package user
+
+import (
+	"time"
+
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+// Controller is the base client for the user controller.
+type Controller struct {
+	client.Client
+}
+
+// NewController returns a new instance of the client for the user controller.
+func NewController(sess *session.Session) *Controller {
+	c := &Controller{
+		Client: *client.NewClient(sess),
+	}
+	return c
+}
+
+// GetToken GETs the details of the session's token. This checks that the
+// token is still valid without changing anything on the server, making it
+// useful for health checks.
+func (c *Controller) GetToken() (out session.Token, err error) {
+	err = c.SendRequest("GET", "/user/", &struct{}{}, &out)
+	return
+}
+
+// GetTokenExpiration GETs the expiry time of the session's token.
+func (c *Controller) GetTokenExpiration() (out time.Time, err error) {
+	var token session.Token
+	if err = c.SendRequest("GET", "/user/token_expires/", &struct{}{}, &token); err != nil {
+		return
+	}
+	out, err = token.ExpiresAt()
+	return
+}
+
+// DeleteToken deletes the session's token on the server, logging the session
+// out, and returns the message returned by the API. The token is also removed
+// from the session, so that the next request made with it logs in again.
+//
+// This follows the same rules as Session.Logout - see client.Logout. In
+// particular, nothing is sent for sessions without a token or with a static
+// token, and an already expired token is not considered an error.
+func (c *Controller) DeleteToken() (message string, err error) {
+	message, err = client.Logout(c.Session)
+	return
+}
@@ -0,0 +1,190 @@
+package user
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+var testGetTokenOutputExpected = session.Token{
+	Expires: "2999-12-31 23:59:59",
+}
+
+const testGetTokenOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "expires": "2999-12-31 23:59:59"
+  }
+}
+`
+
+var testGetTokenExpirationOutputExpected = time.Date(2999, 12, 31, 23, 59, 59, 0, time.Local)
+
+const testGetTokenExpirationOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "expires": "2999-12-31 23:59:59"
+  }
+}
+`
+
+const testDeleteTokenOutputExpected = `User token removed`
+const testDeleteTokenOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": "User token removed"
+}
+`
+
+func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(f))
+	return ts
+}
+
+func httpOKTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
+func fullSessionConfig() *session.Session {
+	return &session.Session{
+		Config: phpipam.Config{
+			AppID:    "0123456789abcdefgh",
+			Password: "changeit",
+			Username: "nobody",
+		},
+		Token: session.Token{
+			String: "foobarbazboop",
+		},
+	}
+}
+
+func TestGetToken(t *testing.T) {
+	ts := httpOKTestServer(testGetTokenOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetTokenOutputExpected
+	actual, err := client.GetToken()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetTokenExpiration(t *testing.T) {
+	var path string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testGetTokenExpirationOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetTokenExpirationOutputExpected
+	actual, err := client.GetTokenExpiration()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !expected.Equal(actual) {
+		t.Fatalf("Expected %s, got %s", expected, actual)
+	}
+
+	expectedPath := "/0123456789abcdefgh/user/token_expires/"
+	if path != expectedPath {
+		t.Fatalf("Expected path %s, got %s", expectedPath, path)
+	}
+}
+
+func TestDeleteToken(t *testing.T) {
+	ts := httpOKTestServer(testDeleteTokenOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testDeleteTokenOutputExpected
+	actual, err := client.DeleteToken()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	if sess.Token.String != "" {
+		t.Fatalf("Expected session token to be cleared, got %q", sess.Token.String)
+	}
+}
+
+func TestDeleteTokenExpired(t *testing.T) {
+	var calls []string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, `{"code":403,"success":false,"message":"Token expired"}`, http.StatusForbidden)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	if _, err := client.DeleteToken(); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	// The expired token is already gone, so there should be no login.
+	if expected := []string{"DELETE /0123456789abcdefgh/user/"}; !reflect.DeepEqual(expected, calls) {
+		t.Fatalf("Expected calls %v, got %v", expected, calls)
+	}
+	if sess.Token.String != "" {
+		t.Fatalf("Expected session token to be cleared, got %q", sess.Token.String)
+	}
+}
+
+func TestDeleteTokenStatic(t *testing.T) {
+	var calls int
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testDeleteTokenOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	sess.StaticToken = true
+	client := NewController(sess)
+
+	if _, err := client.DeleteToken(); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if calls != 0 {
+		t.Fatalf("Expected no requests, got %d", calls)
+	}
+	if sess.Token.String != "foobarbazboop" {
+		t.Fatalf("Expected static token to be kept, got %q", sess.Token.String)
+	}
+}
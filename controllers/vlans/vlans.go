@@ -3,10 +3,13 @@
 package vlans
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/subnets"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/request"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
 )
 
@@ -31,12 +34,12 @@ type VLAN struct {
 	// The date of the last edit to this resource.
 	EditDate string `json:"editDate,omitempty"`
 
-	// A map[string]interface{} of custom fields to set on the resource. Note
+	// A map of custom fields to set on the resource. Note
 	// that this functionality requires PHPIPAM 1.3 or higher with the "Nest
 	// custom fields" flag set on the specific API integration. If this is not
 	// enabled, this map will be nil on GETs and POSTs and PATCHes with this
 	// field set will fail. Use the explicit custom field functions instead.
-	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+	CustomFields phpipam.CustomFields `json:"custom_fields,omitempty"`
 }
 
 // Controller is the base client for the VLAN controller.
@@ -58,6 +61,40 @@ func (c *Controller) CreateVLAN(in VLAN) (message string, err error) {
 	return
 }
 
+// CreateVLANWithID creates a VLAN by sending a POST request, returning the ID
+// of the new VLAN.
+//
+// The ID is taken from the create response if the API supplies it. Otherwise,
+// the VLAN is looked up by its number and L2 domain, and an error is returned
+// if this does not yield exactly one VLAN.
+func (c *Controller) CreateVLANWithID(in VLAN) (id int, err error) {
+	if id, _, err = c.SendCreateRequest("/vlans/", &in); err != nil || id != 0 {
+		return
+	}
+
+	var list []VLAN
+	if list, err = c.GetVLANsByNumber(in.Number); err != nil {
+		return
+	}
+	var matches []VLAN
+	for _, v := range list {
+		if v.DomainID == in.DomainID || in.DomainID == 0 {
+			matches = append(matches, v)
+		}
+	}
+	if len(matches) != 1 {
+		return 0, fmt.Errorf("Could not determine ID of created VLAN %d: found %d matching VLANs", in.Number, len(matches))
+	}
+	id = matches[0].ID
+	return
+}
+
+// ListVLANs lists all VLANs.
+func (c *Controller) ListVLANs() (out []VLAN, err error) {
+	err = c.SendRequest("GET", "/vlans/", &struct{}{}, &out)
+	return
+}
+
 // GetVLANByID GETs a VLAN via its ID in the PHPIPAM database.
 func (c *Controller) GetVLANByID(id int) (out VLAN, err error) {
 	err = c.SendRequest("GET", fmt.Sprintf("/vlans/%d/", id), &struct{}{}, &out)
@@ -75,6 +112,13 @@ func (c *Controller) GetVLANsByNumber(id int) (out []VLAN, err error) {
 	return
 }
 
+// GetSubnetsByVLAN GETs the subnets assigned to a VLAN via the VLAN's ID in
+// the PHPIPAM database.
+func (c *Controller) GetSubnetsByVLAN(id int) (out []subnets.Subnet, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/vlans/%d/subnets/", id), &struct{}{}, &out)
+	return
+}
+
 // GetVLANCustomFieldsSchema GETs the custom fields for the vlans controller via
 // client.GetCustomFieldsSchema.
 func (c *Controller) GetVLANCustomFieldsSchema() (out map[string]phpipam.CustomField, err error) {
@@ -95,6 +139,86 @@ func (c *Controller) UpdateVLAN(in VLAN) (message string, err error) {
 	return
 }
 
+// EnsureVLAN makes sure that a VLAN matching in exists, and returns it along
+// with whether or not it was created.
+//
+// The VLAN is looked up by its Number and DomainID via GetVLANsByNumber, with
+// a DomainID of zero matching VLANs in any L2 domain, and an error is returned
+// if more than one VLAN matches. If it does not exist, it is created.
+// Otherwise, any fields set in in that differ from the existing VLAN are
+// updated - see phpipam.ChangedFields for how differences are determined.
+// Fields with zero values are left as they are. If the API rejects the create
+// because another client created the VLAN in the meantime, the existing VLAN
+// is used instead. If the API does not supply the ID of a created VLAN, it is
+// looked up again.
+func (c *Controller) EnsureVLAN(in VLAN) (out VLAN, created bool, err error) {
+	var found bool
+	if out, found, err = c.findVLAN(in.Number, in.DomainID); err != nil {
+		return
+	}
+	if !found {
+		var id int
+		if id, _, err = c.SendCreateRequest("/vlans/", &in); err == nil {
+			if id != 0 {
+				out, err = c.GetVLANByID(id)
+			} else if out, found, err = c.findVLAN(in.Number, in.DomainID); err == nil && !found {
+				err = fmt.Errorf("Created VLAN %d not found", in.Number)
+			}
+			return out, true, err
+		}
+		var apiErr *request.APIError
+		if !errors.As(err, &apiErr) {
+			return
+		}
+		var lerr error
+		if out, found, lerr = c.findVLAN(in.Number, in.DomainID); lerr != nil || !found {
+			return
+		}
+		err = nil
+	}
+
+	var fields map[string]interface{}
+	if fields, err = phpipam.ChangedFields(in, out, "number", "domainId"); err != nil || len(fields) == 0 {
+		return
+	}
+	// Updating a VLAN requires its name, even if it has not changed.
+	if _, ok := fields["name"]; !ok {
+		fields["name"] = out.Name
+	}
+	if _, err = c.PatchFields("vlans", out.ID, VLAN{}, fields); err != nil {
+		return
+	}
+	out, err = c.GetVLANByID(out.ID)
+	return
+}
+
+// findVLAN looks up the VLAN with the supplied number in the supplied L2
+// domain, or in any L2 domain if domainID is zero. found is false if there is
+// no such VLAN, and an error is returned if there is more than one.
+func (c *Controller) findVLAN(number, domainID int) (out VLAN, found bool, err error) {
+	var list []VLAN
+	if list, err = c.GetVLANsByNumber(number); err != nil {
+		if request.IsNotFound(err) {
+			err = nil
+		}
+		return
+	}
+	var matches []VLAN
+	for _, v := range list {
+		if v.DomainID == domainID || domainID == 0 {
+			matches = append(matches, v)
+		}
+	}
+	switch len(matches) {
+	case 0:
+	case 1:
+		out, found = matches[0], true
+	default:
+		err = fmt.Errorf("Found %d VLANs numbered %d, expected 1", len(matches), number)
+	}
+	return
+}
+
 // UpdateVLANCustomFields PATCHes the vlan's custom fields.
 //
 // This function differs from the custom field functions available in the
@@ -103,22 +227,9 @@ func (c *Controller) UpdateVLAN(in VLAN) (message string, err error) {
 // updating a VLAN requires a name as well.
 func (c *Controller) UpdateVLANCustomFields(id int, name string, in map[string]interface{}) (message string, err error) {
 	// Verify that we are only updating fields that are custom fields.
-	var schema map[string]phpipam.CustomField
-	schema, err = c.GetVLANCustomFieldsSchema()
-	if err != nil {
+	if err = c.ValidateCustomFields("vlans", in); err != nil {
 		return
 	}
-	for k := range in {
-		for l := range schema {
-			if k == l {
-				goto customFieldFound
-			}
-		}
-		// not found
-		return "", fmt.Errorf("Custom field %s not found in schema for controller vlans", k)
-		// found
-	customFieldFound:
-	}
 
 	params := make(map[string]interface{})
 	for k, v := range in {
@@ -1,13 +1,16 @@
 package vlans
 
 import (
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/subnets"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
 	"github.com/pavel-z1/phpipam-sdk-go/testacc"
@@ -27,6 +30,16 @@ const testCreateVLANOutputJSON = `
 }
 `
 
+const testCreateVLANWithIDOutputJSON = `
+{
+  "code": 201,
+  "success": true,
+  "message": "Vlan created",
+  "id": "12",
+  "data": "Vlan created"
+}
+`
+
 var testGetVLANByIDOutputExpected = VLAN{
 	ID:       3,
 	DomainID: 1,
@@ -168,6 +181,36 @@ func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest
 	return ts
 }
 
+var testGetSubnetsByVLANOutputExpected = []subnets.Subnet{
+	subnets.Subnet{
+		ID:            3,
+		SubnetAddress: "10.10.1.0",
+		Mask:          24,
+		SectionID:     1,
+		VLANID:        2,
+	},
+}
+
+const testGetSubnetsByVLANOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "3",
+      "subnet": "10.10.1.0",
+      "mask": "24",
+      "sectionId": "1",
+      "description": null,
+      "vrfId": null,
+      "masterSubnetId": "0",
+      "vlanId": "2",
+      "isFolder": "0"
+    }
+  ]
+}
+`
+
 func httpOKTestServer(output string) *httptest.Server {
 	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Content-Type", "application/json")
@@ -214,6 +257,68 @@ func TestCreateVLAN(t *testing.T) {
 	}
 }
 
+func TestCreateVLANWithID(t *testing.T) {
+	ts := httpCreatedTestServer(testCreateVLANWithIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := 12
+	actual, err := client.CreateVLANWithID(testCreateVLANInput)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if expected != actual {
+		t.Fatalf("Expected %d, got %d", expected, actual)
+	}
+}
+
+func TestCreateVLANWithIDLookup(t *testing.T) {
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		switch r.Method {
+		case "POST":
+			http.Error(w, testCreateVLANOutputJSON, http.StatusCreated)
+		default:
+			http.Error(w, testGetVLANsByNumberOutputJSON, http.StatusOK)
+		}
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := 3
+	actual, err := client.CreateVLANWithID(testCreateVLANInput)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if expected != actual {
+		t.Fatalf("Expected %d, got %d", expected, actual)
+	}
+}
+
+func TestListVLANs(t *testing.T) {
+	ts := httpOKTestServer(testGetVLANsByNumberOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetVLANsByNumberOutputExpected
+	actual, err := client.ListVLANs()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
 func TestGetVLANByID(t *testing.T) {
 	ts := httpOKTestServer(testGetVLANByIDOutputJSON)
 	defer ts.Close()
@@ -250,6 +355,34 @@ func TestGetVLANsByNumber(t *testing.T) {
 	}
 }
 
+func TestGetSubnetsByVLAN(t *testing.T) {
+	var path string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testGetSubnetsByVLANOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSubnetsByVLANOutputExpected
+	actual, err := client.GetSubnetsByVLAN(2)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	expectedPath := "/0123456789abcdefgh/vlans/2/subnets/"
+	if path != expectedPath {
+		t.Fatalf("Expected path %s, got %s", expectedPath, path)
+	}
+}
+
 func TestGetVLANCustomFieldsSchema(t *testing.T) {
 	ts := httpOKTestServer(testGetVLANCustomFieldsSchemaJSON)
 	defer ts.Close()
@@ -287,6 +420,156 @@ func TestUpdateVLAN(t *testing.T) {
 	}
 }
 
+// httpEnsureVLANTestServer returns a server that responds to VLAN number
+// searches with the VLANs in existing, or a 404 if there are none, creates
+// VLANs with ID 9, and responds to lookups by ID with a VLAN with that ID. The
+// method and path of each request are recorded in calls, and the body of any
+// PATCH in body.
+func httpEnsureVLANTestServer(existing []string, calls *[]string, body *string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/0123456789abcdefgh")
+		*calls = append(*calls, r.Method+" "+path)
+		switch {
+		case r.Method == "POST":
+			http.Error(w, testCreateVLANWithIDOutputJSON, http.StatusCreated)
+		case r.Method == "PATCH":
+			b, _ := ioutil.ReadAll(r.Body)
+			*body = string(b)
+			http.Error(w, testUpdateVLANOutputJSON, http.StatusOK)
+		case strings.HasPrefix(path, "/vlans/search/") && len(existing) == 0:
+			http.Error(w, `{"code":404,"success":false,"message":"Vlans not found"}`, http.StatusNotFound)
+		case strings.HasPrefix(path, "/vlans/search/"):
+			http.Error(w, `{"code":200,"success":true,"data":[`+strings.Join(existing, ",")+`]}`, http.StatusOK)
+		default:
+			id := strings.Trim(strings.TrimPrefix(path, "/vlans/"), "/")
+			http.Error(w, `{"code":200,"success":true,"data":{"id":"`+id+`","domainId":"1","name":"web","number":"100"}}`, http.StatusOK)
+		}
+	})
+}
+
+func TestEnsureVLAN(t *testing.T) {
+	in := VLAN{
+		DomainID:    1,
+		Name:        "web",
+		Number:      100,
+		Description: "Web servers",
+	}
+
+	cases := []struct {
+		name            string
+		existing        []string
+		expectedCreated bool
+		expectedID      int
+		expectedCalls   []string
+		expectedBody    string
+	}{
+		{
+			name:            "create",
+			expectedCreated: true,
+			expectedID:      12,
+			expectedCalls:   []string{"GET /vlans/search/100/", "POST /vlans/", "GET /vlans/12/"},
+		},
+		{
+			name: "unchanged",
+			existing: []string{
+				`{"id":"6","domainId":"2","name":"web","number":"100"}`,
+				`{"id":"7","domainId":"1","name":"web","number":"100","description":"Web servers"}`,
+			},
+			expectedID:    7,
+			expectedCalls: []string{"GET /vlans/search/100/"},
+		},
+		{
+			name:          "drifted",
+			existing:      []string{`{"id":"7","domainId":"1","name":"web","number":"100","description":"old"}`},
+			expectedID:    7,
+			expectedCalls: []string{"GET /vlans/search/100/", "PATCH /vlans/", "GET /vlans/7/"},
+			expectedBody:  `{"description":"Web servers","id":7,"name":"web"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			var body string
+			ts := httpEnsureVLANTestServer(tc.existing, &calls, &body)
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewController(sess)
+
+			out, created, err := client.EnsureVLAN(in)
+			if err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+			if created != tc.expectedCreated {
+				t.Fatalf("Expected created to be %t, got %t", tc.expectedCreated, created)
+			}
+			if out.ID != tc.expectedID {
+				t.Fatalf("Expected ID %d, got %d", tc.expectedID, out.ID)
+			}
+			if !reflect.DeepEqual(tc.expectedCalls, calls) {
+				t.Fatalf("Expected calls %v, got %v", tc.expectedCalls, calls)
+			}
+			if body != tc.expectedBody {
+				t.Fatalf("Expected body %s, got %s", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestEnsureVLANCreatedWithoutID(t *testing.T) {
+	var calls []string
+	var created bool
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		calls = append(calls, r.Method+" "+strings.TrimPrefix(r.URL.Path, "/0123456789abcdefgh"))
+		switch {
+		case r.Method == "POST":
+			created = true
+			http.Error(w, `{"code":201,"success":true,"data":"Vlan created"}`, http.StatusCreated)
+		case created:
+			http.Error(w, `{"code":200,"success":true,"data":[{"id":"12","domainId":"1","name":"web","number":"100","description":"Web servers"}]}`, http.StatusOK)
+		default:
+			http.Error(w, `{"code":404,"success":false,"message":"Vlans not found"}`, http.StatusNotFound)
+		}
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	out, ok, err := client.EnsureVLAN(VLAN{DomainID: 1, Name: "web", Number: 100, Description: "Web servers"})
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if !ok || out.ID != 12 {
+		t.Fatalf("Expected created VLAN 12, got VLAN %d (created: %t)", out.ID, ok)
+	}
+	expected := []string{"GET /vlans/search/100/", "POST /vlans/", "GET /vlans/search/100/"}
+	if !reflect.DeepEqual(expected, calls) {
+		t.Fatalf("Expected calls %v, got %v", expected, calls)
+	}
+}
+
+func TestEnsureVLANAmbiguous(t *testing.T) {
+	var calls []string
+	var body string
+	ts := httpEnsureVLANTestServer([]string{
+		`{"id":"6","domainId":"1","name":"web","number":"100"}`,
+		`{"id":"7","domainId":"2","name":"web","number":"100"}`,
+	}, &calls, &body)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	_, _, err := client.EnsureVLAN(VLAN{Name: "web", Number: 100})
+	if err == nil || !strings.Contains(err.Error(), "Found 2 VLANs numbered 100") {
+		t.Fatalf("Expected ambiguous VLAN error, got %v", err)
+	}
+}
+
 func TestDeleteVLAN(t *testing.T) {
 	ts := httpOKTestServer(testDeleteVLANOutputJSON)
 	defer ts.Close()
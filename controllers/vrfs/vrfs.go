@@ -0,0 +1,112 @@
+// Package vrfs provides types and methods for working with the VRF
+// controller.
+package vrfs
+
+import (
+	"fmt"
+
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/subnets"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/client"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/request"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+// VRF represents a PHPIPAM VRF. Subnets belong to a VRF via their VRFID
+// field.
+type VRF struct {
+	// The VRF ID.
+	ID int `json:"id,string,omitempty"`
+
+	// The name of the VRF.
+	Name string `json:"name,omitempty"`
+
+	// The route distinguisher of the VRF (ie: 65000:100).
+	RD string `json:"rd,omitempty"`
+
+	// A detailed description of the VRF.
+	Description string `json:"description,omitempty"`
+
+	// A semicolon-separated list of the IDs of the sections the VRF is
+	// available in.
+	Sections string `json:"sections,omitempty"`
+
+	// The date of the last edit to this resource.
+	EditDate string `json:"editDate,omitempty"`
+}
+
+// Controller is the base client for the VRF controller.
+type Controller struct {
+	client.Client
+}
+
+// NewController returns a new instance of the client for the VRF controller.
+func NewController(sess *session.Session) *Controller {
+	c := &Controller{
+		Client: *client.NewClient(sess),
+	}
+	return c
+}
+
+// ListVRFs lists all VRFs.
+func (c *Controller) ListVRFs() (out []VRF, err error) {
+	err = c.SendRequest("GET", "/vrf/", &struct{}{}, &out)
+	return
+}
+
+// GetVRFByID GETs a VRF via its ID.
+func (c *Controller) GetVRFByID(id int) (out VRF, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/vrf/%d/", id), &struct{}{}, &out)
+	return
+}
+
+// GetSubnetsByVRF GETs the subnets assigned to a VRF via the VRF's ID.
+func (c *Controller) GetSubnetsByVRF(id int) (out []subnets.Subnet, err error) {
+	err = c.SendRequest("GET", fmt.Sprintf("/vrf/%d/subnets/", id), &struct{}{}, &out)
+	return
+}
+
+// GetSubnetsByVRFRecursive GETs the subnets assigned to a VRF via
+// GetSubnetsByVRF, along with all of their descendants via
+// subnets.GetSubnetSlavesRecursive, including descendants that are not
+// assigned to the VRF themselves. Each subnet is returned once, in the order
+// it was first found. An empty list is returned if the VRF has no subnets.
+func (c *Controller) GetSubnetsByVRFRecursive(id int) (out []subnets.Subnet, err error) {
+	var list []subnets.Subnet
+	if list, err = c.GetSubnetsByVRF(id); err != nil {
+		// The API returns a 404 error if the VRF has no subnets.
+		if !request.IsNotFound(err) {
+			return
+		}
+		err = nil
+	}
+
+	sc := subnets.NewController(c.Session)
+	seen := make(map[int]bool)
+	out = []subnets.Subnet{}
+	add := func(v subnets.Subnet) bool {
+		if seen[v.ID] {
+			return false
+		}
+		seen[v.ID] = true
+		out = append(out, v)
+		return true
+	}
+	for _, v := range list {
+		// Subnets already found as the descendants of an earlier subnet have
+		// had their own descendants found as well.
+		if !add(v) {
+			continue
+		}
+		var children []subnets.Subnet
+		if children, err = sc.GetSubnetSlavesRecursive(v.ID); err != nil {
+			if !request.IsNotFound(err) {
+				return nil, err
+			}
+			err = nil
+		}
+		for _, child := range children {
+			add(child)
+		}
+	}
+	return
+}
@@ -0,0 +1,259 @@
+package vrfs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pavel-z1/phpipam-sdk-go/controllers/subnets"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
+)
+
+var testListVRFsOutputExpected = []VRF{
+	VRF{
+		ID:          1,
+		Name:        "red",
+		RD:          "65000:100",
+		Description: "Red tenant",
+	},
+	VRF{
+		ID:       2,
+		Name:     "blue",
+		RD:       "65000:200",
+		Sections: "1;2",
+	},
+}
+
+const testListVRFsOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "1",
+      "name": "red",
+      "rd": "65000:100",
+      "description": "Red tenant",
+      "sections": null,
+      "editDate": null
+    },
+    {
+      "id": "2",
+      "name": "blue",
+      "rd": "65000:200",
+      "description": null,
+      "sections": "1;2",
+      "editDate": null
+    }
+  ]
+}
+`
+
+var testGetVRFByIDOutputExpected = VRF{
+	ID:          1,
+	Name:        "red",
+	RD:          "65000:100",
+	Description: "Red tenant",
+}
+
+const testGetVRFByIDOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": {
+    "id": "1",
+    "name": "red",
+    "rd": "65000:100",
+    "description": "Red tenant",
+    "sections": null,
+    "editDate": null
+  }
+}
+`
+
+var testGetSubnetsByVRFOutputExpected = []subnets.Subnet{
+	subnets.Subnet{
+		ID:            3,
+		SubnetAddress: "10.10.0.0",
+		Mask:          16,
+		VRFID:         1,
+	},
+	subnets.Subnet{
+		ID:             4,
+		SubnetAddress:  "10.10.1.0",
+		Mask:           24,
+		VRFID:          1,
+		MasterSubnetID: 3,
+	},
+}
+
+const testGetSubnetsByVRFOutputJSON = `
+{
+  "code": 200,
+  "success": true,
+  "data": [
+    {
+      "id": "3",
+      "subnet": "10.10.0.0",
+      "mask": "16",
+      "vrfId": "1",
+      "masterSubnetId": "0"
+    },
+    {
+      "id": "4",
+      "subnet": "10.10.1.0",
+      "mask": "24",
+      "vrfId": "1",
+      "masterSubnetId": "3"
+    }
+  ]
+}
+`
+
+func newHTTPTestServer(f func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(f))
+	return ts
+}
+
+func httpOKTestServer(output string) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, output, http.StatusOK)
+	})
+}
+
+func fullSessionConfig() *session.Session {
+	return &session.Session{
+		Config: phpipam.Config{
+			AppID:    "0123456789abcdefgh",
+			Password: "changeit",
+			Username: "nobody",
+		},
+		Token: session.Token{
+			String: "foobarbazboop",
+		},
+	}
+}
+
+func TestListVRFs(t *testing.T) {
+	ts := httpOKTestServer(testListVRFsOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testListVRFsOutputExpected
+	actual, err := client.ListVRFs()
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetVRFByID(t *testing.T) {
+	ts := httpOKTestServer(testGetVRFByIDOutputJSON)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetVRFByIDOutputExpected
+	actual, err := client.GetVRFByID(1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetSubnetsByVRF(t *testing.T) {
+	var path string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testGetSubnetsByVRFOutputJSON, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	expected := testGetSubnetsByVRFOutputExpected
+	actual, err := client.GetSubnetsByVRF(1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+	if expectedPath := "/0123456789abcdefgh/vrf/1/subnets/"; path != expectedPath {
+		t.Fatalf("Expected path %s, got %s", expectedPath, path)
+	}
+}
+
+func TestGetSubnetsByVRFRecursive(t *testing.T) {
+	var calls []string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/0123456789abcdefgh")
+		calls = append(calls, path)
+		switch path {
+		case "/vrf/1/subnets/":
+			http.Error(w, testGetSubnetsByVRFOutputJSON, http.StatusOK)
+		case "/subnets/3/slaves_recursive/":
+			http.Error(w, `{"code":200,"success":true,"data":[`+
+				`{"id":"4","subnet":"10.10.1.0","mask":"24","vrfId":"1","masterSubnetId":"3"},`+
+				`{"id":"5","subnet":"10.10.1.0","mask":"25","masterSubnetId":"4"}]}`, http.StatusOK)
+		default:
+			http.Error(w, `{"code":404,"success":false,"message":"No slaves"}`, http.StatusNotFound)
+		}
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	actual, err := client.GetSubnetsByVRFRecursive(1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	var ids []int
+	for _, v := range actual {
+		ids = append(ids, v.ID)
+	}
+	if expected := []int{3, 4, 5}; !reflect.DeepEqual(expected, ids) {
+		t.Fatalf("Expected subnets %v, got %v", expected, ids)
+	}
+	if expected := []string{"/vrf/1/subnets/", "/subnets/3/slaves_recursive/"}; !reflect.DeepEqual(expected, calls) {
+		t.Fatalf("Expected calls %v, got %v", expected, calls)
+	}
+}
+
+func TestGetSubnetsByVRFRecursiveNoSubnets(t *testing.T) {
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, `{"code":404,"success":false,"message":"No subnets found"}`, http.StatusNotFound)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewController(sess)
+
+	actual, err := client.GetSubnetsByVRFRecursive(1)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if actual == nil || len(actual) != 0 {
+		t.Fatalf("Expected empty list, got %#v", actual)
+	}
+}
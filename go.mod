@@ -1,5 +1,8 @@
 module github.com/pavel-z1/phpipam-sdk-go
 
-require github.com/imdario/mergo v0.0.0-20160517064435-50d4dbd4eb0e
+require (
+	github.com/davecgh/go-spew v1.1.1
+	github.com/imdario/mergo v0.0.0-20160517064435-50d4dbd4eb0e
+)
 
 go 1.13
@@ -3,8 +3,17 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam/request"
@@ -26,64 +35,455 @@ func NewClient(s *session.Session) *Client {
 	return c
 }
 
+func init() {
+	session.LoginFunc = func(s *session.Session) error {
+		return loginSession(context.Background(), s)
+	}
+	session.LogoutFunc = func(s *session.Session) error {
+		_, err := logoutSession(context.Background(), s)
+		return err
+	}
+}
+
 // loginSession logs in a session via the user controller. This is the only
 // valid operation if the session does not have a token yet.
-func loginSession(s *session.Session) error {
+func loginSession(ctx context.Context, s *session.Session) error {
 	var out session.Token
 	r := request.NewRequest(s)
 	r.Method = "POST"
 	r.URI = "/user/"
 	r.Input = &struct{}{}
 	r.Output = &out
-	if err := r.Send(); err != nil {
+	if err := r.SendContext(ctx); err != nil {
 		return err
 	}
-	s.Token = out
+	s.SetToken(out)
 	return nil
 }
 
+// Logout deletes a session's token on the server via the user controller, and
+// removes it from the session so that the next request made with it logs in
+// again. The message returned by the API is returned.
+//
+// Nothing is sent if the session has no token, so it is safe to call more than
+// once. Static tokens aren't session tokens, so nothing is sent for sessions
+// using them either. The request is sent directly, so that a session whose
+// token has already expired isn't logged in again just to log out, and a
+// token that the server rejects as invalid or expired is already gone, so
+// this is not considered an error.
+//
+// Session.Logout and the user controller's DeleteToken both log out via this
+// function.
+func Logout(s *session.Session) (message string, err error) {
+	return logoutSession(context.Background(), s)
+}
+
+// logoutSession implements Logout, binding the request to ctx.
+func logoutSession(ctx context.Context, s *session.Session) (message string, err error) {
+	if s.StaticToken {
+		return
+	}
+	token := s.CurrentToken().String
+	if token == "" {
+		return
+	}
+	r := request.NewRequest(s)
+	r.Method = "DELETE"
+	r.URI = "/user/"
+	r.Input = &struct{}{}
+	r.Output = &message
+	if err = r.SendContext(ctx); err != nil {
+		if !isTokenError(err) {
+			return
+		}
+		err = nil
+	}
+	// Going through RefreshToken only clears the token if it is still the
+	// one that was deleted, and not one from a login made in the meantime.
+	err = s.RefreshToken(token, func() error {
+		s.SetToken(session.Token{})
+		return nil
+	})
+	return
+}
+
 // SendRequest sends a request to a request.Request object.  It's expected that
 // references to specific data types are passed - no checking is done to make
 // sure that references are passed.
 //
 // This function also wraps session management into the workflow, logging in
-// and refreshing session tokens as needed.
+// and refreshing session tokens as needed. Errors returned by the API are
+// *request.APIError values, including those from a failed login, so they can
+// be checked with helpers such as request.IsUnauthorized.
 func (c *Client) SendRequest(method, uri string, in, out interface{}) error {
-	// Check to make sure our session is ok first.
-	if c.Session.Token.String == "" {
-		if err := loginSession(c.Session); err != nil {
-			return fmt.Errorf("Error logging into PHPIPAM: %s", err)
+	return c.SendRequestContext(context.Background(), method, uri, in, out)
+}
+
+// SendRequestContext works like SendRequest, but binds the request (and any
+// login requests performed as part of it) to the supplied context.
+//
+// If retries are enabled in the session's RetryConfig, requests that fail
+// with a retryable error are retried with an exponential backoff, or after the
+// delay supplied in the response's Retry-After header. Retries stop if the
+// context is cancelled while waiting. Each attempt is also subject to the
+// session's RateLimit.
+func (c *Client) SendRequestContext(ctx context.Context, method, uri string, in, out interface{}) error {
+	return c.send(ctx, c.newRequest(method, uri, in, out))
+}
+
+// Ping checks connectivity to the API by GETting the token details from the
+// user controller, logging in first if necessary. This confirms that the
+// endpoint is reachable and the credentials are valid. If the endpoint cannot
+// be reached, the returned error is a *request.ProtocolError.
+//
+// Note that the API does not expose the PHPIPAM version, so this cannot be
+// used to determine it.
+func (c *Client) Ping() error {
+	return c.PingContext(context.Background())
+}
+
+// PingContext works like Ping, but binds the request to the supplied context.
+func (c *Client) PingContext(ctx context.Context) error {
+	return c.SendRequestContext(ctx, "GET", "/user/", &struct{}{}, &struct{}{})
+}
+
+// DetectPHPVersion probes the server for the PHP version it runs on, and sets
+// the session's PHPVersion and NumericMode to match. The detected version is
+// returned, and is empty if only the numeric mode could be determined.
+//
+// The sections are listed, and the version is taken from the X-Powered-By
+// header of the response (ie: PHP/8.1.2). If the server does not send the
+// header, the numeric mode is inferred from the form of the section IDs
+// instead. An error is returned, leaving the session unchanged, if neither is
+// possible. As this changes the session's configuration, it should be called
+// before the session is used for other requests.
+func (c *Client) DetectPHPVersion() (version string, err error) {
+	var sections []map[string]interface{}
+	var resp *http.Response
+	if resp, err = c.SendRequestWithResponse("GET", "/sections/", &struct{}{}, &sections); err != nil {
+		return
+	}
+
+	if p := resp.Header.Get("X-Powered-By"); strings.HasPrefix(p, "PHP/") {
+		version = strings.TrimPrefix(p, "PHP/")
+		if mode := phpipam.NumericModeForPHPVersion(version); mode != phpipam.NumericAuto {
+			c.Session.PHPVersion = version
+			c.Session.NumericMode = mode
+			return
 		}
+		version = ""
+	}
+
+	if len(sections) > 0 {
+		switch sections[0]["id"].(type) {
+		case string:
+			c.Session.NumericMode = phpipam.NumericStrings
+			return
+		case float64:
+			c.Session.NumericMode = phpipam.NumericNumbers
+			return
+		}
+	}
+	err = errors.New("Could not detect the PHP version of the PHPIPAM server")
+	return
+}
+
+// SendRequestStream works like SendRequest, but the response data is decoded
+// into out as the response body is read, rather than after reading the whole
+// body into memory. This is intended for large list requests. See
+// request.Request.Stream for the differences in how streamed responses are
+// handled.
+func (c *Client) SendRequestStream(method, uri string, in, out interface{}) error {
+	r := c.newRequest(method, uri, in, out)
+	r.Stream = true
+	return c.send(context.Background(), r)
+}
+
+// SendRequestWithParams works like SendRequest, but appends the supplied
+// query parameters to uri. This can be used to pass any of the query options
+// supported by the API (ie: filter_by, order_by, or links), including ones
+// that the SDK has no dedicated method for. If uri already has a query
+// string, the parameters are added to it. Nothing is appended if params is
+// empty.
+func (c *Client) SendRequestWithParams(method, uri string, params url.Values, in, out interface{}) error {
+	return c.SendRequest(method, withParams(uri, params), in, out)
+}
+
+// withParams returns uri with params appended to its query string.
+func withParams(uri string, params url.Values) string {
+	if len(params) == 0 {
+		return uri
+	}
+	sep := "?"
+	if strings.Contains(uri, "?") {
+		sep = "&"
 	}
+	return uri + sep + params.Encode()
+}
 
+// hasParam returns true if the query string of uri sets the parameter key.
+func hasParam(uri, key string) bool {
+	i := strings.Index(uri, "?")
+	if i < 0 {
+		return false
+	}
+	q, _ := url.ParseQuery(uri[i+1:])
+	_, ok := q[key]
+	return ok
+}
+
+// SendCreateRequest POSTs in to uri to create a resource, returning the ID of
+// the created resource along with the message in the response data. See
+// request.Request.CreatedID for details on how the ID is determined - if the
+// API does not supply it, id is zero.
+func (c *Client) SendCreateRequest(uri string, in interface{}) (id int, message string, err error) {
+	r := c.newRequest("POST", uri, in, &message)
+	if err = c.send(context.Background(), r); err != nil {
+		return
+	}
+	id = r.CreatedID
+	return
+}
+
+// SendRequestRaw works like SendRequest, but returns the raw data field of
+// the response instead of unmarshaling it. This can be used to decode fields
+// or endpoints that are not yet supported by the SDK.
+func (c *Client) SendRequestRaw(method, uri string, in interface{}) (out json.RawMessage, err error) {
+	err = c.SendRequest(method, uri, in, &out)
+	return
+}
+
+// SendRequestWithResponse works like SendRequest, but also returns the HTTP
+// response, so that headers such as Location can be read. The response's body
+// has already been consumed to decode out, and is replaced with a reader over
+// a copy of it. The response is returned along with any error if one was
+// received, and is nil otherwise. If the request was retried, it is the
+// response to the last attempt.
+func (c *Client) SendRequestWithResponse(method, uri string, in, out interface{}) (resp *http.Response, err error) {
+	r := c.newRequest(method, uri, in, out)
+	err = c.send(context.Background(), r)
+	resp = r.Response
+	return
+}
+
+// newRequest creates a new request for the client's session. If the session
+// has DisableLinks set, links=false is added to the query of GET requests that
+// do not set the links parameter themselves.
+func (c *Client) newRequest(method, uri string, in, out interface{}) *request.Request {
+	if c.Session.DisableLinks && method == "GET" && !hasParam(uri, "links") {
+		uri = withParams(uri, url.Values{"links": {"false"}})
+	}
 	r := request.NewRequest(c.Session)
 	r.Method = method
 	r.URI = uri
 	r.Input = in
 	r.Output = out
-	err := r.Send()
+	return r
+}
+
+// send sends r, retrying it as per the session's RetryConfig, and reports the
+// outcome to the session's Observer. If the session is in dry run mode and r
+// is a write request, a *request.DryRunError is returned instead, and the
+// request is not observed.
+func (c *Client) send(ctx context.Context, r *request.Request) error {
+	if c.Session.DryRun && r.Method != "GET" && r.Method != "OPTIONS" {
+		bs, err := phpipam.MarshalNumeric(r.Input, c.Session.NumericMode)
+		if err != nil {
+			return fmt.Errorf("Error preparing request data: %s", err)
+		}
+		return &request.DryRunError{Method: r.Method, URI: r.URI, Body: bs}
+	}
+
+	if c.Session.Observer == nil {
+		return c.sendWithRetry(ctx, r)
+	}
+	start := time.Now()
+	err := c.sendWithRetry(ctx, r)
+	var status int
+	if r.Response != nil {
+		status = r.Response.StatusCode
+	}
+	// The query string is dropped so that observers that label metrics by path
+	// do not get one label per filter or parameter value.
+	path := r.URI
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	c.Session.Observer.ObserveRequest(r.Method, path, status, time.Since(start), err)
+	return err
+}
+
+// sendWithRetry sends r for send, retrying it as per the session's
+// RetryConfig.
+func (c *Client) sendWithRetry(ctx context.Context, r *request.Request) error {
+	cfg := c.Session.Retry
+	delay := cfg.BaseDelay
+	for attempt := 1; ; attempt++ {
+		if err := c.Session.WaitRateLimit(ctx); err != nil {
+			return err
+		}
+		err := c.sendOnce(ctx, r)
+		if err == nil || attempt >= cfg.MaxAttempts || !isRetryable(cfg, r.Method, err) {
+			return err
+		}
+		wait := delay
+		if d, ok := retryAfter(err); ok {
+			wait = d
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+// sendOnce performs a single attempt of a request for send.
+func (c *Client) sendOnce(ctx context.Context, r *request.Request) error {
+	// Check to make sure our session is ok first. Concurrent requests on a
+	// session without a token only log in once.
+	if c.Session.CurrentToken().String == "" && !c.Session.StaticToken {
+		login := func() error { return loginSession(ctx, c.Session) }
+		if err := c.Session.RefreshToken("", login); err != nil {
+			return &loginError{err: err}
+		}
+	}
+
+	token := c.Session.CurrentToken().String
+	err := r.SendContext(ctx)
 	switch {
 	case err == nil:
 		return nil
-	case err.Error() == "Error from API (403): Token expired":
-		if err := loginSession(c.Session); err != nil {
-			return fmt.Errorf("Error refreshing expired PHPIPAM session token: %s", err)
+	case c.Session.StaticToken:
+		// Static tokens can't be refreshed.
+		return err
+	case err.Error() == "Error from API (403): Token expired", c.Session.AutoRefresh && isTokenError(err):
+		login := func() error { return loginSession(ctx, c.Session) }
+		if err := c.Session.RefreshToken(token, login); err != nil {
+			return fmt.Errorf("Error refreshing expired PHPIPAM session token: %w", err)
 		}
-		return r.Send()
+		return r.SendContext(ctx)
 	}
 	return err
 }
 
+// loginError is returned by sendOnce when the login made before sending a
+// request fails. The request itself has not been sent, so it can be retried
+// regardless of its method.
+type loginError struct {
+	err error
+}
+
+func (e *loginError) Error() string {
+	return fmt.Sprintf("Error logging into PHPIPAM: %s", e.err)
+}
+
+func (e *loginError) Unwrap() error {
+	return e.err
+}
+
+// isTokenError returns true if the supplied error is an API error indicating
+// that the session token is invalid or has expired.
+func isTokenError(err error) bool {
+	var e *request.APIError
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.Code == http.StatusUnauthorized || e.Message == "Token expired" || e.Message == "Invalid token"
+}
+
+// isIdempotent returns true if requests with the supplied method can safely
+// be sent more than once.
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+// isRetryable returns true if a request with the supplied method that failed
+// with err can be retried under the supplied retry configuration. Failed
+// logins are retried for any method, as the request itself was not sent.
+func isRetryable(cfg session.RetryConfig, method string, err error) bool {
+	var le *loginError
+	if !errors.As(err, &le) && !isIdempotent(method) && !cfg.RetryUnsafe {
+		return false
+	}
+
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		return cfg.RetryProtocolErrors && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	var e *request.APIError
+	if !errors.As(err, &e) {
+		return false
+	}
+	codes := cfg.StatusCodes
+	if len(codes) == 0 {
+		codes = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	}
+	for _, code := range codes {
+		if e.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfter returns the delay supplied in the Retry-After header of the
+// response that caused err, if any. Both the delay-seconds and HTTP-date forms
+// of the header are supported.
+func retryAfter(err error) (time.Duration, bool) {
+	var e *request.APIError
+	if !errors.As(err, &e) || e.Header == nil {
+		return 0, false
+	}
+	v := e.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
 // GetCustomFieldsSchema GETs the custom fields for the supplied controller
 // name and returns them as a map[string]phpipam.CustomField.
 //
 // This function is called out to in a controller to implement this
 // functionality in a specific pacakge.
+//
+// If the session's SchemaCacheTTL is set, the schema is served from the
+// session's cache when possible.
 func (c *Client) GetCustomFieldsSchema(controller string) (out map[string]phpipam.CustomField, err error) {
-	err = c.SendRequest("GET", fmt.Sprintf("/%s/custom_fields/", controller), &struct{}{}, &out)
+	if schema, ok := c.Session.CachedSchema(controller); ok {
+		return schema, nil
+	}
+	if err = c.SendRequest("GET", fmt.Sprintf("/%s/custom_fields/", controller), &struct{}{}, &out); err != nil {
+		return
+	}
+	c.Session.CacheSchema(controller, out)
 	return
 }
 
+// ClearSchemaCache removes all custom field schemas cached by the client's
+// session. This should be called after custom field definitions have been
+// changed, if SchemaCacheTTL is set.
+func (c *Client) ClearSchemaCache() {
+	c.Session.ClearSchemaCache()
+}
+
 // GetCustomFields GETs the custom fields for a resource, and returns them
 // as a map[string]interface{}. A call out to GetCustomFields is performed
 // first, and then a GET is performed on the subnet resource with only the
@@ -148,7 +548,7 @@ func (c *Client) UpdateCustomFields(id int, in map[string]interface{}, controlle
 	schema, err = c.GetCustomFieldsSchema(controller)
 	switch {
 	// Ignore this error if the caller is not setting any fields.
-	case len(in) == 0 && err.Error() == "Error from API (200): No custom fields defined":
+	case len(in) == 0 && err != nil && err.Error() == "Error from API (200): No custom fields defined":
 		err = nil
 		return
 	case err != nil:
@@ -158,19 +558,83 @@ func (c *Client) UpdateCustomFields(id int, in map[string]interface{}, controlle
 	return
 }
 
+// ValidateCustomFields checks that all of the keys in in are custom fields
+// defined in the schema for the supplied controller, as returned by
+// GetCustomFieldsSchema. An error is returned for the first key that is not.
+// The schema is not fetched if in is empty.
+func (c *Client) ValidateCustomFields(controller string, in map[string]interface{}) error {
+	if len(in) == 0 {
+		return nil
+	}
+	schema, err := c.GetCustomFieldsSchema(controller)
+	if err != nil {
+		return err
+	}
+	return checkCustomFields(in, controller, schema)
+}
+
+// checkCustomFields checks the keys in in against schema for
+// ValidateCustomFields and updateCustomFieldsRequest.
+func checkCustomFields(in map[string]interface{}, controller string, schema map[string]phpipam.CustomField) error {
+	for k := range in {
+		if _, ok := schema[k]; !ok {
+			return fmt.Errorf("Custom field %s not found in schema for controller %s", k, controller)
+		}
+	}
+	return nil
+}
+
+// PatchFields PATCHes only the supplied fields of the resource with the
+// supplied ID on a controller, leaving all other fields unchanged.
+//
+// The keys of fields are the JSON names of the resource's fields (ie: vlanId),
+// and are validated against the JSON tags of resource, which should be a value
+// of the controller's resource type (ie: subnets.Subnet{}). The ID can't be
+// supplied in fields. Values are sent as supplied, and a nil value is sent as
+// null, which clears the field.
+//
+// This function is called out to in a controller to implement this
+// functionality in a specific package.
+func (c *Client) PatchFields(controller string, id int, resource interface{}, fields map[string]interface{}) (message string, err error) {
+	known := jsonFieldNames(reflect.TypeOf(resource))
+	for k := range fields {
+		if !known[k] || k == "id" {
+			return "", fmt.Errorf("Field %s is not a known field for controller %s", k, controller)
+		}
+	}
+
+	params := make(map[string]interface{})
+	for k, v := range fields {
+		params[k] = v
+	}
+	params["id"] = id
+	err = c.SendRequest("PATCH", fmt.Sprintf("/%s/", controller), &params, &message)
+	return
+}
+
+// jsonFieldNames returns the JSON names of the fields of the struct type t,
+// as per their json tags.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	out := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		switch {
+		case name == "-" || f.PkgPath != "":
+			continue
+		case name == "":
+			name = f.Name
+		}
+		out[name] = true
+	}
+	return out
+}
+
 // updateCustomFieldsRequest performs the actual validation and request work
 // for UpdateCustomFields. This is separated off to make testing easier.
 func (c *Client) updateCustomFieldsRequest(id int, in map[string]interface{}, controller string, schema map[string]phpipam.CustomField) (message string, err error) {
-	for k := range in {
-		for l := range schema {
-			if k == l {
-				goto customFieldFound
-			}
-		}
-		// not found
-		return "", fmt.Errorf("Custom field %s not found in schema for controller %s", k, controller)
-		// found
-	customFieldFound:
+	if err = checkCustomFields(in, controller, schema); err != nil {
+		return
 	}
 
 	params := make(map[string]interface{})
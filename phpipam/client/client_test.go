@@ -1,14 +1,23 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam/request"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
 )
 
@@ -200,6 +209,14 @@ const testUpdateCustomFieldsRequestResponseText = `
 
 const testUpdateCustomFieldsRequestExpected = "subnet updated"
 
+const tokenInvalidResponseText = `
+{
+  "code": 401,
+  "success": false,
+  "message": "Invalid token"
+}
+`
+
 const authErrorExpectedResponse = "Error from API (500): Invalid username or password"
 const sessionErrorExpectedResponse = "Error from API (403): Invalid token"
 const subnetsErrorExpectedResponse = "Error from API (404): No subnets found"
@@ -266,6 +283,69 @@ func httpUpdateCustomFieldsRequestTestServer() *httptest.Server {
 	})
 }
 
+// httpAutoRefreshTestServer returns a server that only accepts the token
+// returned by authOKResponseText, counting logins in logins.
+func httpAutoRefreshTestServer(logins *int32) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/user/"):
+			atomic.AddInt32(logins, 1)
+			time.Sleep(10 * time.Millisecond)
+			http.Error(w, authOKResponseText, http.StatusOK)
+		case r.Header.Get("phpipam-token") == "foobarbazboop":
+			http.Error(w, subnetSearchOKResponseText, http.StatusOK)
+		default:
+			http.Error(w, tokenInvalidResponseText, http.StatusUnauthorized)
+		}
+	})
+}
+
+// httpFlakyTestServer returns a server that fails the first failures requests
+// with a 503 error, counting requests in requests.
+func httpFlakyTestServer(failures int32, requests *int32) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(requests, 1) <= failures {
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, subnetSearchOKResponseText, http.StatusOK)
+	})
+}
+
+// httpRateLimitedTestServer returns a server that fails the first request
+// with a 429 error and a Retry-After header of 0, counting requests in
+// requests.
+func httpRateLimitedTestServer(requests *int32) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		if atomic.AddInt32(requests, 1) == 1 {
+			w.Header().Add("Retry-After", "0")
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		http.Error(w, subnetSearchOKResponseText, http.StatusOK)
+	})
+}
+
+// httpStaticTokenTestServer returns a server that only accepts requests with
+// the static app code token foobarbazboop, counting logins in logins.
+func httpStaticTokenTestServer(logins *int32) *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/user/"):
+			atomic.AddInt32(logins, 1)
+			http.Error(w, authErrorResponseText, http.StatusInternalServerError)
+		case r.Header.Get("token") == "foobarbazboop":
+			http.Error(w, subnetSearchOKResponseText, http.StatusOK)
+		default:
+			http.Error(w, tokenInvalidResponseText, http.StatusUnauthorized)
+		}
+	})
+}
+
 func phpipamConfig() phpipam.Config {
 	return phpipam.Config{
 		AppID:    "0123456789abcdefgh",
@@ -304,12 +384,13 @@ func TestLoginSessionSuccess(t *testing.T) {
 	cfg.Endpoint = ts.URL
 	sess := session.NewSession(cfg)
 	client := NewClient(sess)
-	if err := loginSession(client.Session); err != nil {
+	if err := loginSession(context.Background(), client.Session); err != nil {
 		t.Fatalf("Unexpected error: %#v", err)
 	}
 
 	expected := session.Token{
-		String: "foobarbazboop",
+		String:  "foobarbazboop",
+		Expires: testDateStamp,
 	}
 	actual := client.Session.Token
 
@@ -318,6 +399,140 @@ func TestLoginSessionSuccess(t *testing.T) {
 	}
 }
 
+func TestSessionRefresh(t *testing.T) {
+	ts := httpAuthOKTestServer()
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	sess.Token = session.Token{
+		String:  "stale",
+		Expires: "2000-01-01 00:00:00",
+	}
+	if !sess.TokenExpired() {
+		t.Fatalf("Expected token to be expired")
+	}
+
+	if err := sess.Refresh(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if sess.Token.String != "foobarbazboop" {
+		t.Fatalf("Expected refreshed token, got %q", sess.Token.String)
+	}
+
+	if sess.TokenExpired() {
+		t.Fatalf("Expected token to not be expired")
+	}
+}
+
+func TestSessionLogout(t *testing.T) {
+	var calls []string
+	var tokens []string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		tokens = append(tokens, r.Header.Get("phpipam-token"))
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, `{"code":200,"success":true,"data":"User token removed"}`, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+
+	for i := 0; i < 2; i++ {
+		if err := sess.Logout(); err != nil {
+			t.Fatalf("Unexpected error on call %d: %s", i+1, err)
+		}
+	}
+
+	if expected := []string{"DELETE /0123456789abcdefgh/user/"}; !reflect.DeepEqual(expected, calls) {
+		t.Fatalf("Expected calls %v, got %v", expected, calls)
+	}
+	if expected := []string{"foobarbazboop"}; !reflect.DeepEqual(expected, tokens) {
+		t.Fatalf("Expected tokens %v, got %v", expected, tokens)
+	}
+	if sess.Token.String != "" {
+		t.Fatalf("Expected token to be removed, got %q", sess.Token.String)
+	}
+}
+
+func TestSessionLogoutNoToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		static bool
+	}{
+		{name: "no token"},
+		{name: "static token", token: "foobarbazboop", static: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int32
+			ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				http.Error(w, `{"code":500,"success":false,"message":"Unexpected request"}`, http.StatusInternalServerError)
+			})
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			sess.Token.String = tc.token
+			sess.StaticToken = tc.static
+
+			if err := sess.Logout(); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if calls != 0 {
+				t.Fatalf("Expected no requests, got %d", calls)
+			}
+			if sess.Token.String != tc.token {
+				t.Fatalf("Expected token %q, got %q", tc.token, sess.Token.String)
+			}
+		})
+	}
+}
+
+func TestSessionLogoutExpiredToken(t *testing.T) {
+	var calls int32
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, `{"code":403,"success":false,"message":"Token expired"}`, http.StatusForbidden)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+
+	if err := sess.Logout(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if calls != 1 {
+		t.Fatalf("Expected 1 request, got %d", calls)
+	}
+	if sess.Token.String != "" {
+		t.Fatalf("Expected token to be removed, got %q", sess.Token.String)
+	}
+}
+
+func TestSessionLogoutError(t *testing.T) {
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, `{"code":500,"success":false,"message":"Database error"}`, http.StatusInternalServerError)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+
+	err := sess.Logout()
+	if err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+	if expected := "Error from API (500): Database error"; err.Error() != expected {
+		t.Fatalf("Expected error %q, got %q", expected, err)
+	}
+	if sess.Token.String != "foobarbazboop" {
+		t.Fatalf("Expected token to be kept, got %q", sess.Token.String)
+	}
+}
+
 func TestLoginSessionError(t *testing.T) {
 	ts := httpAuthErrorTestServer()
 	defer ts.Close()
@@ -325,7 +540,7 @@ func TestLoginSessionError(t *testing.T) {
 	cfg.Endpoint = ts.URL
 	sess := session.NewSession(cfg)
 	client := NewClient(sess)
-	err := loginSession(client.Session)
+	err := loginSession(context.Background(), client.Session)
 
 	if err == nil {
 		t.Fatalf("Expected error, got none")
@@ -362,6 +577,175 @@ func TestSendRequestSuccess(t *testing.T) {
 	}
 }
 
+func TestSendRequestBasePath(t *testing.T) {
+	var path string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, subnetSearchOKResponseText, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	sess.Config.BasePath = "/ipam/api/"
+	client := NewClient(sess)
+
+	actual := make([]testSubnetData, 0)
+	if err := client.SendRequest("GET", "/subnets/cidr/10.10.1.0/24/", struct{}{}, &actual); err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+
+	expected := "/ipam/api/0123456789abcdefgh/subnets/cidr/10.10.1.0/24/"
+	if path != expected {
+		t.Fatalf("Expected path %s, got %s", expected, path)
+	}
+}
+
+func TestPing(t *testing.T) {
+	var method, path string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, authOKResponseText, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewClient(sess)
+
+	if err := client.Ping(); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if method != "GET" || path != "/0123456789abcdefgh/user/" {
+		t.Fatalf("Expected GET /0123456789abcdefgh/user/, got %s %s", method, path)
+	}
+}
+
+func TestDetectPHPVersion(t *testing.T) {
+	cases := []struct {
+		name            string
+		poweredBy       string
+		sections        string
+		expectedVersion string
+		expectedMode    phpipam.NumericMode
+		expectErr       bool
+	}{
+		{
+			name:            "PHP 8 header",
+			poweredBy:       "PHP/8.1.2",
+			sections:        `[{"id":"1","name":"Customers"}]`,
+			expectedVersion: "8.1.2",
+			expectedMode:    phpipam.NumericNumbers,
+		},
+		{
+			name:            "PHP 7 header",
+			poweredBy:       "PHP/7.4.33",
+			sections:        `[{"id":"1","name":"Customers"}]`,
+			expectedVersion: "7.4.33",
+			expectedMode:    phpipam.NumericStrings,
+		},
+		{
+			name:         "numeric IDs",
+			sections:     `[{"id":1,"name":"Customers"}]`,
+			expectedMode: phpipam.NumericNumbers,
+		},
+		{
+			name:         "string IDs",
+			sections:     `[{"id":"1","name":"Customers"}]`,
+			expectedMode: phpipam.NumericStrings,
+		},
+		{
+			name:         "no sections",
+			sections:     `[]`,
+			expectedMode: phpipam.NumericAuto,
+			expectErr:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Content-Type", "application/json")
+				if tc.poweredBy != "" {
+					w.Header().Add("X-Powered-By", tc.poweredBy)
+				}
+				http.Error(w, `{"code":200,"success":true,"data":`+tc.sections+`}`, http.StatusOK)
+			})
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewClient(sess)
+
+			version, err := client.DetectPHPVersion()
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("Expected error %t, got %v", tc.expectErr, err)
+			}
+			if version != tc.expectedVersion || sess.PHPVersion != tc.expectedVersion {
+				t.Fatalf("Expected version %q, got %q (session %q)", tc.expectedVersion, version, sess.PHPVersion)
+			}
+			if sess.NumericMode != tc.expectedMode {
+				t.Fatalf("Expected mode %s, got %s", tc.expectedMode, sess.NumericMode)
+			}
+		})
+	}
+}
+
+func TestPingUnreachable(t *testing.T) {
+	ts := httpAuthOKTestServer()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewClient(sess)
+	ts.Close()
+
+	err := client.Ping()
+	if !request.IsProtocolError(err) {
+		t.Fatalf("Expected protocol error, got %#v", err)
+	}
+}
+
+func TestPingUnreachableNoToken(t *testing.T) {
+	ts := httpAuthOKTestServer()
+	cfg := phpipamConfig()
+	cfg.Endpoint = ts.URL
+	sess := session.NewSession(cfg)
+	client := NewClient(sess)
+	ts.Close()
+
+	err := client.Ping()
+	if !request.IsProtocolError(err) {
+		t.Fatalf("Expected protocol error, got %#v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "Error logging into PHPIPAM: ") {
+		t.Fatalf("Expected login error, got %q", err)
+	}
+}
+
+func TestSendRequestLoginUnauthorized(t *testing.T) {
+	var calls []string
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, `{"code":401,"success":false,"message":"Invalid username or password"}`, http.StatusUnauthorized)
+	})
+	defer ts.Close()
+	cfg := phpipamConfig()
+	cfg.Endpoint = ts.URL
+	client := NewClient(session.NewSession(cfg))
+
+	err := client.SendRequest("GET", "/subnets/3/", &struct{}{}, &struct{}{})
+	if !request.IsUnauthorized(err) {
+		t.Fatalf("Expected unauthorized error, got %#v", err)
+	}
+	var apiErr *request.APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "Invalid username or password" {
+		t.Fatalf("Expected API error from login, got %#v", err)
+	}
+	if expected := []string{"POST /0123456789abcdefgh/user/"}; !reflect.DeepEqual(expected, calls) {
+		t.Fatalf("Expected calls %v, got %v", expected, calls)
+	}
+}
+
 func TestSendRequestError(t *testing.T) {
 	ts := httpSubnetSearchErrorTestServer()
 	defer ts.Close()
@@ -384,43 +768,790 @@ func TestSendRequestError(t *testing.T) {
 	}
 }
 
-func TestGetCustomFieldsSchema(t *testing.T) {
-	ts := httpCustomFieldsSchemaTestServer()
+func TestSendRequestRaw(t *testing.T) {
+	ts := httpSubnetSearchOKTestServer()
 	defer ts.Close()
 	sess := fullSessionConfig()
 	sess.Config.Endpoint = ts.URL
 	client := NewClient(sess)
 
-	expected := testCustomFieldsSchemaExpected
-	actual, err := client.GetCustomFieldsSchema("subnets")
+	var parsed testSubnetDataResponse
+	if err := json.Unmarshal([]byte(subnetSearchOKResponseText), &parsed); err != nil {
+		t.Fatalf("Bad: %#v", err)
+	}
+	expected := parsed.Data
+
+	raw, err := client.SendRequestRaw("GET", "/subnets/cidr/10.10.1.0/24/", struct{}{})
 	if err != nil {
 		t.Fatalf("Bad: %s", err)
 	}
 
+	actual := make([]testSubnetData, 0)
+	if err := json.Unmarshal(raw, &actual); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
 	if !reflect.DeepEqual(expected, actual) {
 		t.Fatalf("Expected %#v, got %#v", expected, actual)
 	}
 }
 
-func TestUpdateCustomFieldsRequest(t *testing.T) {
-	ts := httpUpdateCustomFieldsRequestTestServer()
+func TestSendRequestWithParams(t *testing.T) {
+	cases := []struct {
+		Name     string
+		URI      string
+		Params   url.Values
+		Expected string
+	}{
+		{Name: "none", URI: "/subnets/", Expected: "/0123456789abcdefgh/subnets/"},
+		{
+			Name:     "params",
+			URI:      "/subnets/",
+			Params:   url.Values{"filter_by": {"description"}, "filter_value": {"a b&c"}, "links": {"false"}},
+			Expected: "/0123456789abcdefgh/subnets/?filter_by=description&filter_value=a+b%26c&links=false",
+		},
+		{
+			Name:     "existing query",
+			URI:      "/subnets/?order=desc",
+			Params:   url.Values{"order_by": {"description"}},
+			Expected: "/0123456789abcdefgh/subnets/?order=desc&order_by=description",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var actual string
+			ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+				actual = r.URL.RequestURI()
+				w.Header().Add("Content-Type", "application/json")
+				http.Error(w, subnetSearchOKResponseText, http.StatusOK)
+			})
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			client := NewClient(sess)
+
+			var out []testSubnetData
+			if err := client.SendRequestWithParams("GET", tc.URI, tc.Params, &struct{}{}, &out); err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+			if actual != tc.Expected {
+				t.Fatalf("Expected request URI %s, got %s", tc.Expected, actual)
+			}
+			if len(out) == 0 {
+				t.Fatalf("Expected response data to be decoded")
+			}
+		})
+	}
+}
+
+func TestSendRequestDisableLinks(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Method   string
+		URI      string
+		Disable  bool
+		Expected string
+	}{
+		{Name: "default", Method: "GET", URI: "/subnets/", Expected: "/0123456789abcdefgh/subnets/"},
+		{Name: "disabled", Method: "GET", URI: "/subnets/", Disable: true, Expected: "/0123456789abcdefgh/subnets/?links=false"},
+		{Name: "existing query", Method: "GET", URI: "/subnets/?order=desc", Disable: true, Expected: "/0123456789abcdefgh/subnets/?order=desc&links=false"},
+		{Name: "explicit links", Method: "GET", URI: "/subnets/?links=true", Disable: true, Expected: "/0123456789abcdefgh/subnets/?links=true"},
+		{Name: "not a GET", Method: "DELETE", URI: "/subnets/3/", Disable: true, Expected: "/0123456789abcdefgh/subnets/3/"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var actual string
+			ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+				actual = r.URL.RequestURI()
+				w.Header().Add("Content-Type", "application/json")
+				http.Error(w, `{"code":200,"success":true,"data":[]}`, http.StatusOK)
+			})
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			sess.DisableLinks = tc.Disable
+			client := NewClient(sess)
+
+			if err := client.SendRequest(tc.Method, tc.URI, &struct{}{}, &[]interface{}{}); err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+			if actual != tc.Expected {
+				t.Fatalf("Expected request URI %s, got %s", tc.Expected, actual)
+			}
+		})
+	}
+}
+func TestSendRequestWithResponse(t *testing.T) {
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.Header().Add("Location", "/api/test/subnets/8/")
+		w.Header().Add("X-RateLimit-Remaining", "42")
+		http.Error(w, `{"code":201,"success":true,"data":"Subnet created"}`, http.StatusCreated)
+	})
 	defer ts.Close()
 	sess := fullSessionConfig()
 	sess.Config.Endpoint = ts.URL
 	client := NewClient(sess)
 
-	in := map[string]interface{}{
-		"Projects": "updated",
+	var message string
+	resp, err := client.SendRequestWithResponse("POST", "/subnets/", struct{}{}, &message)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
 	}
-
-	expected := testUpdateCustomFieldsRequestExpected
-	actual, err := client.updateCustomFieldsRequest(3, in, "subnets", testCustomFieldsSchemaExpected)
+	if message != "Subnet created" {
+		t.Fatalf("Expected message Subnet created, got %s", message)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+	if loc := resp.Header.Get("Location"); loc != "/api/test/subnets/8/" {
+		t.Fatalf("Expected Location /api/test/subnets/8/, got %s", loc)
+	}
+	if rem := resp.Header.Get("X-RateLimit-Remaining"); rem != "42" {
+		t.Fatalf("Expected X-RateLimit-Remaining 42, got %s", rem)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		t.Fatalf("Bad: %s", err)
 	}
+	if !strings.Contains(string(body), `"data":"Subnet created"`) {
+		t.Fatalf("Expected body to be readable, got %s", body)
+	}
+}
 
-	if !reflect.DeepEqual(expected, actual) {
-		t.Fatalf("Expected %#v, got %#v", expected, actual)
+func TestSendRequestWithResponseError(t *testing.T) {
+	ts := httpSubnetSearchErrorTestServer()
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewClient(sess)
+
+	resp, err := client.SendRequestWithResponse("GET", "/subnets/cidr/10.10.1.0/24/", struct{}{}, &struct{}{})
+	if err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected a 404 response, got %#v", resp)
+	}
+}
+
+// testObserver records the requests observed via ObserveRequest.
+type testObserver struct {
+	mu       sync.Mutex
+	observed []string
+}
+
+func (o *testObserver) ObserveRequest(method, path string, status int, duration time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.observed = append(o.observed, fmt.Sprintf("%s %s %d %t %t", method, path, status, duration > 0, err != nil))
+}
+
+func TestSendRequestObserver(t *testing.T) {
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/10.10.9.0/24/") {
+			http.Error(w, subnetSearchErrorResponseText, http.StatusNotFound)
+			return
+		}
+		http.Error(w, subnetSearchOKResponseText, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	observer := &testObserver{}
+	sess.Observer = observer
+	client := NewClient(sess)
+
+	tmp := make([]testSubnetData, 0)
+	if err := client.SendRequest("GET", "/subnets/cidr/10.10.1.0/24/", struct{}{}, &tmp); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if err := client.SendRequest("GET", "/subnets/cidr/10.10.9.0/24/", struct{}{}, &tmp); err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+
+	sess.Config.Endpoint = "http://127.0.0.1:0"
+	if err := client.SendRequest("GET", "/subnets/cidr/10.10.1.0/24/", struct{}{}, &tmp); err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+
+	expected := []string{
+		"GET /subnets/cidr/10.10.1.0/24/ 200 true false",
+		"GET /subnets/cidr/10.10.9.0/24/ 404 true true",
+		"GET /subnets/cidr/10.10.1.0/24/ 0 true true",
+	}
+	if !reflect.DeepEqual(expected, observer.observed) {
+		t.Fatalf("Expected %v, got %v", expected, observer.observed)
+	}
+}
+
+func TestSendRequestObserverQuery(t *testing.T) {
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, subnetSearchOKResponseText, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	observer := &testObserver{}
+	sess.Observer = observer
+	client := NewClient(sess)
+
+	tmp := make([]testSubnetData, 0)
+	params := url.Values{"filter_by": {"subnet"}, "filter_value": {"10.10.1.0"}}
+	if err := client.SendRequestWithParams("GET", "/subnets/", params, struct{}{}, &tmp); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	expected := []string{"GET /subnets/ 200 true false"}
+	if !reflect.DeepEqual(expected, observer.observed) {
+		t.Fatalf("Expected %v, got %v", expected, observer.observed)
+	}
+	if strings.Contains(observer.observed[0], "?") {
+		t.Fatalf("Expected observed path without query string, got %s", observer.observed[0])
+	}
+}
+
+// testDoer is a session.Doer that responds to every request with body,
+// recording the URL of the last request in url.
+type testDoer struct {
+	body string
+	url  string
+}
+
+func (d *testDoer) Do(req *http.Request) (*http.Response, error) {
+	d.url = req.URL.String()
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(d.body)),
+	}, nil
+}
+
+func TestSendRequestDoer(t *testing.T) {
+	doer := &testDoer{body: subnetSearchOKResponseText}
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = "http://phpipam.example/api"
+	sess.Doer = doer
+	client := NewClient(sess)
+
+	var parsed testSubnetDataResponse
+	if err := json.Unmarshal([]byte(subnetSearchOKResponseText), &parsed); err != nil {
+		t.Fatalf("Bad: %#v", err)
+	}
+	expected := parsed.Data
+
+	actual := make([]testSubnetData, 0)
+	if err := client.SendRequest("GET", "/subnets/cidr/10.10.1.0/24/", struct{}{}, &actual); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+	if expectedURL := "http://phpipam.example/api/0123456789abcdefgh/subnets/cidr/10.10.1.0/24/"; doer.url != expectedURL {
+		t.Fatalf("Expected URL %s, got %s", expectedURL, doer.url)
+	}
+}
+
+func TestSendRequestStaticToken(t *testing.T) {
+	var logins int32
+	ts := httpStaticTokenTestServer(&logins)
+	defer ts.Close()
+	sess := session.NewStaticTokenSession(ts.URL, "0123456789abcdefgh", "foobarbazboop")
+	sess.AutoRefresh = true
+	client := NewClient(sess)
+
+	tmp := make([]testSubnetData, 0)
+	if err := client.SendRequest("GET", "/subnets/cidr/10.10.1.0/24/", struct{}{}, &tmp); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	sess.Token.String = "invalid"
+	if err := client.SendRequest("GET", "/subnets/cidr/10.10.1.0/24/", struct{}{}, &tmp); err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+
+	if logins != 0 {
+		t.Fatalf("Expected no logins, got %d", logins)
+	}
+}
+
+func TestSendRequestContextCancelled(t *testing.T) {
+	ts := httpSubnetSearchOKTestServer()
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewClient(sess)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tmp := make([]testSubnetData, 0)
+	err := client.SendRequestContext(ctx, "GET", "/subnets/cidr/10.10.1.0/24/", struct{}{}, &tmp)
+	if err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+
+	if !strings.Contains(err.Error(), context.Canceled.Error()) {
+		t.Fatalf("Expected error to contain %q, got %q", context.Canceled, err)
+	}
+}
+
+func TestSendRequestAutoRefresh(t *testing.T) {
+	var logins int32
+	ts := httpAutoRefreshTestServer(&logins)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	sess.Token.String = "expired"
+	sess.AutoRefresh = true
+	client := NewClient(sess)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tmp := make([]testSubnetData, 0)
+			errs <- client.SendRequest("GET", "/subnets/cidr/10.10.1.0/24/", struct{}{}, &tmp)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+
+	if logins != 1 {
+		t.Fatalf("Expected 1 login, got %d", logins)
+	}
+}
+
+func TestSendRequestConcurrentLogin(t *testing.T) {
+	var logins int32
+	ts := httpAutoRefreshTestServer(&logins)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	sess.Token = session.Token{}
+	client := NewClient(sess)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tmp := make([]testSubnetData, 0)
+			errs <- client.SendRequest("GET", "/subnets/cidr/10.10.1.0/24/", struct{}{}, &tmp)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+
+	if logins != 1 {
+		t.Fatalf("Expected 1 login, got %d", logins)
+	}
+	if sess.CurrentToken().String != "foobarbazboop" {
+		t.Fatalf("Expected token foobarbazboop, got %s", sess.CurrentToken().String)
+	}
+}
+
+func TestSendRequestAutoRefreshDisabled(t *testing.T) {
+	var logins int32
+	ts := httpAutoRefreshTestServer(&logins)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	sess.Token.String = "expired"
+	client := NewClient(sess)
+
+	tmp := make([]testSubnetData, 0)
+	err := client.SendRequest("GET", "/subnets/cidr/10.10.1.0/24/", struct{}{}, &tmp)
+	if err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+
+	if logins != 0 {
+		t.Fatalf("Expected no logins, got %d", logins)
+	}
+}
+
+func TestSendRequestRetry(t *testing.T) {
+	var requests int32
+	ts := httpFlakyTestServer(2, &requests)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	sess.Retry = session.RetryConfig{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+	}
+	client := NewClient(sess)
+
+	var parsed testSubnetDataResponse
+	actual := make([]testSubnetData, 0)
+	if err := json.Unmarshal([]byte(subnetSearchOKResponseText), &parsed); err != nil {
+		t.Fatalf("Bad: %#v", err)
+	}
+	expected := parsed.Data
+
+	if err := client.SendRequest("GET", "/subnets/cidr/10.10.1.0/24/", struct{}{}, &actual); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	if requests != 3 {
+		t.Fatalf("Expected 3 requests, got %d", requests)
+	}
+}
+
+func TestSendRequestRetryLogin(t *testing.T) {
+	for _, method := range []string{"GET", "POST"} {
+		t.Run(method, func(t *testing.T) {
+			var calls []string
+			ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, r.Method+" "+strings.TrimPrefix(r.URL.Path, "/0123456789abcdefgh"))
+				w.Header().Add("Content-Type", "application/json")
+				switch {
+				case r.URL.Path == "/0123456789abcdefgh/user/" && len(calls) == 1:
+					http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+				case r.URL.Path == "/0123456789abcdefgh/user/":
+					http.Error(w, authOKResponseText, http.StatusOK)
+				default:
+					http.Error(w, `{"code":200,"success":true,"data":"ok"}`, http.StatusOK)
+				}
+			})
+			defer ts.Close()
+			cfg := phpipamConfig()
+			cfg.Endpoint = ts.URL
+			sess := session.NewSession(cfg)
+			sess.Retry = session.RetryConfig{
+				MaxAttempts: 3,
+				BaseDelay:   time.Millisecond,
+			}
+			client := NewClient(sess)
+
+			var out string
+			if err := client.SendRequest(method, "/subnets/", &struct{}{}, &out); err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+
+			expected := []string{"POST /user/", "POST /user/", method + " /subnets/"}
+			if !reflect.DeepEqual(expected, calls) {
+				t.Fatalf("Expected calls %v, got %v", expected, calls)
+			}
+		})
+	}
+}
+
+func TestSendRequestRetryDisabledByDefault(t *testing.T) {
+	var requests int32
+	ts := httpFlakyTestServer(1, &requests)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewClient(sess)
+
+	tmp := make([]testSubnetData, 0)
+	if err := client.SendRequest("GET", "/subnets/cidr/10.10.1.0/24/", struct{}{}, &tmp); err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+
+	if requests != 1 {
+		t.Fatalf("Expected 1 request, got %d", requests)
+	}
+}
+
+func TestSendRequestRetryIdempotentOnly(t *testing.T) {
+	cases := []struct {
+		method   string
+		expected int32
+	}{
+		{method: "GET", expected: 3},
+		{method: "POST", expected: 1},
+		{method: "PATCH", expected: 1},
+		{method: "DELETE", expected: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.method, func(t *testing.T) {
+			var requests int32
+			ts := httpFlakyTestServer(5, &requests)
+			defer ts.Close()
+			sess := fullSessionConfig()
+			sess.Config.Endpoint = ts.URL
+			sess.Retry = session.RetryConfig{
+				MaxAttempts: 3,
+				BaseDelay:   time.Millisecond,
+			}
+			client := NewClient(sess)
+
+			tmp := make([]testSubnetData, 0)
+			if err := client.SendRequest(tc.method, "/subnets/", struct{}{}, &tmp); err == nil {
+				t.Fatalf("Expected error, got none")
+			}
+
+			if requests != tc.expected {
+				t.Fatalf("Expected %d requests, got %d", tc.expected, requests)
+			}
+		})
+	}
+}
+
+func TestSendRequestRetryUnsafe(t *testing.T) {
+	var requests int32
+	ts := httpFlakyTestServer(1, &requests)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	sess.Retry = session.RetryConfig{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		RetryUnsafe: true,
+	}
+	client := NewClient(sess)
+
+	tmp := make([]testSubnetData, 0)
+	if err := client.SendRequest("POST", "/subnets/", struct{}{}, &tmp); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if requests != 2 {
+		t.Fatalf("Expected 2 requests, got %d", requests)
+	}
+}
+
+func TestSendRequestRetryContextCancelled(t *testing.T) {
+	var requests int32
+	ts := httpFlakyTestServer(5, &requests)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	sess.Retry = session.RetryConfig{
+		MaxAttempts: 5,
+		BaseDelay:   time.Hour,
+	}
+	client := NewClient(sess)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	tmp := make([]testSubnetData, 0)
+	err := client.SendRequestContext(ctx, "GET", "/subnets/cidr/10.10.1.0/24/", struct{}{}, &tmp)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected %q, got %v", context.DeadlineExceeded, err)
+	}
+
+	if requests != 1 {
+		t.Fatalf("Expected 1 request, got %d", requests)
+	}
+}
+
+func TestSendRequestRetryAfter(t *testing.T) {
+	var requests int32
+	ts := httpRateLimitedTestServer(&requests)
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	sess.Retry = session.RetryConfig{
+		MaxAttempts: 2,
+		BaseDelay:   time.Hour,
+	}
+	client := NewClient(sess)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tmp := make([]testSubnetData, 0)
+	if err := client.SendRequestContext(ctx, "GET", "/subnets/cidr/10.10.1.0/24/", struct{}{}, &tmp); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if requests != 2 {
+		t.Fatalf("Expected 2 requests, got %d", requests)
+	}
+}
+
+func TestGetCustomFieldsSchema(t *testing.T) {
+	ts := httpCustomFieldsSchemaTestServer()
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewClient(sess)
+
+	expected := testCustomFieldsSchemaExpected
+	actual, err := client.GetCustomFieldsSchema("subnets")
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestGetCustomFieldsSchemaCache(t *testing.T) {
+	var requests int32
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, testCustomFieldsSchemaResponseText, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	sess.SchemaCacheTTL = time.Minute
+	client := NewClient(sess)
+
+	for i := 0; i < 3; i++ {
+		actual, err := client.GetCustomFieldsSchema("subnets")
+		if err != nil {
+			t.Fatalf("Bad: %s", err)
+		}
+		if !reflect.DeepEqual(testCustomFieldsSchemaExpected, actual) {
+			t.Fatalf("Expected %#v, got %#v", testCustomFieldsSchemaExpected, actual)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("Expected 1 request, got %d", n)
+	}
+
+	if _, err := client.GetCustomFieldsSchema("addresses"); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("Expected 2 requests after fetching another controller, got %d", n)
+	}
+
+	client.ClearSchemaCache()
+	if _, err := client.GetCustomFieldsSchema("subnets"); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("Expected 3 requests after clearing the cache, got %d", n)
+	}
+}
+
+func TestValidateCustomFields(t *testing.T) {
+	ts := httpCustomFieldsSchemaTestServer()
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewClient(sess)
+
+	if err := client.ValidateCustomFields("subnets", map[string]interface{}{"Projects": "foo"}); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if err := client.ValidateCustomFields("subnets", map[string]interface{}{}); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	err := client.ValidateCustomFields("subnets", map[string]interface{}{"Projcts": "foo"})
+	expected := "Custom field Projcts not found in schema for controller subnets"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Expected error %q, got %v", expected, err)
+	}
+}
+
+func TestUpdateCustomFieldsRequest(t *testing.T) {
+	ts := httpUpdateCustomFieldsRequestTestServer()
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	client := NewClient(sess)
+
+	in := map[string]interface{}{
+		"Projects": "updated",
+	}
+
+	expected := testUpdateCustomFieldsRequestExpected
+	actual, err := client.updateCustomFieldsRequest(3, in, "subnets", testCustomFieldsSchemaExpected)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestSendRequestDryRun(t *testing.T) {
+	var requests int32
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, subnetSearchOKResponseText, http.StatusOK)
+	})
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	sess.DryRun = true
+	client := NewClient(sess)
+
+	actual := make([]testSubnetData, 0)
+	if err := client.SendRequest("GET", "/subnets/cidr/10.10.1.0/24/", struct{}{}, &actual); err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+
+	in := map[string]interface{}{"description": "foo"}
+	var message string
+	err := client.SendRequest("PATCH", "/subnets/3/", &in, &message)
+	var e *request.DryRunError
+	if !errors.As(err, &e) {
+		t.Fatalf("Expected dry run error, got %#v", err)
+	}
+
+	expected := &request.DryRunError{Method: "PATCH", URI: "/subnets/3/", Body: []byte(`{"description":"foo"}`)}
+	if !reflect.DeepEqual(expected, e) {
+		t.Fatalf("Expected %#v, got %#v", expected, e)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("Expected 1 request to be sent, got %d", n)
+	}
+}
+
+func TestUpdateCustomFieldsRequestDryRun(t *testing.T) {
+	ts := httpUpdateCustomFieldsRequestTestServer()
+	defer ts.Close()
+	sess := fullSessionConfig()
+	sess.Config.Endpoint = ts.URL
+	sess.DryRun = true
+	client := NewClient(sess)
+
+	in := map[string]interface{}{
+		"Projects": "updated",
+	}
+
+	_, err := client.updateCustomFieldsRequest(3, in, "subnets", testCustomFieldsSchemaExpected)
+	if !request.IsDryRun(err) {
+		t.Fatalf("Expected dry run error, got %#v", err)
+	}
+
+	in["foo"] = "bar"
+	_, err = client.updateCustomFieldsRequest(3, in, "subnets", testCustomFieldsSchemaExpected)
+	if err == nil || request.IsDryRun(err) {
+		t.Fatalf("Expected schema error, got %#v", err)
 	}
 }
 
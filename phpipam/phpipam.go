@@ -4,15 +4,27 @@ package phpipam
 
 import (
 	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // The default PHPIPAM API endpoint.
 const defaultAPIAddress = "http://localhost/api"
 
+// Version is the version of the SDK.
+const Version = "0.1.0"
+
+// DefaultUserAgent is the User-Agent header sent with requests when a session
+// does not set one.
+const DefaultUserAgent = "phpipam-sdk-go/" + Version
+
 // Config contains the configuration for connecting to the PHPIPAM API.
 //
 //
@@ -39,6 +51,14 @@ type Config struct {
 	// The API endpoint.
 	Endpoint string
 
+	// An optional path that is appended to Endpoint to form the API base URL,
+	// for example "/ipam/api" when the API is served from a reverse proxy
+	// under a non-default prefix. Requests are sent to
+	// Endpoint + BasePath + "/" + AppID + the request path. Leading and
+	// trailing slashes are ignored. If this is empty, Endpoint is expected to
+	// contain the full base URL, ie: http://localhost/api.
+	BasePath string
+
 	// The password for the PHPIPAM account.
 	Password string
 
@@ -84,7 +104,10 @@ func DefaultConfigProvider() Config {
 //
 // This is technically a binary string as per the PHPIPAM spec, however in test
 // JSON and the spec itself, boolean values seem to be represented by the
-// actual string values as shown above.
+// actual string values as shown above. Some endpoints (and PHP 8 installs)
+// return actual JSON booleans or numbers instead, so when unmarshaling, true,
+// false, 1, 0, and the string forms of all of these are accepted. Values are
+// always marshaled in the "0"/"1" form.
 type BoolIntString bool
 
 // MarshalJSON implements json.Marshaler for the BoolIntString type.
@@ -101,19 +124,19 @@ func (bis BoolIntString) MarshalJSON() ([]byte, error) {
 
 // UnmarshalJSON implements json.Unmarshaler for the BoolIntString type.
 func (bis *BoolIntString) UnmarshalJSON(b []byte) error {
-	var s string
-	if err := json.Unmarshal(b, &s); err != nil {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
 		return err
 	}
-	switch s {
-	case "0", "":
+	switch v {
+	case "0", "", "false", float64(0), false, nil:
 		*bis = false
-	case "1":
+	case "1", "true", float64(1), true:
 		*bis = true
 	default:
 		return &json.UnmarshalTypeError{
-			Value: "bool",
-			Type:  reflect.ValueOf(s).Type(),
+			Value: string(b),
+			Type:  reflect.TypeOf(*bis),
 		}
 	}
 
@@ -121,7 +144,12 @@ func (bis *BoolIntString) UnmarshalJSON(b []byte) error {
 }
 
 // JSONIntString is a type for representing an IntString JSON value, but with
-// "" also representing a zero value.
+// "" and null also representing a zero value. Unquoted JSON numbers are also
+// accepted.
+//
+// As the underlying type is an int, fields of this type honor omitempty, and
+// are left out of the JSON entirely when zero. Use omitempty on optional ID
+// fields, so that an unset ID (ie: no VLAN) is not sent as "0".
 type JSONIntString int
 
 // MarshalJSON implements json.Marshaler for the JSONIntString type.
@@ -131,26 +159,604 @@ func (jis JSONIntString) MarshalJSON() ([]byte, error) {
 
 // UnmarshalJSON implements json.Unmarshaler for the JSONIntString type.
 func (jis *JSONIntString) UnmarshalJSON(b []byte) error {
-	var s string
-	if err := json.Unmarshal(b, &s); err != nil {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
 		return err
 	}
-	if s == "" {
+	switch v := v.(type) {
+	case nil:
 		*jis = 0
-	} else {
-		i, err := strconv.Atoi(s)
+	case float64:
+		if v != float64(int(v)) {
+			return &json.UnmarshalTypeError{
+				Value: "number " + string(b),
+				Type:  reflect.TypeOf(*jis),
+			}
+		}
+		*jis = JSONIntString(v)
+	case string:
+		if v == "" {
+			*jis = 0
+			return nil
+		}
+		i, err := strconv.Atoi(v)
 		if err != nil {
 			return &json.UnmarshalTypeError{
-				Value: "int",
-				Type:  reflect.ValueOf(s).Type(),
+				Value: "string " + string(b),
+				Type:  reflect.TypeOf(*jis),
 			}
 		}
 		*jis = JSONIntString(i)
+	default:
+		return &json.UnmarshalTypeError{
+			Value: string(b),
+			Type:  reflect.TypeOf(*jis),
+		}
+	}
+
+	return nil
+}
+
+// JSONFloatString is a type for representing a floating point number that
+// may be returned either quoted or unquoted, such as the percentages returned
+// by usage endpoints. As with JSONIntString, "" and null represent a zero
+// value.
+type JSONFloatString float64
+
+// MarshalJSON implements json.Marshaler for the JSONFloatString type.
+func (jfs JSONFloatString) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatFloat(float64(jfs), 'f', -1, 64))
+}
+
+// UnmarshalJSON implements json.Unmarshaler for the JSONFloatString type.
+func (jfs *JSONFloatString) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
 	}
+	switch v := v.(type) {
+	case nil:
+		*jfs = 0
+	case float64:
+		*jfs = JSONFloatString(v)
+	case string:
+		if v == "" {
+			*jfs = 0
+			return nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return &json.UnmarshalTypeError{
+				Value: "string " + string(b),
+				Type:  reflect.TypeOf(*jfs),
+			}
+		}
+		*jfs = JSONFloatString(f)
+	default:
+		return &json.UnmarshalTypeError{
+			Value: string(b),
+			Type:  reflect.TypeOf(*jfs),
+		}
+	}
+
+	return nil
+}
+
+// NumericMode describes the JSON form a PHPIPAM server uses for the values of
+// JSONIntString and BoolIntString fields. PHPIPAM on PHP 7 returns these as
+// strings (ie: "12" and "1"), while PHP 8 returns them as numbers.
+//
+// The types themselves accept both forms regardless of the mode. Setting a
+// mode other than NumericAuto on a session makes requests check that
+// responses use the expected form, so a mismatch is reported as a
+// *NumericModeError instead of going unnoticed, and makes request bodies use
+// the same form.
+type NumericMode int
 
+const (
+	// NumericAuto accepts both forms, and writes strings. This is the
+	// default.
+	NumericAuto NumericMode = iota
+
+	// NumericStrings expects and writes strings, as used by PHP 7.
+	NumericStrings
+
+	// NumericNumbers expects and writes numbers, as used by PHP 8.
+	NumericNumbers
+)
+
+// String implements fmt.Stringer for NumericMode.
+func (m NumericMode) String() string {
+	switch m {
+	case NumericAuto:
+		return "auto"
+	case NumericStrings:
+		return "strings"
+	case NumericNumbers:
+		return "numbers"
+	}
+	return fmt.Sprintf("NumericMode(%d)", int(m))
+}
+
+// NumericModeForPHPVersion returns the NumericMode used by a PHPIPAM server
+// running the supplied PHP version (ie: 8.1.2). NumericAuto is returned if
+// the version is empty or not recognized.
+func NumericModeForPHPVersion(version string) NumericMode {
+	major, err := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+	switch {
+	case err != nil || major <= 0:
+		return NumericAuto
+	case major >= 8:
+		return NumericNumbers
+	}
+	return NumericStrings
+}
+
+// NumericModeError is returned when a JSONIntString or BoolIntString field in
+// a response does not use the form expected by the session's NumericMode.
+type NumericModeError struct {
+	// The JSON name of the field.
+	Field string
+
+	// The raw JSON value of the field.
+	Value string
+
+	// The mode that was expected.
+	Mode NumericMode
+}
+
+// Error implements error for NumericModeError.
+func (e *NumericModeError) Error() string {
+	return fmt.Sprintf("Field %s has value %s, which does not match numeric mode %s", e.Field, e.Value, e.Mode)
+}
+
+var (
+	jsonIntStringType = reflect.TypeOf(JSONIntString(0))
+	boolIntStringType = reflect.TypeOf(BoolIntString(false))
+)
+
+// numericFields returns the JSON names of the JSONIntString and BoolIntString
+// fields of v, which can be a struct, a map, or a pointer to either. For maps,
+// the dynamic types of the values are used.
+func numericFields(v reflect.Value) map[string]bool {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	out := make(map[string]bool)
+	switch v.Kind() {
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			e := v.MapIndex(k)
+			if e.Kind() == reflect.Interface && !e.IsNil() {
+				e = e.Elem()
+			}
+			if k.Kind() == reflect.String && (e.Type() == jsonIntStringType || e.Type() == boolIntStringType) {
+				out[k.String()] = true
+			}
+		}
+	case reflect.Struct:
+		for k := range numericStructFields(v.Type()) {
+			out[k] = true
+		}
+	}
+	return out
+}
+
+// numericStructFields returns the JSON names of the JSONIntString and
+// BoolIntString fields of the struct type t, including those of embedded
+// structs.
+func numericStructFields(t reflect.Type) map[string]bool {
+	out := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && name == "" {
+			for k := range numericStructFields(f.Type) {
+				out[k] = true
+			}
+			continue
+		}
+		if name == "-" || (f.Type != jsonIntStringType && f.Type != boolIntStringType) {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		out[name] = true
+	}
+	return out
+}
+
+// MarshalNumeric marshals v to JSON like json.Marshal, writing its top-level
+// JSONIntString and BoolIntString fields in the form for the supplied mode.
+// v can be a struct or a map, or a pointer to either.
+func MarshalNumeric(v interface{}, mode NumericMode) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil || mode != NumericNumbers {
+		return b, err
+	}
+	fields := numericFields(reflect.ValueOf(v))
+	if len(fields) == 0 {
+		return b, nil
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return b, nil
+	}
+	for k := range fields {
+		var s string
+		if err := json.Unmarshal(obj[k], &s); err != nil {
+			continue
+		}
+		if n, err := strconv.Atoi(s); err == nil {
+			obj[k] = json.RawMessage(strconv.Itoa(n))
+		}
+	}
+	return json.Marshal(obj)
+}
+
+// CheckNumeric checks that the JSONIntString and BoolIntString fields of v
+// have the form expected by the supplied mode in data, the JSON v was
+// unmarshaled from, returning a *NumericModeError for the first one that does
+// not. v can be a struct or a slice of structs, or a pointer to either. null
+// values are not checked, and nothing is checked for NumericAuto.
+func CheckNumeric(data []byte, v interface{}, mode NumericMode) error {
+	if mode == NumericAuto {
+		return nil
+	}
+	t := reflect.TypeOf(v)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	fields := numericStructFields(t)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	var objs []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &objs); err != nil {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return nil
+		}
+		objs = append(objs, obj)
+	}
+	names := make([]string, 0, len(fields))
+	for k := range fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, obj := range objs {
+		for _, k := range names {
+			raw := strings.TrimSpace(string(obj[k]))
+			if raw == "" || raw == "null" {
+				continue
+			}
+			if isString := raw[0] == '"'; isString != (mode == NumericStrings) {
+				return &NumericModeError{Field: k, Value: raw, Mode: mode}
+			}
+		}
+	}
 	return nil
 }
 
+// ListOptions contains the filtering and ordering options that can be supplied
+// to list methods that support them. These map to the filter_by,
+// filter_value, order_by, and order query parameters of the API.
+type ListOptions struct {
+	// The field to filter results by.
+	FilterBy string
+
+	// The value that FilterBy needs to match.
+	FilterValue string
+
+	// The field to order results by.
+	OrderBy string
+
+	// The order direction, either "asc" or "desc".
+	Order string
+}
+
+// Values returns the options as query parameters, for use with
+// client.SendRequestWithParams. Options that are not set are omitted.
+func (o ListOptions) Values() url.Values {
+	q := url.Values{}
+	if o.FilterBy != "" {
+		q.Set("filter_by", o.FilterBy)
+		q.Set("filter_value", o.FilterValue)
+	}
+	if o.OrderBy != "" {
+		q.Set("order_by", o.OrderBy)
+	}
+	if o.Order != "" {
+		q.Set("order", o.Order)
+	}
+	return q
+}
+
+// Query returns the options encoded as a query string, including the leading
+// "?", for appending to a request URI. An empty string is returned if no
+// options are set.
+func (o ListOptions) Query() string {
+	q := o.Values()
+	if len(q) == 0 {
+		return ""
+	}
+	return "?" + q.Encode()
+}
+
+// CustomFields is a map of custom field names to values, as carried by
+// resources that support custom fields.
+//
+// Depending on the PHP version of the server, numeric values may be decoded as
+// either strings or float64s. The typed accessors normalize across these
+// forms, and should be preferred over type assertions on the raw values.
+type CustomFields map[string]interface{}
+
+// GetString returns the value of the custom field key as a string. Numeric
+// and boolean values are formatted. ok is false if the field is not set, is
+// null, or is of another type.
+func (f CustomFields) GetString(key string) (value string, ok bool) {
+	switch v := f[key].(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case json.Number:
+		return v.String(), true
+	case int:
+		return strconv.Itoa(v), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// GetInt returns the value of the custom field key as an int. String values
+// are parsed. ok is false if the field is not set, or is not an integer.
+func (f CustomFields) GetInt(key string) (value int, ok bool) {
+	switch v := f[key].(type) {
+	case int:
+		return v, true
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), true
+		}
+	case string, json.Number:
+		i, err := strconv.Atoi(strings.TrimSpace(fmt.Sprint(v)))
+		if err == nil {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// GetFloat returns the value of the custom field key as a float64. String
+// values are parsed. ok is false if the field is not set, or is not a number.
+func (f CustomFields) GetFloat(key string) (value float64, ok bool) {
+	switch v := f[key].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string, json.Number:
+		n, err := strconv.ParseFloat(strings.TrimSpace(fmt.Sprint(v)), 64)
+		if err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// GetBool returns the value of the custom field key as a bool. The forms
+// accepted by BoolIntString are supported, such as "0" and "1". ok is false if
+// the field is not set, or is not a boolean.
+func (f CustomFields) GetBool(key string) (value bool, ok bool) {
+	v, set := f[key]
+	if !set || v == nil {
+		return false, false
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return false, false
+	}
+	var bis BoolIntString
+	if err := json.Unmarshal(b, &bis); err != nil {
+		return false, false
+	}
+	return bool(bis), true
+}
+
+// MultiError collects the errors from a batch operation, keyed by the ID of
+// the resource that each error relates to.
+type MultiError map[int]error
+
+// Error implements error for MultiError. The individual errors are listed in
+// ID order.
+func (e MultiError) Error() string {
+	ids := make([]int, 0, len(e))
+	for id := range e {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	msgs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		msgs = append(msgs, fmt.Sprintf("ID %d: %s", id, e[id]))
+	}
+	return fmt.Sprintf("%d error(s) occurred: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// PathIP validates an IPv4 or IPv6 address and returns it in canonical form,
+// escaped for use as a request path segment. Expanded IPv6 addresses are
+// compressed, so "2001:0db8:0000:0000:0000:0000:0000:0001" becomes
+// "2001:db8::1". An error is returned if the address is malformed.
+func PathIP(ip string) (string, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return "", fmt.Errorf("Invalid IP address: %q", ip)
+	}
+	return url.PathEscape(addr.String()), nil
+}
+
+// PathCIDR validates a CIDR such as "10.10.1.0/24" or "2001:db8::/64" and
+// returns it in canonical form for use in a request path, in the
+// address/mask layout that the API expects. The address is not masked to the
+// network address. An error is returned if the CIDR is malformed.
+//
+// IPv4-mapped IPv6 CIDRs (ie: "::ffff:10.0.0.0/120") are rejected, as the
+// address would be printed in its IPv4 form alongside an IPv6 mask.
+func PathCIDR(cidr string) (string, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", fmt.Errorf("Invalid CIDR: %q", cidr)
+	}
+	if len(network.IP) == net.IPv6len && network.IP.To4() != nil {
+		return "", fmt.Errorf("Invalid CIDR: %q, IPv4-mapped IPv6 addresses are not supported", cidr)
+	}
+	ip, err := PathIP(cidr[:strings.IndexByte(cidr, '/')])
+	if err != nil {
+		return "", err
+	}
+	ones, _ := network.Mask.Size()
+	return fmt.Sprintf("%s/%d", ip, ones), nil
+}
+
+// The access levels used in section and subnet permissions.
+const (
+	PermissionNone      = 0
+	PermissionRead      = 1
+	PermissionReadWrite = 2
+	PermissionAdmin     = 3
+)
+
+// ParsePermissions parses the stringified JSON permissions of a section or
+// subnet, returning a map of group IDs to access levels (ie:
+// PermissionRead). Both quoted and unquoted numbers are accepted. An empty
+// string, "null", "{}", and "[]" all parse to an empty map.
+func ParsePermissions(s string) (map[int]int, error) {
+	out := make(map[int]int)
+	switch strings.TrimSpace(s) {
+	case "", "null", "[]":
+		return out, nil
+	}
+	var raw map[string]JSONIntString
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return nil, fmt.Errorf("Error parsing permissions %q: %s", s, err)
+	}
+	for k, v := range raw {
+		id, err := strconv.Atoi(k)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing permissions %q: invalid group ID %q", s, k)
+		}
+		out[id] = int(v)
+	}
+	return out, nil
+}
+
+// FormatPermissions formats a map of group IDs to access levels as
+// stringified JSON, in the form used by the permissions field of sections and
+// subnets. It is the inverse of ParsePermissions.
+func FormatPermissions(perms map[int]int) string {
+	raw := make(map[string]string, len(perms))
+	for k, v := range perms {
+		raw[strconv.Itoa(k)] = strconv.Itoa(v)
+	}
+	// Marshaling a map of strings can't fail.
+	b, _ := json.Marshal(raw)
+	return string(b)
+}
+
+// ChangedFields compares two values of the same resource type via their JSON
+// encodings, and returns the fields set in want that differ in have, keyed by
+// their JSON names (ie: vlanId) with the values from want. The result can be
+// passed to a controller's patch method to bring have in line with want.
+//
+// As fields with zero values are generally omitted from the encoding of a
+// resource, they are treated as unset in want and never reported as changed.
+// The id field, and any fields named in ignore, are left out as well.
+func ChangedFields(want, have interface{}, ignore ...string) (map[string]interface{}, error) {
+	w, err := jsonFields(want)
+	if err != nil {
+		return nil, err
+	}
+	h, err := jsonFields(have)
+	if err != nil {
+		return nil, err
+	}
+
+	skip := map[string]bool{"id": true}
+	for _, k := range ignore {
+		skip[k] = true
+	}
+	out := make(map[string]interface{})
+	for k, v := range w {
+		if !skip[k] && !reflect.DeepEqual(v, h[k]) {
+			out[k] = v
+		}
+	}
+	return out, nil
+}
+
+// jsonFields returns the fields of the JSON encoding of v, keyed by name.
+func jsonFields(v interface{}) (out map[string]interface{}, err error) {
+	var bs []byte
+	if bs, err = json.Marshal(v); err != nil {
+		return
+	}
+	err = json.Unmarshal(bs, &out)
+	return
+}
+
+// DateTimeLayout is the layout of the datetimes returned by the API, such as
+// the EditDate of a resource. They are in the server's time zone.
+const DateTimeLayout = "2006-01-02 15:04:05"
+
+// ParseDateTime parses a datetime returned by the API, in the layout
+// DateTimeLayout, as a time in the supplied location. If loc is nil, the
+// server is assumed to be in the local time zone.
+//
+// ok is false, with a zero time and no error, if s is empty or is the
+// "0000-00-00 00:00:00" value PHPIPAM uses for times that are not set, such
+// as the last seen time of an address that has never been seen.
+func ParseDateTime(s string, loc *time.Location) (t time.Time, ok bool, err error) {
+	if s == "" || s == "0000-00-00 00:00:00" {
+		return
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	if t, err = time.ParseInLocation(DateTimeLayout, s, loc); err != nil {
+		return time.Time{}, false, err
+	}
+	return t, true, nil
+}
+
+// ChangelogEntry represents an entry in the changelog of a PHPIPAM resource,
+// such as a subnet or an address.
+type ChangelogEntry struct {
+	// The name of the user that made the change.
+	User string `json:"user,omitempty"`
+
+	// The action performed (ie: add, edit, or delete).
+	Action string `json:"action,omitempty"`
+
+	// The result of the action (ie: success).
+	Result string `json:"result,omitempty"`
+
+	// The date of the change. This is left as returned by the API.
+	Date string `json:"date,omitempty"`
+
+	// The changed fields, keyed by field name.
+	Diff map[string]interface{} `json:"diff,omitempty"`
+}
+
 // CustomField represents a PHPIPAM custom field schema entry.
 //
 // Custom fields are currently embedded in a resource's table (such as subnets
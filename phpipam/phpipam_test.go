@@ -2,8 +2,12 @@ package phpipam
 
 import (
 	"encoding/json"
+	"errors"
+	"net/url"
 	"os"
+	"reflect"
 	"testing"
+	"time"
 )
 
 const testBoolIntStringJSONTrue = `{"foo":"1"}`
@@ -27,6 +31,10 @@ type testJSONIntStringTypeOmitEmpty struct {
 	Foo JSONIntString `json:"foo,omitempty"`
 }
 
+type testJSONFloatStringType struct {
+	Foo JSONFloatString `json:"foo"`
+}
+
 func setPHPIPAMenv() {
 	os.Setenv("PHPIPAM_APP_ID", "foobar")
 	os.Setenv("PHPIPAM_ENDPOINT_ADDR", "https://example.com/phpipam/api")
@@ -102,10 +110,51 @@ func TestBoolIntStringUnmarshalJSONError(t *testing.T) {
 		t.Fatalf("Expected error, got none")
 	}
 
-	expected := "json: cannot unmarshal bool into Go struct field testBoolIntStringType.foo of type string"
-	actual := err.Error()
-	if expected != actual {
-		t.Fatalf("Expected %s, got %s", expected, actual)
+	var e *json.UnmarshalTypeError
+	if !errors.As(err, &e) {
+		t.Fatalf("Expected *json.UnmarshalTypeError, got %#v", err)
+	}
+}
+
+func TestBoolIntStringUnmarshalJSONForms(t *testing.T) {
+	cases := []struct {
+		JSON     string
+		Expected BoolIntString
+	}{
+		{JSON: `{"foo":true}`, Expected: true},
+		{JSON: `{"foo":false}`, Expected: false},
+		{JSON: `{"foo":"true"}`, Expected: true},
+		{JSON: `{"foo":"false"}`, Expected: false},
+		{JSON: `{"foo":1}`, Expected: true},
+		{JSON: `{"foo":0}`, Expected: false},
+		{JSON: `{"foo":"1"}`, Expected: true},
+		{JSON: `{"foo":"0"}`, Expected: false},
+		{JSON: `{"foo":""}`, Expected: false},
+		{JSON: `{"foo":null}`, Expected: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.JSON, func(t *testing.T) {
+			var actual testBoolIntStringType
+			if err := json.Unmarshal([]byte(tc.JSON), &actual); err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+			if actual.Foo != tc.Expected {
+				t.Fatalf("Expected value to be %t, got %t", tc.Expected, actual.Foo)
+			}
+
+			b, err := json.Marshal(actual)
+			if err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+			expected := testBoolIntStringJSONFalse
+			if tc.Expected {
+				expected = testBoolIntStringJSONTrue
+			}
+			if string(b) != expected {
+				t.Fatalf("Expected %s, got %s", expected, b)
+			}
+		})
 	}
 }
 
@@ -176,10 +225,35 @@ func TestJSONIntStringUnmarshalJSONError(t *testing.T) {
 		t.Fatalf("Expected error, got none")
 	}
 
-	expected := "json: cannot unmarshal int into Go struct field testJSONIntStringType.foo of type string"
-	actual := err.Error()
-	if expected != actual {
-		t.Fatalf("Expected %s, got %s", expected, actual)
+	var e *json.UnmarshalTypeError
+	if !errors.As(err, &e) {
+		t.Fatalf("Expected *json.UnmarshalTypeError, got %#v", err)
+	}
+}
+
+func TestJSONIntStringUnmarshalJSONForms(t *testing.T) {
+	cases := []struct {
+		JSON     string
+		Expected JSONIntString
+	}{
+		{JSON: `{"foo":null}`, Expected: 0},
+		{JSON: `{"foo":""}`, Expected: 0},
+		{JSON: `{"foo":"0"}`, Expected: 0},
+		{JSON: `{"foo":"2"}`, Expected: 2},
+		{JSON: `{"foo":0}`, Expected: 0},
+		{JSON: `{"foo":2}`, Expected: 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.JSON, func(t *testing.T) {
+			var actual testJSONIntStringType
+			if err := json.Unmarshal([]byte(tc.JSON), &actual); err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+			if actual.Foo != tc.Expected {
+				t.Fatalf("Expected value to be %d, got %d", tc.Expected, actual.Foo)
+			}
+		})
 	}
 }
 
@@ -212,3 +286,471 @@ func TestJSONIntStringMarshalJSONOmitEmpty(t *testing.T) {
 		t.Fatalf("Expected %s, got %s", expected, actual)
 	}
 }
+
+func TestJSONFloatStringUnmarshalJSON(t *testing.T) {
+	cases := []struct {
+		JSON     string
+		Expected JSONFloatString
+	}{
+		{JSON: `{"foo":null}`, Expected: 0},
+		{JSON: `{"foo":""}`, Expected: 0},
+		{JSON: `{"foo":"12.5"}`, Expected: 12.5},
+		{JSON: `{"foo":12.5}`, Expected: 12.5},
+		{JSON: `{"foo":"100"}`, Expected: 100},
+		{JSON: `{"foo":0}`, Expected: 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.JSON, func(t *testing.T) {
+			var actual testJSONFloatStringType
+			if err := json.Unmarshal([]byte(tc.JSON), &actual); err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+			if actual.Foo != tc.Expected {
+				t.Fatalf("Expected value to be %g, got %g", tc.Expected, actual.Foo)
+			}
+		})
+	}
+}
+
+func TestJSONFloatStringUnmarshalJSONError(t *testing.T) {
+	var v testJSONFloatStringType
+	err := json.Unmarshal([]byte(`{"foo":"a"}`), &v)
+	if err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+
+	var e *json.UnmarshalTypeError
+	if !errors.As(err, &e) {
+		t.Fatalf("Expected *json.UnmarshalTypeError, got %#v", err)
+	}
+}
+
+func TestJSONFloatStringMarshalJSON(t *testing.T) {
+	v := testJSONFloatStringType{
+		Foo: 12.5,
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	expected := `{"foo":"12.5"}`
+	actual := string(b)
+	if expected != actual {
+		t.Fatalf("Expected %s, got %s", expected, actual)
+	}
+}
+
+// testNumericType is a resource with numeric fields, for the NumericMode
+// tests.
+type testNumericType struct {
+	ID       int           `json:"id,string,omitempty"`
+	Name     string        `json:"name,omitempty"`
+	VLANID   JSONIntString `json:"vlanId,omitempty"`
+	IsFolder BoolIntString `json:"isFolder,omitempty"`
+}
+
+func TestNumericModeForPHPVersion(t *testing.T) {
+	cases := map[string]NumericMode{
+		"":       NumericAuto,
+		"foo":    NumericAuto,
+		"5.6.40": NumericStrings,
+		"7.4.33": NumericStrings,
+		"8.1.2":  NumericNumbers,
+		"8":      NumericNumbers,
+	}
+	for version, expected := range cases {
+		if actual := NumericModeForPHPVersion(version); actual != expected {
+			t.Fatalf("%q: expected %s, got %s", version, expected, actual)
+		}
+	}
+}
+
+func TestMarshalNumeric(t *testing.T) {
+	in := &testNumericType{ID: 3, Name: "12", VLANID: 5, IsFolder: true}
+	fields := map[string]interface{}{"id": 3, "vlanId": JSONIntString(5), "name": "12"}
+	cases := []struct {
+		name     string
+		in       interface{}
+		mode     NumericMode
+		expected string
+	}{
+		{name: "struct auto", in: in, mode: NumericAuto, expected: `{"id":"3","name":"12","vlanId":"5","isFolder":"1"}`},
+		{name: "struct strings", in: in, mode: NumericStrings, expected: `{"id":"3","name":"12","vlanId":"5","isFolder":"1"}`},
+		{name: "struct numbers", in: in, mode: NumericNumbers, expected: `{"id":"3","isFolder":1,"name":"12","vlanId":5}`},
+		{name: "map numbers", in: fields, mode: NumericNumbers, expected: `{"id":3,"name":"12","vlanId":5}`},
+		{name: "empty numbers", in: &struct{}{}, mode: NumericNumbers, expected: `{}`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := MarshalNumeric(tc.in, tc.mode)
+			if err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+			if string(b) != tc.expected {
+				t.Fatalf("Expected %s, got %s", tc.expected, b)
+			}
+		})
+	}
+}
+
+func TestCheckNumeric(t *testing.T) {
+	stringsJSON := `{"id":"3","name":"12","vlanId":"5","isFolder":"1"}`
+	numbersJSON := `{"id":3,"name":"12","vlanId":5,"isFolder":1}`
+	cases := []struct {
+		name     string
+		data     string
+		out      interface{}
+		mode     NumericMode
+		expected *NumericModeError
+	}{
+		{name: "auto", data: numbersJSON, out: &testNumericType{}, mode: NumericAuto},
+		{name: "strings", data: stringsJSON, out: &testNumericType{}, mode: NumericStrings},
+		{name: "numbers", data: numbersJSON, out: &testNumericType{}, mode: NumericNumbers},
+		{name: "null", data: `{"vlanId":null}`, out: &testNumericType{}, mode: NumericNumbers},
+		{
+			name:     "strings mismatch",
+			data:     numbersJSON,
+			out:      &testNumericType{},
+			mode:     NumericStrings,
+			expected: &NumericModeError{Field: "isFolder", Value: "1", Mode: NumericStrings},
+		},
+		{
+			name:     "numbers mismatch in list",
+			data:     `[` + numbersJSON + `,{"vlanId":"5"}]`,
+			out:      &[]testNumericType{},
+			mode:     NumericNumbers,
+			expected: &NumericModeError{Field: "vlanId", Value: `"5"`, Mode: NumericNumbers},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckNumeric([]byte(tc.data), tc.out, tc.mode)
+			if tc.expected == nil {
+				if err != nil {
+					t.Fatalf("Bad: %s", err)
+				}
+				return
+			}
+			var e *NumericModeError
+			if !errors.As(err, &e) || !reflect.DeepEqual(tc.expected, e) {
+				t.Fatalf("Expected %#v, got %#v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestListOptionsQuery(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Options  ListOptions
+		Expected string
+	}{
+		{Name: "empty", Options: ListOptions{}, Expected: ""},
+		{Name: "filter", Options: ListOptions{FilterBy: "isFolder", FilterValue: "0"}, Expected: "?filter_by=isFolder&filter_value=0"},
+		{Name: "order", Options: ListOptions{OrderBy: "description", Order: "desc"}, Expected: "?order=desc&order_by=description"},
+		{Name: "escaped", Options: ListOptions{FilterBy: "description", FilterValue: "a b&c"}, Expected: "?filter_by=description&filter_value=a+b%26c"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if actual := tc.Options.Query(); actual != tc.Expected {
+				t.Fatalf("Expected %s, got %s", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestListOptionsValues(t *testing.T) {
+	opts := ListOptions{FilterBy: "isFolder", FilterValue: "0", OrderBy: "description"}
+	expected := url.Values{"filter_by": {"isFolder"}, "filter_value": {"0"}, "order_by": {"description"}}
+	if actual := opts.Values(); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+	if actual := (ListOptions{}).Values(); len(actual) != 0 {
+		t.Fatalf("Expected no values, got %#v", actual)
+	}
+}
+
+func TestCustomFieldsAccessors(t *testing.T) {
+	fields := CustomFields{
+		"php7Int":   "42",
+		"php8Int":   float64(42),
+		"php7Float": "1.5",
+		"php8Float": float64(1.5),
+		"text":      "foo",
+		"php7Bool":  "1",
+		"php8Bool":  float64(0),
+		"empty":     "",
+		"null":      nil,
+	}
+
+	strCases := []struct {
+		key      string
+		expected string
+		ok       bool
+	}{
+		{key: "php7Int", expected: "42", ok: true},
+		{key: "php8Int", expected: "42", ok: true},
+		{key: "php8Float", expected: "1.5", ok: true},
+		{key: "text", expected: "foo", ok: true},
+		{key: "null"},
+		{key: "missing"},
+	}
+	for _, tc := range strCases {
+		if actual, ok := fields.GetString(tc.key); actual != tc.expected || ok != tc.ok {
+			t.Fatalf("GetString(%q): expected %q, %t, got %q, %t", tc.key, tc.expected, tc.ok, actual, ok)
+		}
+	}
+
+	intCases := []struct {
+		key      string
+		expected int
+		ok       bool
+	}{
+		{key: "php7Int", expected: 42, ok: true},
+		{key: "php8Int", expected: 42, ok: true},
+		{key: "php7Float"},
+		{key: "php8Float"},
+		{key: "text"},
+		{key: "empty"},
+		{key: "missing"},
+	}
+	for _, tc := range intCases {
+		if actual, ok := fields.GetInt(tc.key); actual != tc.expected || ok != tc.ok {
+			t.Fatalf("GetInt(%q): expected %d, %t, got %d, %t", tc.key, tc.expected, tc.ok, actual, ok)
+		}
+	}
+
+	floatCases := []struct {
+		key      string
+		expected float64
+		ok       bool
+	}{
+		{key: "php7Int", expected: 42, ok: true},
+		{key: "php7Float", expected: 1.5, ok: true},
+		{key: "php8Float", expected: 1.5, ok: true},
+		{key: "text"},
+		{key: "missing"},
+	}
+	for _, tc := range floatCases {
+		if actual, ok := fields.GetFloat(tc.key); actual != tc.expected || ok != tc.ok {
+			t.Fatalf("GetFloat(%q): expected %f, %t, got %f, %t", tc.key, tc.expected, tc.ok, actual, ok)
+		}
+	}
+
+	boolCases := []struct {
+		key      string
+		expected bool
+		ok       bool
+	}{
+		{key: "php7Bool", expected: true, ok: true},
+		{key: "php8Bool", expected: false, ok: true},
+		{key: "empty", expected: false, ok: true},
+		{key: "text"},
+		{key: "null"},
+		{key: "missing"},
+	}
+	for _, tc := range boolCases {
+		if actual, ok := fields.GetBool(tc.key); actual != tc.expected || ok != tc.ok {
+			t.Fatalf("GetBool(%q): expected %t, %t, got %t, %t", tc.key, tc.expected, tc.ok, actual, ok)
+		}
+	}
+}
+
+func TestParsePermissions(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected map[int]int
+		err      bool
+	}{
+		{in: `{"3":"1","2":"2"}`, expected: map[int]int{3: PermissionRead, 2: PermissionReadWrite}},
+		{in: `{"3":1,"4":3}`, expected: map[int]int{3: PermissionRead, 4: PermissionAdmin}},
+		{in: "", expected: map[int]int{}},
+		{in: "null", expected: map[int]int{}},
+		{in: "{}", expected: map[int]int{}},
+		{in: "[]", expected: map[int]int{}},
+		{in: `{"foo":"1"}`, err: true},
+		{in: `{"3":"x"}`, err: true},
+		{in: `{"3":`, err: true},
+	}
+
+	for _, tc := range cases {
+		actual, err := ParsePermissions(tc.in)
+		if tc.err {
+			if err == nil {
+				t.Fatalf("%q: expected error, got %#v", tc.in, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: Bad: %s", tc.in, err)
+		}
+		if !reflect.DeepEqual(tc.expected, actual) {
+			t.Fatalf("%q: expected %#v, got %#v", tc.in, tc.expected, actual)
+		}
+	}
+}
+
+func TestFormatPermissions(t *testing.T) {
+	in := map[int]int{3: PermissionRead, 2: PermissionReadWrite}
+	expected := `{"2":"2","3":"1"}`
+	actual := FormatPermissions(in)
+	if actual != expected {
+		t.Fatalf("Expected %s, got %s", expected, actual)
+	}
+
+	parsed, err := ParsePermissions(actual)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if !reflect.DeepEqual(in, parsed) {
+		t.Fatalf("Expected %#v, got %#v", in, parsed)
+	}
+}
+
+func TestMultiErrorError(t *testing.T) {
+	err := MultiError{
+		3: errors.New("bar"),
+		1: errors.New("foo"),
+	}
+
+	expected := "2 error(s) occurred: ID 1: foo; ID 3: bar"
+	if actual := err.Error(); actual != expected {
+		t.Fatalf("Expected %s, got %s", expected, actual)
+	}
+}
+
+func TestPathIP(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected string
+		err      bool
+	}{
+		{in: "10.10.1.10", expected: "10.10.1.10"},
+		{in: "2001:db8::1", expected: "2001:db8::1"},
+		{in: "2001:0db8:0000:0000:0000:0000:0000:0001", expected: "2001:db8::1"},
+		{in: "2001:DB8::1", expected: "2001:db8::1"},
+		{in: "", err: true},
+		{in: "10.10.1", err: true},
+		{in: "10.10.1.10/24", err: true},
+		{in: "2001:db8:::1", err: true},
+		{in: "fe80::1%eth0", err: true},
+	}
+
+	for _, tc := range cases {
+		actual, err := PathIP(tc.in)
+		if tc.err {
+			if err == nil {
+				t.Fatalf("%q: expected error, got %q", tc.in, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: Bad: %s", tc.in, err)
+		}
+		if actual != tc.expected {
+			t.Fatalf("%q: expected %q, got %q", tc.in, tc.expected, actual)
+		}
+	}
+}
+
+func TestPathCIDR(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected string
+		err      bool
+	}{
+		{in: "10.10.1.0/24", expected: "10.10.1.0/24"},
+		{in: "10.10.1.10/24", expected: "10.10.1.10/24"},
+		{in: "2001:db8::/64", expected: "2001:db8::/64"},
+		{in: "2001:0db8:0000:0000:0000:0000:0000:0000/64", expected: "2001:db8::/64"},
+		{in: "10.10.1.0", err: true},
+		{in: "10.10.1.0/33", err: true},
+		{in: "2001:db8::/129", err: true},
+		{in: "foo/24", err: true},
+		{in: "::ffff:10.0.0.0/120", err: true},
+		{in: "::ffff:0:0/96", err: true},
+	}
+
+	for _, tc := range cases {
+		actual, err := PathCIDR(tc.in)
+		if tc.err {
+			if err == nil {
+				t.Fatalf("%q: expected error, got %q", tc.in, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: Bad: %s", tc.in, err)
+		}
+		if actual != tc.expected {
+			t.Fatalf("%q: expected %q, got %q", tc.in, tc.expected, actual)
+		}
+	}
+}
+
+func TestParseDateTime(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	cases := []struct {
+		in        string
+		expected  time.Time
+		ok        bool
+		expectErr bool
+	}{
+		{in: "2023-01-02 15:04:05", expected: time.Date(2023, 1, 2, 15, 4, 5, 0, loc), ok: true},
+		{in: ""},
+		{in: "0000-00-00 00:00:00"},
+		{in: "2023-01-02T15:04:05Z", expectErr: true},
+	}
+
+	for _, tc := range cases {
+		actual, ok, err := ParseDateTime(tc.in, loc)
+		if tc.expectErr != (err != nil) {
+			t.Fatalf("%q: expected error %t, got %v", tc.in, tc.expectErr, err)
+		}
+		if ok != tc.ok || !actual.Equal(tc.expected) {
+			t.Fatalf("%q: expected %s (%t), got %s (%t)", tc.in, tc.expected, tc.ok, actual, ok)
+		}
+	}
+
+	actual, _, err := ParseDateTime("2023-01-02 15:04:05", nil)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if actual.Location() != time.Local {
+		t.Fatalf("Expected local time, got %s", actual.Location())
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	type resource struct {
+		ID          int           `json:"id,string,omitempty"`
+		Name        string        `json:"name,omitempty"`
+		Description string        `json:"description,omitempty"`
+		VLANID      JSONIntString `json:"vlanId,omitempty"`
+		Flag        BoolIntString `json:"flag,omitempty"`
+	}
+
+	want := resource{ID: 1, Name: "foo", Description: "new", VLANID: 3, Flag: true}
+	have := resource{ID: 2, Name: "bar", Description: "old", VLANID: 3}
+
+	expected := map[string]interface{}{"description": "new", "flag": "1"}
+	actual, err := ChangedFields(want, have, "name")
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %#v, got %#v", expected, actual)
+	}
+
+	actual, err = ChangedFields(resource{Name: "bar"}, have)
+	if err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+	if len(actual) != 0 {
+		t.Fatalf("Expected no changes, got %#v", actual)
+	}
+}
@@ -2,14 +2,22 @@
 package request
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 
+	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
 )
 
@@ -28,6 +36,115 @@ type APIResponse struct {
 
 	// Whether or not the API request was successful.
 	Success bool
+
+	// The ID of the created resource, supplied by the API on some successful
+	// POST requests.
+	ID json.RawMessage
+}
+
+// APIError represents an error returned by the PHPIPAM API, or a non-API
+// error returned by the HTTP server in front of it.
+type APIError struct {
+	// The error code. For errors from the API, this is the code supplied in the
+	// response body. For non-API errors, this is the HTTP status code.
+	Code int
+
+	// The error message supplied by the API. This is empty for non-API errors.
+	Message string
+
+	// The HTTP status, with short-form message (ie: 404 Not Found).
+	Status string
+
+	// The raw response body.
+	Body []byte
+
+	// The HTTP response headers.
+	Header http.Header
+
+	// true if the response body could not be parsed as an API response.
+	NonAPI bool
+}
+
+// Error implements error for APIError.
+func (e *APIError) Error() string {
+	if e.NonAPI {
+		return fmt.Sprintf("Non-API error (%s): %s", e.Status, e.Body)
+	}
+	return fmt.Sprintf("Error from API (%d): %s", e.Code, e.Message)
+}
+
+// hasCode returns true if err is an *APIError with the supplied code.
+func hasCode(err error, code int) bool {
+	var e *APIError
+	return errors.As(err, &e) && e.Code == code
+}
+
+// IsNotFound returns true if err is an *APIError indicating that the
+// requested resource was not found.
+func IsNotFound(err error) bool {
+	return hasCode(err, http.StatusNotFound)
+}
+
+// IsConflict returns true if err is an *APIError indicating that the request
+// conflicts with an existing resource.
+func IsConflict(err error) bool {
+	return hasCode(err, http.StatusConflict)
+}
+
+// IsUnauthorized returns true if err is an *APIError indicating that the
+// request was not authorized.
+func IsUnauthorized(err error) bool {
+	return hasCode(err, http.StatusUnauthorized)
+}
+
+// ProtocolError is returned when a request could not be completed at the HTTP
+// level, ie: when the endpoint is unreachable or the connection fails.
+type ProtocolError struct {
+	// The underlying error returned by the HTTP client.
+	Err error
+}
+
+// Error implements error for ProtocolError.
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("HTTP protocol error: %s", e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// IsProtocolError returns true if err is a *ProtocolError, indicating that the
+// API endpoint could not be reached.
+func IsProtocolError(err error) bool {
+	var e *ProtocolError
+	return errors.As(err, &e)
+}
+
+// DryRunError is returned in place of sending a write request when the
+// session's DryRun flag is set. It carries the request that would have been
+// sent.
+type DryRunError struct {
+	// The request method.
+	Method string
+
+	// The request URI, relative to the app ID.
+	URI string
+
+	// The JSON request body.
+	Body []byte
+}
+
+// Error implements error for DryRunError.
+func (e *DryRunError) Error() string {
+	return fmt.Sprintf("Dry run: %s %s not sent: %s", e.Method, e.URI, e.Body)
+}
+
+// IsDryRun returns true if err is a *DryRunError, indicating that a write
+// request was not sent because the session is in dry run mode.
+func IsDryRun(err error) bool {
+	var e *DryRunError
+	return errors.As(err, &e)
 }
 
 // Request represents the API request.
@@ -47,6 +164,28 @@ type Request struct {
 	// The output of the request. This corresponds to the "data" field in a
 	// response.
 	Output interface{}
+
+	// The ID of the resource created by the request. This is set after a
+	// successful request from the "id" field of the response, or failing
+	// that, the last path element of the Location header. It is zero if
+	// neither are present.
+	CreatedID int
+
+	// If true, the data of a successful response is decoded into Output as
+	// the response body is read, instead of the body being read into memory
+	// and decoded afterwards. This keeps memory use down for large list
+	// requests. Streamed responses are not logged, are not checked against
+	// the session's NumericMode, and do not set CreatedID. Error responses
+	// are still read in full.
+	Stream bool
+
+	// The HTTP response to the request. This is set once a response has been
+	// received, including responses carrying an API error, and can be used to
+	// read headers that the SDK does not otherwise expose. The original body
+	// has already been read and closed - Body is replaced with a reader over
+	// a copy of it, which can be read again. For streamed responses, Body is
+	// replaced with an empty reader instead.
+	Response *http.Response
 }
 
 // requestResponse is an unexported struct that encompasses status codes
@@ -59,6 +198,9 @@ type requestResponse struct {
 	// Status code with short-form message.
 	Status string
 
+	// Response headers.
+	Header http.Header
+
 	// Response body.
 	Body []byte
 }
@@ -69,42 +211,246 @@ func (r *requestResponse) BodyString() string {
 	return buf.String()
 }
 
+// envelope decodes the response body as the APIResponse envelope that
+// PHPIPAM wraps responses in. ok is false if the body is not wrapped in an
+// envelope, such as the bare arrays and objects returned by some endpoints
+// and PHPIPAM versions, in which case the body is the response data itself.
+//
+// A body is treated as an envelope if it is a JSON object with a success
+// field, or with a code field along with a data or message field. The code
+// and success fields are accepted in both their string and JSON forms, and if
+// there is no success field, the request is successful if the code is below
+// 300.
+func (r *requestResponse) envelope() (resp APIResponse, ok bool, err error) {
+	body := bytes.TrimSpace(r.Body)
+	if len(body) == 0 || body[0] != '{' {
+		return
+	}
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(body, &fields); err != nil {
+		return
+	}
+	return envelopeFields(fields)
+}
+
+// envelopeFields builds the APIResponse envelope from the top-level fields of
+// a JSON object response body, as per envelope.
+func envelopeFields(fields map[string]json.RawMessage) (resp APIResponse, ok bool, err error) {
+	_, hasSuccess := fields["success"]
+	_, hasCode := fields["code"]
+	_, hasData := fields["data"]
+	_, hasMessage := fields["message"]
+	if !hasSuccess && !(hasCode && (hasData || hasMessage)) {
+		return
+	}
+
+	var code phpipam.JSONIntString
+	var success phpipam.BoolIntString
+	for k, v := range map[string]interface{}{"code": &code, "success": &success, "message": &resp.Message} {
+		if raw, found := fields[k]; found {
+			if err = json.Unmarshal(raw, v); err != nil {
+				return
+			}
+		}
+	}
+	resp.Code = int(code)
+	resp.Success = bool(success)
+	resp.Data = fields["data"]
+	resp.ID = fields["id"]
+	if !hasSuccess {
+		resp.Success = resp.Code < 300
+	}
+	return resp, true, nil
+}
+
+// streamResponseJSON decodes the body of a response with a successful status
+// code into v as it is read, for requests with Stream set. The same response
+// shapes are accepted as with ReadResponseJSON: the data field of an envelope
+// is decoded straight from the body, and bare arrays are decoded as a whole.
+// Any other top-level fields, including the data field of an object that
+// turns out not to be an envelope, are small and are collected as they are
+// read. If the envelope reports an error, an *APIError is returned, without
+// the body.
+func streamResponseJSON(re *http.Response, v interface{}) error {
+	br := bufio.NewReader(re.Body)
+	first, err := peekNonSpace(br)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error reading response body: %s", err)
+	}
+	dec := json.NewDecoder(br)
+	if first != '{' {
+		if err := dec.Decode(v); err != nil {
+			return fmt.Errorf("JSON parsing error: %s", err)
+		}
+		return nil
+	}
+
+	fields := make(map[string]json.RawMessage)
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("JSON parsing error: %s", err)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("JSON parsing error: %s", err)
+		}
+		key, _ := tok.(string)
+		_, hasSuccess := fields["success"]
+		_, hasCode := fields["code"]
+		if key == "data" && (hasSuccess || hasCode) {
+			if err := dec.Decode(v); err != nil {
+				return fmt.Errorf("JSON parsing error: %s", err)
+			}
+			// Mark the data as present, without holding on to it.
+			fields[key] = nil
+			continue
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return fmt.Errorf("JSON parsing error: %s", err)
+		}
+		fields[key] = raw
+	}
+
+	resp, ok, err := envelopeFields(fields)
+	if err != nil {
+		return fmt.Errorf("JSON parsing error: %s", err)
+	}
+	if !ok {
+		// A bare object, which is a single resource and small enough to be
+		// decoded from the collected fields.
+		bs, err := json.Marshal(fields)
+		if err != nil {
+			return fmt.Errorf("JSON parsing error: %s", err)
+		}
+		if err := json.Unmarshal(bs, v); err != nil {
+			return fmt.Errorf("JSON parsing error: %s", err)
+		}
+		return nil
+	}
+	if !resp.Success {
+		code := resp.Code
+		if code == 0 {
+			code = re.StatusCode
+		}
+		return &APIError{
+			Code:    code,
+			Message: resp.Message,
+			Status:  re.Status,
+			Header:  re.Header,
+		}
+	}
+	if len(resp.Data) > 0 {
+		if err := json.Unmarshal(resp.Data, v); err != nil {
+			return fmt.Errorf("JSON parsing error: %s", err)
+		}
+	}
+	return nil
+}
+
+// peekNonSpace returns the first byte in br that is not JSON whitespace,
+// without consuming it.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			br.ReadByte()
+		default:
+			return b[0], nil
+		}
+	}
+}
+
 // readResponseJSON reads a "successful" response body as JSON into variable
 // pointed to by v.
 //
-// First the main HTTP response is unmarshalled. If the request at that point
+// First the response envelope is unmarshalled. If the request at that point
 // failed according to the success field, the request is handed off to
 // handleError and the resulting error message is returned. Otherwise, the
-// request is successful and the response data is unmarshalled.
-func (r *requestResponse) ReadResponseJSON(v interface{}) error {
-	var resp APIResponse
-	if err := json.Unmarshal(r.Body, &resp); err != nil {
+// request is successful and the response data is unmarshalled. If the body is
+// not wrapped in an envelope, it is unmarshalled as the response data as-is.
+// The data is then checked against the supplied numeric mode via
+// phpipam.CheckNumeric.
+func (r *requestResponse) ReadResponseJSON(v interface{}, mode phpipam.NumericMode) error {
+	resp, ok, err := r.envelope()
+	if err != nil {
 		return fmt.Errorf("JSON parsing error: %s - Response body: %s", err, r.Body)
 	}
 
-	if !resp.Success {
-		return r.handleError()
+	data := json.RawMessage(bytes.TrimSpace(r.Body))
+	if ok {
+		if !resp.Success {
+			return r.handleError()
+		}
+		data = resp.Data
 	}
 
-	if string(resp.Data) != "" {
-		if err := json.Unmarshal(resp.Data, v); err != nil {
-			return fmt.Errorf("JSON parsing error: %s - Response data: %s", err, string(resp.Data))
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("JSON parsing error: %s - Response data: %s", err, string(data))
 		}
+		return phpipam.CheckNumeric(data, v, mode)
 	}
 	return nil
 }
 
-// handleError handles a PHPIPAM API error response.
+// createdID returns the ID of a created resource from the id field of the
+// response body, or the Location header, or zero if neither are present or
+// valid.
+func (r *requestResponse) createdID() int {
+	if resp, ok, err := r.envelope(); err == nil && ok && len(resp.ID) > 0 {
+		var s string
+		if err := json.Unmarshal(resp.ID, &s); err != nil {
+			s = string(resp.ID)
+		}
+		if id, err := strconv.Atoi(s); err == nil {
+			return id
+		}
+	}
+
+	parts := strings.Split(strings.Trim(r.Header.Get("Location"), "/"), "/")
+	if id, err := strconv.Atoi(parts[len(parts)-1]); err == nil {
+		return id
+	}
+	return 0
+}
+
+// handleError handles a PHPIPAM API error response, returning an *APIError.
+// If the body is not an API response envelope, the error is a non-API one.
 func (r *requestResponse) handleError() error {
-	var resp APIResponse
-	if err := json.Unmarshal(r.Body, &resp); err != nil {
+	resp, ok, err := r.envelope()
+	if err != nil || !ok {
 		// more than likely not JSON, just pull together the body and return it as
 		// the error message
-		return fmt.Errorf("Non-API error (%s): %s", r.Status, r.BodyString())
+		return &APIError{
+			Code:   r.StatusCode,
+			Status: r.Status,
+			Body:   r.Body,
+			Header: r.Header,
+			NonAPI: true,
+		}
 	}
 
-	// Return a properly formatted error from the appropraite fields.
-	return fmt.Errorf("Error from API (%d): %s", resp.Code, resp.Message)
+	// Return a properly formatted error from the appropraite fields. If the
+	// envelope has no code, the HTTP status code is used instead.
+	code := resp.Code
+	if code == 0 {
+		code = r.StatusCode
+	}
+	return &APIError{
+		Code:    code,
+		Message: resp.Message,
+		Status:  r.Status,
+		Body:    r.Body,
+		Header:  r.Header,
+	}
 }
 
 // newRequestResponse creates a new requestResponse instance off a HTTP
@@ -113,10 +459,10 @@ func newRequestResponse(r *http.Response) *requestResponse {
 	rr := &requestResponse{
 		StatusCode: r.StatusCode,
 		Status:     r.Status,
+		Header:     r.Header,
 	}
 	defer r.Body.Close()
 	body, err := ioutil.ReadAll(r.Body)
-	log.Printf("Response Body Debug ................... %s", body)
 	if err != nil {
 		panic(err)
 	}
@@ -124,35 +470,112 @@ func newRequestResponse(r *http.Response) *requestResponse {
 	return rr
 }
 
+// tokenPattern matches a token field in a JSON body, such as the one returned
+// by the user controller on login.
+var tokenPattern = regexp.MustCompile(`"token"\s*:\s*"[^"]*"`)
+
+// logf logs a debug message to the session's logger, or the standard logger
+// if the session does not have one.
+func (r *Request) logf(format string, v ...interface{}) {
+	if r.Session.Logger != nil {
+		r.Session.Logger.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
+
+// redact returns body with any session tokens removed, for logging.
+func (r *Request) redact(body []byte) []byte {
+	body = tokenPattern.ReplaceAll(body, []byte(`"token":"REDACTED"`))
+	if token := r.Session.CurrentToken().String; token != "" {
+		body = bytes.ReplaceAll(body, []byte(token), []byte("REDACTED"))
+	}
+	return body
+}
+
+// url returns the full URL for the request, composed from the session's
+// endpoint, base path, and app ID, and the request URI.
+func (r *Request) url() string {
+	base := strings.TrimSuffix(r.Session.Config.Endpoint, "/")
+	if p := strings.Trim(r.Session.Config.BasePath, "/"); p != "" {
+		base += "/" + p
+	}
+	return fmt.Sprintf("%s/%s%s", base, r.Session.Config.AppID, r.URI)
+}
+
+// userAgent returns the User-Agent header to send with the request.
+func (r *Request) userAgent() string {
+	if r.Session.UserAgent != "" {
+		return r.Session.UserAgent
+	}
+	return phpipam.DefaultUserAgent
+}
+
+// checkRedirect is the http.Client.CheckRedirect function used for all
+// requests. It stops redirects from being followed.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// httpClient returns the HTTP client to use for the request. This is the
+// session's Doer if set, or its HTTPClient, otherwise a default client is
+// built from the session's configuration and TLSConfig.
+func (r *Request) httpClient() session.Doer {
+	if r.Session.Doer != nil {
+		return r.Session.Doer
+	}
+	if r.Session.HTTPClient != nil {
+		client := *r.Session.HTTPClient
+		if client.CheckRedirect == nil {
+			client.CheckRedirect = checkRedirect
+		}
+		return &client
+	}
+
+	tlsConfig := &tls.Config{}
+	if r.Session.TLSConfig != nil {
+		tlsConfig = r.Session.TLSConfig.Clone()
+	}
+	if r.Session.Config.Insecure {
+		tlsConfig.InsecureSkipVerify = true
+	}
+	tr := &http.Transport{
+		TLSClientConfig: tlsConfig,
+	}
+	return &http.Client{
+		Transport:     tr,
+		CheckRedirect: checkRedirect,
+	}
+}
+
 // Send sends a request to the API endpoint, and parsees the response.
 //
 // Note that by design, Send does not handle redirects - if you get a 302 error
 // or some other sort of 300 error from the SDK, please check your API
 // endpoints.
 func (r *Request) Send() error {
+	return r.SendContext(context.Background())
+}
+
+// SendContext works like Send, but the request is bound to the supplied
+// context, allowing it to be cancelled or subject to a deadline.
+func (r *Request) SendContext(ctx context.Context) error {
+	r.Response = nil
 	var req *http.Request
 	var err error
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: r.Session.Config.Insecure},
-	}
-	client := &http.Client{
-		Transport: tr,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-	}
+	client := r.httpClient()
 
 	switch r.Method {
 	case "OPTIONS", "GET", "POST", "PUT", "PATCH", "DELETE":
-		bs, err := json.Marshal(r.Input)
-		log.Printf("Request Body Debug ................... %s", bs)
+		bs, err := phpipam.MarshalNumeric(r.Input, r.Session.NumericMode)
 		if err != nil {
 			return fmt.Errorf("Error preparing request data: %s", err)
 		}
+		r.logf("%s %s request body: %s", r.Method, r.URI, r.redact(bs))
 		buf := bytes.NewBuffer(bs)
-		log.Printf("Request URL Debug ...................Method: %s, UR: %s/%s%s", r.Method, r.Session.Config.Endpoint, r.Session.Config.AppID, r.URI)
-		req, err = http.NewRequest(r.Method, fmt.Sprintf("%s/%s%s", r.Session.Config.Endpoint, r.Session.Config.AppID, r.URI), buf)
+		req, err = http.NewRequestWithContext(ctx, r.Method, r.url(), buf)
 		req.Header.Add("Content-Type", "application/json")
+		req.Header.Set("User-Agent", r.userAgent())
 	default:
 		return fmt.Errorf("API request method %s not supported by PHPIPAM", r.Method)
 	}
@@ -161,23 +584,40 @@ func (r *Request) Send() error {
 		panic(err)
 	}
 
-	// Add session token if it exists, otherwise append username/password from the config.
+	// Add the static app code token or session token if it exists, otherwise
+	// append username/password from the config.
 	// Note that according to the PHPIPAM docs, Basic Auth does not work on
 	// anything else other than the user controller. Falling back to basic auth
 	// should only be used for setting up the session only.
-	if r.Session.Token.String != "" {
-		req.Header.Add("phpipam-token", r.Session.Token.String)
-	} else {
+	token := r.Session.CurrentToken().String
+	switch {
+	case r.Session.StaticToken:
+		req.Header.Add("token", token)
+	case token != "":
+		req.Header.Add("phpipam-token", token)
+	default:
 		req.SetBasicAuth(r.Session.Config.Username, r.Session.Config.Password)
 	}
 
 	re, err := client.Do(req)
 
 	if err != nil {
-		return fmt.Errorf("HTTP protocol error: %s", err)
+		return &ProtocolError{Err: err}
+	}
+
+	if r.Stream && re.StatusCode < 300 {
+		r.logf("%s %s response (%s): streamed", r.Method, r.URI, re.Status)
+		err := streamResponseJSON(re, r.Output)
+		re.Body.Close()
+		re.Body = ioutil.NopCloser(bytes.NewReader(nil))
+		r.Response = re
+		return err
 	}
 
 	resp := newRequestResponse(re)
+	re.Body = ioutil.NopCloser(bytes.NewReader(resp.Body))
+	r.Response = re
+	r.logf("%s %s response (%s): %s", r.Method, r.URI, resp.Status, r.redact(resp.Body))
 
 	// A response code of 300 or higher is an error. We do not handle redirects.
 	if resp.StatusCode >= 300 {
@@ -186,9 +626,10 @@ func (r *Request) Send() error {
 
 	// Unmarshal response into Output. The service is responsible for
 	// this being functional past JSON parsing.
-	if err := resp.ReadResponseJSON(r.Output); err != nil {
+	if err := resp.ReadResponseJSON(r.Output, r.Session.NumericMode); err != nil {
 		return err
 	}
+	r.CreatedID = resp.createdID()
 
 	return nil
 }
@@ -1,13 +1,19 @@
 package request
 
 import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam/session"
@@ -21,6 +27,14 @@ const errorResponseText = `
 }
 `
 
+const notFoundResponseText = `
+{
+  "code": 404,
+  "success": false,
+  "message": "No subnets found"
+}
+`
+
 const errorResponseNonJSONText = "<html><head><title>Service Unavailable</title></head><body><b>Service Unavailable</b></body></html>"
 
 const okResponseText = `
@@ -78,6 +92,23 @@ func httpOKTestServer() *httptest.Server {
 	})
 }
 
+func httpSlowTestServer() *httptest.Server {
+	return newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, okResponseText, http.StatusOK)
+	})
+}
+
+// testLogger is a session.Logger that records log output in a buffer.
+type testLogger struct {
+	bytes.Buffer
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	fmt.Fprintf(&l.Buffer, format+"\n", v...)
+}
+
 func phpipamConfig() phpipam.Config {
 	return phpipam.Config{
 		AppID:    "0123456789abcdefgh",
@@ -119,6 +150,241 @@ func TestRequestSendSuccess(t *testing.T) {
 	}
 }
 
+func TestRequestSendLogger(t *testing.T) {
+	ts := httpOKTestServer()
+	defer ts.Close()
+	cfg := phpipamConfig()
+	cfg.Endpoint = ts.URL
+	in := struct {
+		Name string `json:"name"`
+	}{Name: "foo"}
+	out := okAuthResponseData{}
+	r := testRequest(cfg, &in, &out)
+	logger := &testLogger{}
+	r.Session.Logger = logger
+	if err := r.Send(); err != nil {
+		t.Fatalf("Unexpected request error: %s", err)
+	}
+
+	actual := logger.String()
+	for _, expected := range []string{
+		`GET /api/test/users/ request body: {"name":"foo"}`,
+		`GET /api/test/users/ response (200 OK): `,
+		`"token":"REDACTED"`,
+	} {
+		if !strings.Contains(actual, expected) {
+			t.Fatalf("Expected log to contain %q, got %q", expected, actual)
+		}
+	}
+
+	for _, secret := range []string{"foobarbazboop", cfg.AppID} {
+		if strings.Contains(actual, secret) {
+			t.Fatalf("Expected log to not contain %q, got %q", secret, actual)
+		}
+	}
+}
+
+func TestRequestSendStream(t *testing.T) {
+	ts := httpOKTestServer()
+	defer ts.Close()
+	cfg := phpipamConfig()
+	cfg.Endpoint = ts.URL
+	out := okAuthResponseData{}
+	r := testRequest(cfg, &struct{}{}, &out)
+	r.Stream = true
+	logger := &testLogger{}
+	r.Session.Logger = logger
+	if err := r.Send(); err != nil {
+		t.Fatalf("Unexpected request error: %s", err)
+	}
+
+	if expected := okResponse(); !reflect.DeepEqual(expected, out) {
+		t.Fatalf("expected %v, got %v", expected, out)
+	}
+	if expected := "GET /api/test/users/ response (200 OK): streamed"; !strings.Contains(logger.String(), expected) {
+		t.Fatalf("Expected log to contain %q, got %q", expected, logger.String())
+	}
+	if r.Response == nil {
+		t.Fatalf("Expected response to be set")
+	}
+	if body, _ := ioutil.ReadAll(r.Response.Body); len(body) != 0 {
+		t.Fatalf("Expected empty response body, got %q", body)
+	}
+}
+
+func TestRequestSendCreatedID(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Body     string
+		Location string
+		Expected int
+	}{
+		{Name: "string id", Body: `{"code":201,"success":true,"id":"12","data":"created"}`, Expected: 12},
+		{Name: "number id", Body: `{"code":201,"success":true,"id":12,"data":"created"}`, Expected: 12},
+		{Name: "location", Body: `{"code":201,"success":true,"data":"created"}`, Location: "/api/test/vlans/13/", Expected: 13},
+		{Name: "none", Body: `{"code":201,"success":true,"data":"created"}`, Expected: 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Content-Type", "application/json")
+				if tc.Location != "" {
+					w.Header().Add("Location", tc.Location)
+				}
+				http.Error(w, tc.Body, http.StatusCreated)
+			})
+			defer ts.Close()
+			cfg := phpipamConfig()
+			cfg.Endpoint = ts.URL
+			var out string
+			r := testRequest(cfg, &struct{}{}, &out)
+			r.Method = "POST"
+			if err := r.Send(); err != nil {
+				t.Fatalf("Unexpected request error: %s", err)
+			}
+
+			if r.CreatedID != tc.Expected {
+				t.Fatalf("Expected %d, got %d", tc.Expected, r.CreatedID)
+			}
+		})
+	}
+}
+
+func TestRequestSendResponseShapes(t *testing.T) {
+	cases := []struct {
+		Name string
+		Body string
+	}{
+		{Name: "envelope", Body: okResponseText},
+		{Name: "envelope with string fields", Body: `{"code":"200","success":"1","data":{"token":"foobarbazboop","expires":"2017-03-03 00:56:34"}}`},
+		{Name: "envelope without success", Body: `{"code":200,"data":{"token":"foobarbazboop","expires":"2017-03-03 00:56:34"}}`},
+		{Name: "bare object", Body: `{"token":"foobarbazboop","expires":"2017-03-03 00:56:34"}`},
+		{Name: "bare array", Body: `[{"token":"foobarbazboop","expires":"2017-03-03 00:56:34"}]`},
+		{Name: "data before code", Body: `{"data":{"token":"foobarbazboop","expires":"2017-03-03 00:56:34"},"code":200,"success":true,"time":0.01}`},
+	}
+
+	for _, tc := range cases {
+		for _, stream := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/stream=%t", tc.Name, stream), func(t *testing.T) {
+				ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Add("Content-Type", "application/json")
+					http.Error(w, tc.Body, http.StatusOK)
+				})
+				defer ts.Close()
+				cfg := phpipamConfig()
+				cfg.Endpoint = ts.URL
+
+				var out okAuthResponseData
+				var outList []okAuthResponseData
+				r := testRequest(cfg, &struct{}{}, &out)
+				r.Stream = stream
+				if strings.HasPrefix(tc.Body, "[") {
+					r.Output = &outList
+				}
+				if err := r.Send(); err != nil {
+					t.Fatalf("Unexpected request error: %s", err)
+				}
+				if outList != nil {
+					if len(outList) != 1 {
+						t.Fatalf("Expected 1 item, got %#v", outList)
+					}
+					out = outList[0]
+				}
+
+				if expected := okResponse(); !reflect.DeepEqual(expected, out) {
+					t.Fatalf("expected %v, got %v", expected, out)
+				}
+			})
+		}
+	}
+}
+
+func TestRequestSendEnvelopeNotSuccessful(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Body     string
+		Expected string
+	}{
+		{Name: "success false", Body: `{"code":200,"success":false,"message":"No subnets found"}`, Expected: "Error from API (200): No subnets found"},
+		{Name: "string success", Body: `{"code":"404","success":"0","message":"No subnets found"}`, Expected: "Error from API (404): No subnets found"},
+		{Name: "code only", Body: `{"code":409,"message":"Subnet already exists"}`, Expected: "Error from API (409): Subnet already exists"},
+	}
+
+	for _, tc := range cases {
+		for _, stream := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/stream=%t", tc.Name, stream), func(t *testing.T) {
+				ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Add("Content-Type", "application/json")
+					http.Error(w, tc.Body, http.StatusOK)
+				})
+				defer ts.Close()
+				cfg := phpipamConfig()
+				cfg.Endpoint = ts.URL
+
+				var out okAuthResponseData
+				r := testRequest(cfg, &struct{}{}, &out)
+				r.Stream = stream
+				err := r.Send()
+				if err == nil || err.Error() != tc.Expected {
+					t.Fatalf("Expected error %q, got %v", tc.Expected, err)
+				}
+			})
+		}
+	}
+}
+
+func TestRequestSendNumericMode(t *testing.T) {
+	type resource struct {
+		VLANID phpipam.JSONIntString `json:"vlanId,omitempty"`
+	}
+	cases := []struct {
+		name         string
+		mode         phpipam.NumericMode
+		response     string
+		expectedBody string
+		expectErr    bool
+	}{
+		{name: "strings", mode: phpipam.NumericStrings, response: `{"vlanId":"5"}`, expectedBody: `{"vlanId":"5"}`},
+		{name: "numbers", mode: phpipam.NumericNumbers, response: `{"vlanId":5}`, expectedBody: `{"vlanId":5}`},
+		{name: "strings mismatch", mode: phpipam.NumericStrings, response: `{"vlanId":5}`, expectedBody: `{"vlanId":"5"}`, expectErr: true},
+		{name: "numbers mismatch", mode: phpipam.NumericNumbers, response: `{"vlanId":"5"}`, expectedBody: `{"vlanId":5}`, expectErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var body string
+			ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				body = string(b)
+				w.Header().Add("Content-Type", "application/json")
+				http.Error(w, `{"code":200,"success":true,"data":`+tc.response+`}`, http.StatusOK)
+			})
+			defer ts.Close()
+			cfg := phpipamConfig()
+			cfg.Endpoint = ts.URL
+
+			var out resource
+			r := testRequest(cfg, &resource{VLANID: 5}, &out)
+			r.Session.NumericMode = tc.mode
+			err := r.Send()
+			var e *phpipam.NumericModeError
+			if tc.expectErr != errors.As(err, &e) {
+				t.Fatalf("Expected numeric mode error %t, got %v", tc.expectErr, err)
+			}
+			if !tc.expectErr && err != nil {
+				t.Fatalf("Unexpected request error: %s", err)
+			}
+			if body != tc.expectedBody {
+				t.Fatalf("Expected body %s, got %s", tc.expectedBody, body)
+			}
+			if out.VLANID != 5 {
+				t.Fatalf("Expected VLAN ID 5, got %d", out.VLANID)
+			}
+		})
+	}
+}
+
 func TestRequestSendError(t *testing.T) {
 	ts := httpErrorTestServer()
 	defer ts.Close()
@@ -181,4 +447,185 @@ func TestRequestSendProtocolError(t *testing.T) {
 	if ok, _ := regexp.MatchString(expected, err.Error()); ok == false {
 		t.Fatalf("expected error to match %s, got %s", expected, err)
 	}
+
+	if !IsProtocolError(err) {
+		t.Fatalf("Expected protocol error, got %#v", err)
+	}
+}
+
+func TestRequestSendHTTPClientTimeout(t *testing.T) {
+	ts := httpSlowTestServer()
+	defer ts.Close()
+	cfg := phpipamConfig()
+	cfg.Endpoint = ts.URL
+	in := struct{}{}
+	out := okAuthResponseData{}
+	r := testRequest(cfg, &in, &out)
+	r.Session.HTTPClient = &http.Client{Timeout: 10 * time.Millisecond}
+	err := r.Send()
+
+	if err == nil {
+		t.Fatalf("Expected error, got success")
+	}
+
+	expected := "^HTTP protocol error"
+
+	if ok, _ := regexp.MatchString(expected, err.Error()); ok == false {
+		t.Fatalf("expected error to match %s, got %s", expected, err)
+	}
+}
+
+func TestRequestSendBasePath(t *testing.T) {
+	cases := []struct {
+		name         string
+		endpoint     string
+		basePath     string
+		expectedPath string
+	}{
+		{name: "no base path", expectedPath: "/0123456789abcdefgh/api/test/users/"},
+		{name: "base path", basePath: "/ipam/api", expectedPath: "/ipam/api/0123456789abcdefgh/api/test/users/"},
+		{name: "base path with slashes", endpoint: "/", basePath: "/ipam/api/", expectedPath: "/ipam/api/0123456789abcdefgh/api/test/users/"},
+		{name: "endpoint with path", endpoint: "/ipam", basePath: "api", expectedPath: "/ipam/api/0123456789abcdefgh/api/test/users/"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var path string
+			ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				w.Header().Add("Content-Type", "application/json")
+				http.Error(w, okResponseText, http.StatusOK)
+			})
+			defer ts.Close()
+			cfg := phpipamConfig()
+			cfg.Endpoint = ts.URL + tc.endpoint
+			cfg.BasePath = tc.basePath
+			in := struct{}{}
+			out := okAuthResponseData{}
+			r := testRequest(cfg, &in, &out)
+			if err := r.Send(); err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+
+			if path != tc.expectedPath {
+				t.Fatalf("Expected path %s, got %s", tc.expectedPath, path)
+			}
+		})
+	}
+}
+
+func TestRequestSendUserAgent(t *testing.T) {
+	cases := []struct {
+		name      string
+		userAgent string
+		expected  string
+	}{
+		{name: "default", expected: "phpipam-sdk-go/" + phpipam.Version},
+		{name: "custom", userAgent: "ipam-sync/2.3", expected: "ipam-sync/2.3"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var userAgent string
+			ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+				userAgent = r.Header.Get("User-Agent")
+				w.Header().Add("Content-Type", "application/json")
+				http.Error(w, okResponseText, http.StatusOK)
+			})
+			defer ts.Close()
+			cfg := phpipamConfig()
+			cfg.Endpoint = ts.URL
+			in := struct{}{}
+			out := okAuthResponseData{}
+			r := testRequest(cfg, &in, &out)
+			r.Session.UserAgent = tc.userAgent
+			if err := r.Send(); err != nil {
+				t.Fatalf("Bad: %s", err)
+			}
+
+			if userAgent != tc.expected {
+				t.Fatalf("Expected User-Agent %s, got %s", tc.expected, userAgent)
+			}
+		})
+	}
+}
+
+func TestRequestSendTLSConfig(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, okResponseText, http.StatusOK)
+	}))
+	defer ts.Close()
+	cfg := phpipamConfig()
+	cfg.Endpoint = ts.URL
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+
+	cases := []struct {
+		name      string
+		tlsConfig *tls.Config
+		insecure  bool
+		err       bool
+	}{
+		{name: "default verification", err: true},
+		{name: "custom root CA", tlsConfig: &tls.Config{RootCAs: pool}},
+		{name: "insecure", insecure: true},
+		{name: "insecure with custom config", tlsConfig: &tls.Config{}, insecure: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := cfg
+			c.Insecure = tc.insecure
+			in := struct{}{}
+			out := okAuthResponseData{}
+			r := testRequest(c, &in, &out)
+			r.Session.TLSConfig = tc.tlsConfig
+			err := r.Send()
+			switch {
+			case tc.err && err == nil:
+				t.Fatalf("Expected error, got success")
+			case !tc.err && err != nil:
+				t.Fatalf("Bad: %s", err)
+			}
+			if tc.tlsConfig != nil && tc.tlsConfig.InsecureSkipVerify {
+				t.Fatalf("Expected session TLSConfig to be left unmodified")
+			}
+		})
+	}
+}
+
+func TestRequestSendAPIError(t *testing.T) {
+	ts := newHTTPTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		http.Error(w, notFoundResponseText, http.StatusNotFound)
+	})
+	defer ts.Close()
+	cfg := phpipamConfig()
+	cfg.Endpoint = ts.URL
+	in := struct{}{}
+	out := okAuthResponseData{}
+	r := testRequest(cfg, &in, &out)
+	err := r.Send()
+
+	if err == nil {
+		t.Fatalf("Expected error, got success")
+	}
+
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("Expected *APIError, got %T", err)
+	}
+	if apiErr.Code != 404 || apiErr.Message != "No subnets found" {
+		t.Fatalf("Expected code 404 and message %q, got %d and %q", "No subnets found", apiErr.Code, apiErr.Message)
+	}
+	if !IsNotFound(err) {
+		t.Fatalf("Expected IsNotFound to be true for %s", err)
+	}
+	if IsConflict(err) {
+		t.Fatalf("Expected IsConflict to be false for %s", err)
+	}
+	if !IsNotFound(fmt.Errorf("wrapped: %w", err)) {
+		t.Fatalf("Expected IsNotFound to be true for wrapped error")
+	}
 }
@@ -2,26 +2,440 @@
 package session
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
 	"github.com/imdario/mergo"
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
 )
 
-// timeLayout represents the datetime format returned by the PHPIPAM api.
-const timeLayout = "2006-01-02 15:04:05"
-
 // Token represents a PHPIPAM session token.
 type Token struct {
 	// The token string.
 	String string `json:"token"`
+
+	// The expiry time of the token, as returned by the API on login.
+	Expires string `json:"expires,omitempty"`
+}
+
+// ExpiresAt parses the token's expiry time. The API returns the expiry time in
+// the server's time zone, which is assumed to be the same as the local one.
+func (t Token) ExpiresAt() (time.Time, error) {
+	return time.ParseInLocation(phpipam.DateTimeLayout, t.Expires, time.Local)
+}
+
+// LoginFunc logs in a session, updating its token. It is set by the client
+// package, which implements the login flow, and is used by Session.Refresh.
+var LoginFunc func(s *Session) error
+
+// LogoutFunc logs out a session, deleting its token on the server and
+// removing it from the session. It is set by the client package, which
+// implements the logout flow, and is used by Session.Logout.
+var LogoutFunc func(s *Session) error
+
+// Logger is the interface used by a session to log the requests and
+// responses made with it. *log.Logger satisfies this interface.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Doer is the interface used by a session to send HTTP requests. *http.Client
+// satisfies this interface, and it can be implemented by a mock to test code
+// that uses the SDK without a PHPIPAM server:
+//
+//	type mockDoer struct{}
+//
+//	func (mockDoer) Do(req *http.Request) (*http.Response, error) {
+//	  return &http.Response{
+//	    StatusCode: http.StatusOK,
+//	    Status:     "200 OK",
+//	    Header:     http.Header{"Content-Type": []string{"application/json"}},
+//	    Body:       ioutil.NopCloser(strings.NewReader(`{"code":200,"success":true,"data":[]}`)),
+//	  }, nil
+//	}
+//
+//	sess := session.NewSession()
+//	sess.Token.String = "mock"
+//	sess.Doer = mockDoer{}
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Observer is the interface used by a session to report the outcome of each
+// request made through a client with it, for example to record metrics.
+//
+// ObserveRequest is called once per request, after any retries and token
+// refreshes, with the request's method and path without its query string
+// (ie: /subnets/3/), the HTTP status of the final response, the total time
+// taken, and the resulting error. status is zero if no response was
+// received. It may be called from multiple goroutines at once.
+type Observer interface {
+	ObserveRequest(method, path string, status int, duration time.Duration, err error)
+}
+
+// RetryConfig controls how requests that fail with a transient error are
+// retried. The zero value disables retries.
+type RetryConfig struct {
+	// The maximum number of attempts made for a single request, including the
+	// first one. A value of 1 or less disables retries.
+	MaxAttempts int
+
+	// The delay before the first retry. The delay is doubled after each
+	// subsequent attempt.
+	BaseDelay time.Duration
+
+	// The status codes that are considered retryable. If this is empty, 429,
+	// 502, 503, and 504 are retried. If the server supplies a Retry-After
+	// header with the error, it is used as the delay for the next attempt
+	// instead.
+	StatusCodes []int
+
+	// If true, HTTP protocol errors (ie: connection failures) are also retried.
+	RetryProtocolErrors bool
+
+	// If true, requests with methods that are not idempotent (ie: POST,
+	// PATCH, and DELETE) are retried as well. By default only GET, HEAD, and
+	// OPTIONS requests are retried, as retrying a request that reached the
+	// server before failing could otherwise apply it twice, such as creating
+	// a duplicate subnet. Failures of the login made before a request are
+	// retried for any method, as the request itself has not been sent yet.
+	RetryUnsafe bool
 }
 
 // Session represents a PHPIPAM session.
+//
+// A session is safe for concurrent use by multiple goroutines and controllers
+// once it has been configured. Its configuration fields should not be changed
+// while requests are in flight.
 type Session struct {
 	// The session's configuration.
 	Config phpipam.Config
 
-	// The session token.
+	// The session token. This can be set when creating a session, but once the
+	// session is in use, use CurrentToken and SetToken to access it instead, as
+	// the token may be replaced by concurrent requests logging in again.
 	Token Token
+
+	// tokenMu protects Token.
+	tokenMu sync.RWMutex
+
+	// If true, Token is a static app code token (ie: for an API app using the
+	// "SSL with App code token" security mode). The /user/ login call is
+	// skipped, and the token is sent directly with every request.
+	StaticToken bool
+
+	// An optional HTTP client to use for requests made with this session. This
+	// can be used to set a timeout or a custom transport.
+	//
+	// If this is set, it wins over any transport settings derived from the
+	// session's configuration, such as Config.Insecure - configure TLS on the
+	// supplied client's transport instead. If the client has no CheckRedirect
+	// function, redirects are still not followed. If this is nil, a default
+	// client with no timeout is used.
+	HTTPClient *http.Client
+
+	// An optional Doer that sends the HTTP requests made with this session,
+	// such as a mock for testing. If this is set, it wins over HTTPClient and
+	// any transport settings, and redirects are left to it. If this is nil,
+	// requests are sent with HTTPClient or the default client.
+	Doer Doer
+
+	// An optional TLS configuration for the default client, used when
+	// HTTPClient is nil. This can be used to supply a custom root CA pool or
+	// client certificates without modifying http.DefaultTransport. If
+	// Config.Insecure is true, certificate verification is disabled
+	// regardless of this setting. If this is nil, the system roots are used.
+	TLSConfig *tls.Config
+
+	// The User-Agent header sent with requests made with this session. If this
+	// is empty, phpipam.DefaultUserAgent is used.
+	UserAgent string
+
+	// An optional logger for request and response debugging. The method, path,
+	// request body, status, and response body of each request are logged to
+	// it, with the session token redacted. If this is nil, the standard logger
+	// is used.
+	Logger Logger
+
+	// An optional observer that is notified of the outcome of each request
+	// made through a client with this session. If this is nil, requests are
+	// not observed.
+	Observer Observer
+
+	// If true, requests that fail because the session token is invalid or has
+	// expired (ie: a 401 error) are retried once after logging in again. Note
+	// that requests that fail with the "Token expired" error are always
+	// retried, regardless of this setting.
+	AutoRefresh bool
+
+	// If true, write requests (ie: POST, PUT, PATCH, and DELETE) made through
+	// a client are validated and serialized, but not sent. They fail with a
+	// *request.DryRunError carrying the request body instead. Read requests,
+	// such as the custom field schema lookups done before updating custom
+	// fields, are still sent, as are the login and logout requests.
+	DryRun bool
+
+	// If true, GET requests made through a client are sent with the
+	// links=false query parameter, so that the API leaves out the links array
+	// it otherwise adds to every returned object. This shrinks the responses
+	// to large list requests, and the time spent decoding them. Each subnet
+	// carries nine links, so leaving them out saves about 700 bytes per subnet
+	// in a compact response - a GetAllSubnets response is around 60% smaller.
+	// Requests that already set the links parameter are left as is.
+	DisableLinks bool
+
+	// The PHP version of the PHPIPAM server (ie: 8.1.2), if known. This is
+	// informational, and is set by client.DetectPHPVersion.
+	PHPVersion string
+
+	// The JSON form the server uses for numeric and boolean fields. See
+	// phpipam.NumericMode. With the default of phpipam.NumericAuto, both forms
+	// are accepted. Use phpipam.NumericModeForPHPVersion or
+	// client.DetectPHPVersion to set it from the server's PHP version.
+	NumericMode phpipam.NumericMode
+
+	// The retry configuration for requests made with this session. By default,
+	// requests are not retried.
+	Retry RetryConfig
+
+	// If greater than zero, the maximum number of requests per second made with
+	// this session. The limit is shared by all controllers using the session.
+	RateLimit float64
+
+	// rateMu protects nextRequest.
+	rateMu sync.Mutex
+
+	// nextRequest is the earliest time the next request can be made under
+	// RateLimit.
+	nextRequest time.Time
+
+	// If greater than zero, custom field schemas fetched through a client are
+	// cached for this long. The cache is shared by all controllers using the
+	// session. Use ClearSchemaCache to drop cached schemas after the custom
+	// field definitions have been changed.
+	SchemaCacheTTL time.Duration
+
+	// schemaMu protects schemaCache.
+	schemaMu sync.Mutex
+
+	// schemaCache holds the cached custom field schemas, keyed by controller.
+	schemaCache map[string]cachedSchema
+
+	// refreshMu protects login.
+	refreshMu sync.Mutex
+
+	// login is the login in progress via RefreshToken, if any.
+	login *loginCall
+}
+
+// loginCall is a login in progress via RefreshToken, whose result is shared
+// by all callers that wait on it.
+type loginCall struct {
+	// done is closed once the login has finished.
+	done chan struct{}
+
+	// The result of the login.
+	err error
+}
+
+// cachedSchema is a custom field schema cached by a session.
+type cachedSchema struct {
+	// The cached schema.
+	schema map[string]phpipam.CustomField
+
+	// The time the cache entry expires.
+	expires time.Time
+}
+
+// copySchema returns a copy of a custom field schema, so that cached schemas
+// can't be modified by callers.
+func copySchema(schema map[string]phpipam.CustomField) map[string]phpipam.CustomField {
+	out := make(map[string]phpipam.CustomField, len(schema))
+	for k, v := range schema {
+		out[k] = v
+	}
+	return out
+}
+
+// CachedSchema returns the cached custom field schema for the supplied
+// controller. ok is false if SchemaCacheTTL is not set, or if there is no
+// unexpired schema for the controller in the cache.
+func (s *Session) CachedSchema(controller string) (schema map[string]phpipam.CustomField, ok bool) {
+	if s.SchemaCacheTTL <= 0 {
+		return nil, false
+	}
+	s.schemaMu.Lock()
+	defer s.schemaMu.Unlock()
+	entry, ok := s.schemaCache[controller]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return copySchema(entry.schema), true
+}
+
+// CacheSchema stores the custom field schema for the supplied controller in
+// the cache for SchemaCacheTTL. It does nothing if SchemaCacheTTL is not set.
+func (s *Session) CacheSchema(controller string, schema map[string]phpipam.CustomField) {
+	if s.SchemaCacheTTL <= 0 {
+		return
+	}
+	s.schemaMu.Lock()
+	defer s.schemaMu.Unlock()
+	if s.schemaCache == nil {
+		s.schemaCache = make(map[string]cachedSchema)
+	}
+	s.schemaCache[controller] = cachedSchema{
+		schema:  copySchema(schema),
+		expires: time.Now().Add(s.SchemaCacheTTL),
+	}
+}
+
+// ClearSchemaCache removes all cached custom field schemas.
+func (s *Session) ClearSchemaCache() {
+	s.schemaMu.Lock()
+	defer s.schemaMu.Unlock()
+	s.schemaCache = nil
+}
+
+// CurrentToken returns the session's current token. It is safe to call
+// while other goroutines are making requests with the session.
+func (s *Session) CurrentToken() Token {
+	s.tokenMu.RLock()
+	defer s.tokenMu.RUnlock()
+	return s.Token
+}
+
+// SetToken replaces the session's token. It is safe to call while other
+// goroutines are making requests with the session.
+func (s *Session) SetToken(t Token) {
+	s.tokenMu.Lock()
+	defer s.tokenMu.Unlock()
+	s.Token = t
+}
+
+// RefreshToken refreshes the session token by calling login, which is
+// expected to log in and update the session's token.
+//
+// stale should be the token that the caller was using when it determined that
+// a refresh was necessary. If the session's token has already been changed
+// from this value, another caller has already refreshed the token and login
+// is not called. If another caller's login is still in progress, RefreshToken
+// waits for it and returns its result instead of calling login. This ensures
+// that concurrent requests that all need a new token only log in once, even
+// if that login fails.
+func (s *Session) RefreshToken(stale string, login func() error) error {
+	s.refreshMu.Lock()
+	if call := s.login; call != nil {
+		s.refreshMu.Unlock()
+		<-call.done
+		return call.err
+	}
+	if s.CurrentToken().String != stale {
+		s.refreshMu.Unlock()
+		return nil
+	}
+	call := &loginCall{done: make(chan struct{})}
+	s.login = call
+	s.refreshMu.Unlock()
+
+	call.err = login()
+
+	s.refreshMu.Lock()
+	s.login = nil
+	s.refreshMu.Unlock()
+	close(call.done)
+	return call.err
+}
+
+// TokenExpired returns true if the session does not have a token yet, or if
+// its token has passed its expiry time. Static tokens, and tokens without a
+// valid expiry time, are never considered expired.
+func (s *Session) TokenExpired() bool {
+	if s.StaticToken {
+		return false
+	}
+	token := s.CurrentToken()
+	if token.String == "" {
+		return true
+	}
+	t, err := token.ExpiresAt()
+	if err != nil {
+		return false
+	}
+	return time.Now().After(t)
+}
+
+// Refresh logs the session in again, replacing its token. This can be used to
+// refresh a token before it expires. Static tokens can't be refreshed, so
+// Refresh does nothing for sessions using them.
+func (s *Session) Refresh() error {
+	if s.StaticToken {
+		return nil
+	}
+	if LoginFunc == nil {
+		return errors.New("no login function registered, import the client package to set one")
+	}
+	return s.RefreshToken(s.CurrentToken().String, func() error { return LoginFunc(s) })
+}
+
+// Logout deletes the session's token on the server and removes it from the
+// session, so that the next request made with it logs in again. This keeps
+// short-lived sessions from leaving tokens behind on the server:
+//
+//	sess := session.NewSession()
+//	defer sess.Logout()
+//
+// The logout is implemented by client.Logout, which describes how sessions
+// without a token, static tokens, and expired tokens are handled. Logout is
+// safe to call more than once.
+func (s *Session) Logout() error {
+	if LogoutFunc == nil {
+		return errors.New("no logout function registered, import the client package to set one")
+	}
+	return LogoutFunc(s)
+}
+
+// NewStaticTokenSession creates a new session that authenticates with a
+// static app code token, instead of logging in with a username and password.
+// Any further configuration is loaded from the environment as per NewSession.
+func NewStaticTokenSession(endpoint, appID, token string) *Session {
+	s := NewSession(phpipam.Config{
+		Endpoint: endpoint,
+		AppID:    appID,
+	})
+	s.Token.String = token
+	s.StaticToken = true
+	return s
+}
+
+// WaitRateLimit blocks until a request can be made under the session's
+// RateLimit, or until ctx is done, in which case the context's error is
+// returned. It returns immediately if RateLimit is not set.
+func (s *Session) WaitRateLimit(ctx context.Context) error {
+	if s.RateLimit <= 0 {
+		return nil
+	}
+	s.rateMu.Lock()
+	now := time.Now()
+	slot := s.nextRequest
+	if slot.Before(now) {
+		slot = now
+	}
+	s.nextRequest = slot.Add(time.Duration(float64(time.Second) / s.RateLimit))
+	s.rateMu.Unlock()
+
+	t := time.NewTimer(slot.Sub(now))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
 }
 
 // NewSession creates a new session based off supplied configs. It is up to the
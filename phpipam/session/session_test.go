@@ -1,8 +1,13 @@
 package session
 
 import (
+	"context"
+	"errors"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/pavel-z1/phpipam-sdk-go/phpipam"
 )
@@ -38,3 +43,165 @@ func TestNewSession(t *testing.T) {
 		t.Fatalf("Expected session to be %#v, got %#v", expected, actual)
 	}
 }
+
+func TestNewStaticTokenSession(t *testing.T) {
+	expected := &Session{
+		Config: phpipamConfig(),
+		Token: Token{
+			String: "foobarbazboop",
+		},
+		StaticToken: true,
+	}
+
+	actual := NewStaticTokenSession("http://localhost/api", "0123456789abcdefgh", "foobarbazboop")
+	actual.Config.Username = expected.Config.Username
+	actual.Config.Password = expected.Config.Password
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected session to be %#v, got %#v", expected, actual)
+	}
+}
+
+func TestRefreshTokenCoalesced(t *testing.T) {
+	cases := []struct {
+		name      string
+		loginErr  error
+		token     string
+		expectErr bool
+	}{
+		{name: "success", token: "newtoken"},
+		{name: "failure", loginErr: errors.New("too many login attempts"), expectErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := fullSessionConfig()
+			s.Token = Token{}
+			var logins int32
+			login := func() error {
+				atomic.AddInt32(&logins, 1)
+				time.Sleep(20 * time.Millisecond)
+				if tc.loginErr != nil {
+					return tc.loginErr
+				}
+				s.SetToken(Token{String: tc.token})
+				return nil
+			}
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 10)
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs <- s.RefreshToken("", login)
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			if n := atomic.LoadInt32(&logins); n != 1 {
+				t.Fatalf("Expected 1 login, got %d", n)
+			}
+			for err := range errs {
+				if tc.expectErr != (err != nil) {
+					t.Fatalf("Expected error %t, got %v", tc.expectErr, err)
+				}
+			}
+			if actual := s.CurrentToken().String; actual != tc.token {
+				t.Fatalf("Expected token %q, got %q", tc.token, actual)
+			}
+		})
+	}
+}
+
+func TestTokenExpired(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Token    Token
+		Static   bool
+		Expected bool
+	}{
+		{Name: "no token", Token: Token{}, Expected: true},
+		{Name: "expired", Token: Token{String: "foo", Expires: "2000-01-01 00:00:00"}, Expected: true},
+		{Name: "valid", Token: Token{String: "foo", Expires: "2999-12-31 23:59:59"}, Expected: false},
+		{Name: "no expiry", Token: Token{String: "foo"}, Expected: false},
+		{Name: "static", Token: Token{String: "foo", Expires: "2000-01-01 00:00:00"}, Static: true, Expected: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			sess := fullSessionConfig()
+			sess.Token = tc.Token
+			sess.StaticToken = tc.Static
+			if actual := sess.TokenExpired(); actual != tc.Expected {
+				t.Fatalf("Expected %t, got %t", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestWaitRateLimit(t *testing.T) {
+	sess := fullSessionConfig()
+	sess.RateLimit = 50
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := sess.WaitRateLimit(context.Background()); err != nil {
+			t.Fatalf("Bad: %s", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("Expected rate limit to delay requests by at least 40ms, took %s", elapsed)
+	}
+}
+
+func TestWaitRateLimitContextCancelled(t *testing.T) {
+	sess := fullSessionConfig()
+	sess.RateLimit = 0.001
+	if err := sess.WaitRateLimit(context.Background()); err != nil {
+		t.Fatalf("Bad: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sess.WaitRateLimit(ctx); err != context.Canceled {
+		t.Fatalf("Expected %q, got %v", context.Canceled, err)
+	}
+}
+
+func TestSchemaCache(t *testing.T) {
+	schema := map[string]phpipam.CustomField{
+		"foo": phpipam.CustomField{Name: "foo", Type: "varchar(255)"},
+	}
+
+	s := &Session{}
+	s.CacheSchema("subnets", schema)
+	if _, ok := s.CachedSchema("subnets"); ok {
+		t.Fatalf("Expected no cached schema with the cache disabled")
+	}
+
+	s.SchemaCacheTTL = 20 * time.Millisecond
+	s.CacheSchema("subnets", schema)
+	actual, ok := s.CachedSchema("subnets")
+	if !ok || !reflect.DeepEqual(schema, actual) {
+		t.Fatalf("Expected cached schema %#v, got %#v", schema, actual)
+	}
+
+	actual["bar"] = phpipam.CustomField{Name: "bar"}
+	if actual, _ := s.CachedSchema("subnets"); len(actual) != 1 {
+		t.Fatalf("Expected cached schema to be unmodified, got %#v", actual)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := s.CachedSchema("subnets"); ok {
+		t.Fatalf("Expected cached schema to expire")
+	}
+
+	s.CacheSchema("subnets", schema)
+	s.ClearSchemaCache()
+	if _, ok := s.CachedSchema("subnets"); ok {
+		t.Fatalf("Expected cached schema to be cleared")
+	}
+}